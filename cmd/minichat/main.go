@@ -0,0 +1,347 @@
+// Command minichat is an interactive multi-turn chat with any provider
+// supported by generators.Open.
+//
+// Usage:
+//
+//	minichat [flags] URL
+//
+// Lines starting with a slash are commands; type /help to list them.
+// Responses are streamed as they are generated and the token usage of each
+// turn is shown after it. Pressing Ctrl-C while a response is streaming
+// stops that response; pressing it at the prompt, or typing /quit, exits.
+//
+// Example:
+//
+//	minichat --system "You are a concise assistant." gemini:///gemini-2.0-flash
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tnotstar/go-minolas/pkg/ai/generators"
+	"github.com/tnotstar/go-minolas/pkg/ai/memory"
+	"github.com/tnotstar/go-minolas/pkg/cli"
+	"github.com/tnotstar/go-minolas/pkg/cli/argparse"
+)
+
+const helpText = `Commands:
+  /system [TEXT]        Show or set the system instruction ("/system -" clears it)
+  /model [NAME]         Show or set the model ("/model -" restores the URL's model)
+  /temperature [VALUE]  Show or set the temperature ("/temperature -" restores the default)
+  /save FILE            Save the transcript (.md for Markdown, JSON otherwise)
+  /load FILE            Load a transcript saved as JSON
+  /reset                Forget the conversation, keeping the settings
+  /usage                Show the token usage of the session
+  /help                 Show this help
+  /quit                 Exit
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command and returns its exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	parser := argparse.NewArgumentParser("minichat", "Chat interactively with an AI provider URL.")
+	aiurl := parser.String("url", argparse.Required(), argparse.Help("Provider URL, e.g. ollama://localhost:11434/llama3.2"))
+	system := parser.String("--system", argparse.Short("s"), argparse.Help("System instruction"))
+	model := parser.String("--model", argparse.Short("m"), argparse.Help("Override the model named in the URL"))
+	temperature := parser.String("--temperature", argparse.Short("t"), argparse.Help("Sampling temperature"))
+	load := parser.String("--load", argparse.Short("l"), argparse.Help("Resume a transcript saved as JSON; other flags override its settings"))
+
+	if err := parser.Parse(args); err != nil {
+		if errors.Is(err, argparse.ErrHelp) {
+			fmt.Fprint(stdout, parser.Usage())
+			return 0
+		}
+		fmt.Fprintf(stderr, "minichat: %v\n\n%s", err, parser.Usage())
+		return 2
+	}
+
+	// The transcript is loaded first so that explicit flags override the
+	// settings saved in it.
+	c := &chat{url: *aiurl, out: stdout}
+	if *load != "" {
+		if err := c.load(*load); err != nil {
+			fmt.Fprintf(stderr, "minichat: %v\n", err)
+			return 1
+		}
+	}
+	if *system != "" {
+		c.system = *system
+	}
+	if *model != "" {
+		c.model = *model
+	}
+	if *temperature != "" {
+		if err := c.setTemperature(*temperature); err != nil {
+			fmt.Fprintf(stderr, "minichat: %v\n", err)
+			return 2
+		}
+	}
+
+	gen, err := generators.Open(context.Background(), *aiurl)
+	if err != nil {
+		fmt.Fprintf(stderr, "minichat: %v\n", err)
+		return 1
+	}
+	defer gen.Close()
+	c.gen = gen
+
+	fmt.Fprintf(stdout, "Chatting with %s. Type /help for commands.\n", c.displayModel())
+	c.loop(bufio.NewReader(stdin))
+	return 0
+}
+
+// chat holds the state of an interactive session.
+type chat struct {
+	gen         generators.Generator
+	url         string
+	out         io.Writer
+	system      string
+	model       string
+	temperature *float32
+	turns       []memory.Turn
+	total       generators.Usage
+}
+
+// loop reads lines until end of input or /quit. Ctrl-C cancels the
+// response being streamed, or exits when waiting for input.
+func (c *chat) loop(in *bufio.Reader) {
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	// Lines are read in the background so that Ctrl-C can end the wait,
+	// but only when asked for, so the prompt never interrupts a response.
+	lines := make(chan string)
+	next := make(chan struct{}, 1)
+	go func() {
+		defer close(lines)
+		for range next {
+			// in is already buffered, so cli does not lose input between calls.
+			line, err := cli.ReadInputFromReader(in, c.out, "\n> ", nil)
+			if err != nil {
+				return
+			}
+			lines <- line
+		}
+	}()
+
+	for {
+		next <- struct{}{}
+		var line string
+		select {
+		case l, ok := <-lines:
+			if !ok {
+				fmt.Fprintln(c.out)
+				return
+			}
+			line = l
+		case <-interrupts:
+			fmt.Fprintln(c.out)
+			return
+		}
+
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "/") {
+			if quit := c.command(line); quit {
+				return
+			}
+			continue
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-interrupts:
+				cancel()
+			case <-done:
+			}
+		}()
+		err := c.send(ctx, line)
+		close(done)
+		cancel()
+		if err != nil {
+			fmt.Fprintf(c.out, "\nerror: %v\n", err)
+		}
+	}
+}
+
+// send runs one turn, streaming the answer to the output. The exchange is
+// only added to the conversation if the stream completes.
+func (c *chat) send(ctx context.Context, input string) error {
+	user := memory.Turn{Role: memory.RoleUser, Content: input, CreatedAt: time.Now()}
+	_, prompt := memory.Render(append(c.turns, user))
+
+	ch, err := c.gen.Stream(ctx, prompt, c.options()...)
+	if err != nil {
+		return err
+	}
+
+	var (
+		answer    strings.Builder
+		usage     *generators.Usage
+		streamErr error
+	)
+	for chunk := range ch {
+		if chunk.Error != nil {
+			streamErr = chunk.Error
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		if chunk.Text != "" {
+			fmt.Fprint(c.out, chunk.Text)
+			answer.WriteString(chunk.Text)
+		}
+	}
+	fmt.Fprintln(c.out)
+	if streamErr != nil {
+		return streamErr
+	}
+	if err := ctx.Err(); err != nil {
+		return errors.New("response interrupted")
+	}
+
+	c.turns = append(c.turns, user, memory.Turn{
+		Role:      memory.RoleAssistant,
+		Content:   strings.TrimSpace(answer.String()),
+		CreatedAt: time.Now(),
+	})
+	if usage != nil {
+		c.total.PromptTokens += usage.PromptTokens
+		c.total.CompletionTokens += usage.CompletionTokens
+		c.total.ThoughtTokens += usage.ThoughtTokens
+		c.total.CachedTokens += usage.CachedTokens
+		c.total.TotalTokens += usage.TotalTokens
+		fmt.Fprintf(c.out, "[tokens: prompt %d, completion %d, total %d | session %d]\n",
+			usage.PromptTokens, usage.CompletionTokens, usage.TotalTokens, c.total.TotalTokens)
+	}
+	return nil
+}
+
+// options returns the generator options for the current settings.
+func (c *chat) options() []generators.Option {
+	var opts []generators.Option
+	if c.system != "" {
+		opts = append(opts, generators.WithSystemInstruction(c.system))
+	}
+	if c.model != "" {
+		opts = append(opts, generators.WithModel(c.model))
+	}
+	if c.temperature != nil {
+		opts = append(opts, generators.WithTemperature(*c.temperature))
+	}
+	return opts
+}
+
+// command runs a slash command and reports whether the session should end.
+func (c *chat) command(line string) bool {
+	name, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+
+	var err error
+	switch name {
+	case "/quit", "/exit":
+		return true
+	case "/help":
+		fmt.Fprint(c.out, helpText)
+	case "/system":
+		switch arg {
+		case "":
+			fmt.Fprintf(c.out, "system: %q\n", c.system)
+		case "-":
+			c.system = ""
+		default:
+			c.system = arg
+		}
+	case "/model":
+		switch arg {
+		case "":
+			fmt.Fprintf(c.out, "model: %s\n", c.displayModel())
+		case "-":
+			c.model = ""
+		default:
+			c.model = arg
+		}
+	case "/temperature":
+		if arg == "" {
+			if c.temperature == nil {
+				fmt.Fprintln(c.out, "temperature: provider default")
+			} else {
+				fmt.Fprintf(c.out, "temperature: %g\n", *c.temperature)
+			}
+			break
+		}
+		err = c.setTemperature(arg)
+	case "/save":
+		if arg == "" {
+			err = errors.New("usage: /save FILE")
+			break
+		}
+		if err = c.save(arg); err == nil {
+			fmt.Fprintf(c.out, "saved %d turns to %s\n", len(c.turns), arg)
+		}
+	case "/load":
+		if arg == "" {
+			err = errors.New("usage: /load FILE")
+			break
+		}
+		if err = c.load(arg); err == nil {
+			fmt.Fprintf(c.out, "loaded %d turns from %s\n", len(c.turns), arg)
+		}
+	case "/reset":
+		c.turns = nil
+		c.total = generators.Usage{}
+		fmt.Fprintln(c.out, "conversation cleared")
+	case "/usage":
+		fmt.Fprintf(c.out, "session tokens: prompt %d, completion %d, thoughts %d, total %d\n",
+			c.total.PromptTokens, c.total.CompletionTokens, c.total.ThoughtTokens, c.total.TotalTokens)
+	default:
+		err = fmt.Errorf("unknown command %s (try /help)", name)
+	}
+	if err != nil {
+		fmt.Fprintf(c.out, "error: %v\n", err)
+	}
+	return false
+}
+
+// setTemperature parses and sets the temperature; "-" restores the
+// provider's default.
+func (c *chat) setTemperature(v string) error {
+	if v == "-" {
+		c.temperature = nil
+		return nil
+	}
+	f, err := strconv.ParseFloat(v, 32)
+	if err != nil {
+		return fmt.Errorf("invalid temperature %q", v)
+	}
+	t := float32(f)
+	c.temperature = &t
+	return nil
+}
+
+// displayModel returns the model in use, for display.
+func (c *chat) displayModel() string {
+	if c.model != "" {
+		return c.model
+	}
+	if m, ok := c.gen.(interface{ Model() string }); ok {
+		return m.Model()
+	}
+	return c.url
+}
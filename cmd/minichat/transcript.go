@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tnotstar/go-minolas/pkg/ai/generators"
+	"github.com/tnotstar/go-minolas/pkg/ai/memory"
+)
+
+// transcript is the JSON form of a saved chat.
+type transcript struct {
+	URL         string           `json:"url"`
+	Model       string           `json:"model,omitempty"`
+	System      string           `json:"system,omitempty"`
+	Temperature *float32         `json:"temperature,omitempty"`
+	Usage       generators.Usage `json:"usage"`
+	Turns       []transcriptTurn `json:"turns"`
+}
+
+type transcriptTurn struct {
+	Role    string    `json:"role"`
+	Content string    `json:"content"`
+	Time    time.Time `json:"time"`
+}
+
+// save writes the conversation to path, as Markdown if the file name ends
+// in ".md" and as JSON otherwise.
+func (c *chat) save(path string) error {
+	var data []byte
+	if strings.EqualFold(filepath.Ext(path), ".md") {
+		data = []byte(c.markdown())
+	} else {
+		t := transcript{
+			URL:         c.url,
+			Model:       c.model,
+			System:      c.system,
+			Temperature: c.temperature,
+			Usage:       c.total,
+		}
+		for _, turn := range c.turns {
+			t.Turns = append(t.Turns, transcriptTurn{Role: turn.Role, Content: turn.Content, Time: turn.CreatedAt})
+		}
+		var err error
+		if data, err = json.MarshalIndent(t, "", "  "); err != nil {
+			return err
+		}
+		data = append(data, '\n')
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// load replaces the conversation and settings with a JSON transcript.
+// The provider URL is not changed.
+func (c *chat) load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var t transcript
+	if err := json.Unmarshal(data, &t); err != nil {
+		return fmt.Errorf("%s is not a JSON transcript: %w", path, err)
+	}
+	c.model, c.system, c.temperature, c.total = t.Model, t.System, t.Temperature, t.Usage
+	c.turns = nil
+	for _, turn := range t.Turns {
+		c.turns = append(c.turns, memory.Turn{Role: turn.Role, Content: turn.Content, CreatedAt: turn.Time})
+	}
+	return nil
+}
+
+// markdown renders the conversation for reading.
+func (c *chat) markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Chat with %s\n\n", c.displayModel())
+	if c.system != "" {
+		fmt.Fprintf(&b, "> **System:** %s\n\n", c.system)
+	}
+	for _, turn := range c.turns {
+		role := "User"
+		if turn.Role == memory.RoleAssistant {
+			role = "Assistant"
+		}
+		fmt.Fprintf(&b, "## %s\n\n%s\n\n", role, turn.Content)
+	}
+	fmt.Fprintf(&b, "---\n\nTokens: prompt %d, completion %d, total %d\n",
+		c.total.PromptTokens, c.total.CompletionTokens, c.total.TotalTokens)
+	return b.String()
+}
@@ -0,0 +1,209 @@
+// Command minieval runs a prompt test suite against several providers
+// supported by generators.Open and prints a comparison report.
+//
+// Usage:
+//
+//	minieval [flags] --url [NAME=]URL [--url [NAME=]URL ...] SUITE
+//	minieval --db DBURL --report RUN_ID
+//
+// SUITE is a JSON file in the format described in package eval. Targets
+// run concurrently; "judge" scorers use the generator given with --judge.
+// With --db, results are stored in the database and earlier runs can be
+// printed again with --report, or listed with --runs.
+//
+// Example:
+//
+//	minieval -u fast=ollama://localhost:11434/llama3.2 -u gemini:///gemini-2.0-flash \
+//	    --judge gemini:///gemini-2.5-pro --db sqlite:eval.db capitals.json
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/tnotstar/go-minolas/pkg/ai/eval"
+	"github.com/tnotstar/go-minolas/pkg/ai/generators"
+	"github.com/tnotstar/go-minolas/pkg/cli/argparse"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// run executes the command and returns its exit status.
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	parser := argparse.NewArgumentParser("minieval", "Compare AI provider URLs on a prompt test suite.")
+	suitePath := parser.String("suite", argparse.Help("Suite file in JSON"))
+	urls := parser.StringList("--url", argparse.Short("u"), argparse.Help("Target as [NAME=]URL (repeatable)"))
+	judgeURL := parser.String("--judge", argparse.Short("j"), argparse.Help("Provider URL of the judge for judge scorers"))
+	dburl := parser.String("--db", argparse.Short("d"), argparse.Help("Store results in this database, e.g. sqlite:eval.db"))
+	concurrency := parser.Int("--concurrency", argparse.Short("c"), argparse.Default(4), argparse.Help("Number of requests run at once"))
+	reportID := parser.String("--report", argparse.Help("Print the stored report of a run instead of running"))
+	listRuns := parser.Bool("--runs", argparse.Help("List the stored runs instead of running"))
+	verbose := parser.Bool("--verbose", argparse.Short("v"), argparse.Help("Print the output and reasons of failed cases"))
+
+	if err := parser.Parse(args); err != nil {
+		if errors.Is(err, argparse.ErrHelp) {
+			fmt.Fprint(stdout, parser.Usage())
+			return 0
+		}
+		return usageError(stderr, parser, err)
+	}
+
+	var store *eval.Store
+	if *dburl != "" {
+		var err error
+		if store, err = eval.OpenStore(ctx, *dburl); err != nil {
+			fmt.Fprintf(stderr, "minieval: %v\n", err)
+			return 1
+		}
+		defer store.Close()
+	}
+
+	switch {
+	case *listRuns || *reportID != "":
+		if store == nil {
+			return usageError(stderr, parser, errors.New("--runs and --report require --db"))
+		}
+		if *listRuns {
+			return printRuns(ctx, store, stdout, stderr)
+		}
+		report, err := store.Load(ctx, *reportID)
+		if err != nil {
+			fmt.Fprintf(stderr, "minieval: %v\n", err)
+			return 1
+		}
+		return printReport(report, *verbose, stdout, stderr)
+	case *suitePath == "":
+		return usageError(stderr, parser, errors.New("missing required positional argument: suite"))
+	case len(*urls) == 0:
+		return usageError(stderr, parser, errors.New("at least one --url is required"))
+	}
+
+	suite, err := eval.LoadSuite(*suitePath)
+	if err != nil {
+		fmt.Fprintf(stderr, "minieval: %v\n", err)
+		return 1
+	}
+
+	var targets []eval.Target
+	defer func() {
+		for _, t := range targets {
+			t.Generator.Close()
+		}
+	}()
+	for _, u := range *urls {
+		name, aiurl := splitTarget(u)
+		t, err := eval.OpenTarget(ctx, name, aiurl)
+		if err != nil {
+			fmt.Fprintf(stderr, "minieval: %v\n", err)
+			return 1
+		}
+		targets = append(targets, t)
+	}
+
+	opts := []eval.RunnerOption{
+		eval.WithConcurrency(*concurrency),
+		eval.WithProgress(func(done, total int, res eval.Result) {
+			status := fmt.Sprintf("%.2f", res.Score)
+			if res.Error != "" {
+				status = "error: " + res.Error
+			}
+			fmt.Fprintf(stderr, "[%d/%d] %s %s: %s\n", done, total, res.Target, res.CaseID, status)
+		}),
+	}
+	if store != nil {
+		opts = append(opts, eval.WithStore(store))
+	}
+	if *judgeURL != "" {
+		judge, err := generators.Open(ctx, *judgeURL)
+		if err != nil {
+			fmt.Fprintf(stderr, "minieval: judge: %v\n", err)
+			return 1
+		}
+		defer judge.Close()
+		opts = append(opts, eval.WithJudge(judge))
+	}
+
+	report, err := eval.NewRunner(opts...).Run(ctx, suite, targets...)
+	if report == nil {
+		fmt.Fprintf(stderr, "minieval: %v\n", err)
+		return 1
+	}
+	fmt.Fprintln(stderr)
+	code := printReport(report, *verbose, stdout, stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "minieval: %v\n", err)
+		return 1
+	}
+	return code
+}
+
+// splitTarget splits a "NAME=URL" target. A bare URL has no name; an "="
+// after the scheme belongs to the URL.
+func splitTarget(s string) (name, aiurl string) {
+	if name, aiurl, ok := strings.Cut(s, "="); ok && !strings.Contains(name, "://") {
+		return name, aiurl
+	}
+	return "", s
+}
+
+// printReport writes the comparison report and, if verbose, the details of
+// the cases that did not pass.
+func printReport(report *eval.Report, verbose bool, stdout, stderr io.Writer) int {
+	if err := report.WriteText(stdout); err != nil {
+		fmt.Fprintf(stderr, "minieval: %v\n", err)
+		return 1
+	}
+	if !verbose {
+		return 0
+	}
+	for _, res := range report.Results {
+		if res.Passed {
+			continue
+		}
+		fmt.Fprintf(stdout, "\n--- %s / %s\n", res.Target, res.CaseID)
+		if res.Error != "" {
+			fmt.Fprintf(stdout, "error: %s\n", res.Error)
+			continue
+		}
+		fmt.Fprintf(stdout, "%s\n", strings.TrimSpace(res.Output))
+		for _, s := range res.Scores {
+			if !s.Passed {
+				fmt.Fprintf(stdout, "  %s %.2f: %s\n", s.Scorer, s.Value, s.Reason)
+			}
+		}
+	}
+	return 0
+}
+
+// printRuns lists the stored runs.
+func printRuns(ctx context.Context, store *eval.Store, stdout, stderr io.Writer) int {
+	runs, err := store.Runs(ctx, "")
+	if err != nil {
+		fmt.Fprintf(stderr, "minieval: %v\n", err)
+		return 1
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RUN\tSUITE\tSTARTED\tDURATION")
+	for _, r := range runs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.ID, r.Suite, r.Started.Format(time.DateTime), r.Duration.Round(time.Millisecond))
+	}
+	tw.Flush()
+	return 0
+}
+
+func usageError(stderr io.Writer, parser *argparse.ArgumentParser, err error) int {
+	fmt.Fprintf(stderr, "minieval: %v\n\n%s", err, parser.Usage())
+	return 2
+}
@@ -0,0 +1,105 @@
+// Command minigateway serves providers supported by generators.Open behind
+// an OpenAI-compatible HTTP API, so that tools speaking only that API can
+// use them.
+//
+// Usage:
+//
+//	minigateway [flags] --route MODEL=URL [--route MODEL=URL ...]
+//
+// Each --route maps a model name, or a path.Match pattern such as
+// "gemini-*", to a provider URL; see package gateway for the routing rules.
+// API keys given with --api-key, or in the MINIGATEWAY_API_KEY environment
+// variable, are required as bearer tokens when set.
+//
+// Example:
+//
+//	minigateway -r gpt-4o-mini=ollama://localhost:11434/llama3.2 -r 'gemini-*=gemini://'
+//	curl localhost:8080/v1/chat/completions -d '{"model":"gpt-4o-mini","messages":[{"role":"user","content":"Hi"}]}'
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/tnotstar/go-minolas/pkg/ai/gateway"
+	"github.com/tnotstar/go-minolas/pkg/cli/argparse"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// run executes the command and returns its exit status.
+func run(ctx context.Context, args []string, stderr io.Writer) int {
+	parser := argparse.NewArgumentParser("minigateway", "Serve AI provider URLs behind an OpenAI-compatible API.")
+	listen := parser.String("--listen", argparse.Short("l"), argparse.Default(":8080"), argparse.Help("Address to listen on"))
+	routeArgs := parser.StringList("--route", argparse.Short("r"), argparse.Required(), argparse.Help("MODEL=URL route (repeatable)"))
+	keys := parser.StringList("--api-key", argparse.Short("k"), argparse.Help("Accepted bearer token (repeatable)"))
+
+	if err := parser.Parse(args); err != nil {
+		if errors.Is(err, argparse.ErrHelp) {
+			fmt.Fprint(os.Stdout, parser.Usage())
+			return 0
+		}
+		fmt.Fprintf(stderr, "minigateway: %v\n\n%s", err, parser.Usage())
+		return 2
+	}
+
+	routes := make(map[string]string)
+	for _, r := range *routeArgs {
+		model, aiurl, ok := strings.Cut(r, "=")
+		if !ok || model == "" || aiurl == "" {
+			fmt.Fprintf(stderr, "minigateway: invalid route %q, want MODEL=URL\n", r)
+			return 2
+		}
+		routes[model] = aiurl
+	}
+	apiKeys := *keys
+	if k := os.Getenv("MINIGATEWAY_API_KEY"); k != "" {
+		apiKeys = append(apiKeys, k)
+	}
+
+	gw, err := gateway.Open(ctx, routes, gateway.WithAPIKeys(apiKeys...))
+	if err != nil {
+		fmt.Fprintf(stderr, "minigateway: %v\n", err)
+		return 1
+	}
+	defer gw.Close()
+
+	logger := log.New(stderr, "minigateway: ", log.LstdFlags)
+	srv := &http.Server{
+		Addr:              *listen,
+		Handler:           gw,
+		ReadHeaderTimeout: 10 * time.Second,
+		ErrorLog:          logger,
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+	logger.Printf("listening on %s with %d routes", *listen, len(routes))
+
+	select {
+	case err := <-errc:
+		logger.Print(err)
+		return 1
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Print(err)
+		return 1
+	}
+	return 0
+}
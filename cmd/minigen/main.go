@@ -0,0 +1,232 @@
+// Command minigen sends a prompt to any provider supported by
+// generators.Open and writes the response to standard output.
+//
+// Usage:
+//
+//	minigen [flags] URL [PROMPT]
+//
+// The prompt is taken from the PROMPT argument, from the file given with
+// --file, or from standard input, in that order. The response is streamed
+// as it is generated unless --no-stream or --raw is given. --json asks
+// the model to answer with a JSON value; --raw prints the whole response,
+// including usage and candidates, as JSON.
+//
+// Examples:
+//
+//	minigen gemini:///gemini-2.0-flash "Write a haiku about Go"
+//	git diff | minigen --system "Write a commit message" ollama://localhost:11434/llama3.2
+//	minigen --json ollama://localhost:11434/llama3.2 "List three primes as a JSON array" | jq .
+//	minigen --raw --temperature 0 -f question.txt gemini:// | jq .Text
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+
+	"github.com/tnotstar/go-minolas/pkg/ai/generators"
+	"github.com/tnotstar/go-minolas/pkg/cli/argparse"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// run executes the command and returns its exit status.
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	parser := argparse.NewArgumentParser("minigen", "Generate text with an AI provider URL.")
+	aiurl := parser.String("url", argparse.Required(), argparse.Help("Provider URL, e.g. gemini:///gemini-2.0-flash"))
+	promptArg := parser.String("prompt", argparse.Help("Prompt text (default: --file or standard input)"))
+	file := parser.String("--file", argparse.Short("f"), argparse.Help("Read the prompt from a file ('-' for standard input)"))
+	model := parser.String("--model", argparse.Short("m"), argparse.Help("Override the model named in the URL"))
+	system := parser.String("--system", argparse.Short("s"), argparse.Help("System instruction"))
+	temperature := parser.String("--temperature", argparse.Short("t"), argparse.Help("Sampling temperature"))
+	maxTokens := parser.String("--max-tokens", argparse.Help("Maximum number of output tokens"))
+	topP := parser.String("--top-p", argparse.Help("Nucleus sampling probability"))
+	topK := parser.String("--top-k", argparse.Help("Top-k sampling cutoff"))
+	stops := parser.StringList("--stop", argparse.Help("Stop sequence (repeatable)"))
+	jsonOutput := parser.Bool("--json", argparse.Help("Ask the model to answer with JSON"))
+	raw := parser.Bool("--raw", argparse.Help("Print the whole response as JSON instead of streaming text"))
+	noStream := parser.Bool("--no-stream", argparse.Help("Wait for the complete response before printing"))
+	thoughts := parser.Bool("--thoughts", argparse.Help("Request model thoughts and print them to standard error"))
+	showUsage := parser.Bool("--usage", argparse.Short("u"), argparse.Help("Print token usage to standard error"))
+
+	if err := parser.Parse(args); err != nil {
+		if errors.Is(err, argparse.ErrHelp) {
+			fmt.Fprint(stdout, parser.Usage())
+			return 0
+		}
+		fmt.Fprintf(stderr, "minigen: %v\n\n%s", err, parser.Usage())
+		return 2
+	}
+
+	opts, err := options(*model, *system, *temperature, *maxTokens, *topP, *topK, *stops, *thoughts, *jsonOutput)
+	if err != nil {
+		fmt.Fprintf(stderr, "minigen: %v\n", err)
+		return 2
+	}
+
+	prompt, err := readPrompt(*promptArg, *file, stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "minigen: %v\n", err)
+		return 1
+	}
+
+	gen, err := generators.Open(ctx, *aiurl)
+	if err != nil {
+		fmt.Fprintf(stderr, "minigen: %v\n", err)
+		return 1
+	}
+	defer gen.Close()
+
+	var usage *generators.Usage
+	if *raw || *noStream {
+		resp, err := gen.Generate(ctx, prompt, opts...)
+		if err != nil {
+			fmt.Fprintf(stderr, "minigen: %v\n", err)
+			return 1
+		}
+		usage = &resp.Usage
+		if *raw {
+			enc := json.NewEncoder(stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(resp); err != nil {
+				fmt.Fprintf(stderr, "minigen: %v\n", err)
+				return 1
+			}
+		} else {
+			if *thoughts && resp.Thoughts != "" {
+				fmt.Fprintln(stderr, resp.Thoughts)
+			}
+			fmt.Fprintln(stdout, resp.Text)
+		}
+	} else {
+		if usage, err = stream(ctx, gen, prompt, opts, stdout, stderr); err != nil {
+			fmt.Fprintf(stderr, "\nminigen: %v\n", err)
+			return 1
+		}
+	}
+
+	if *showUsage && usage != nil {
+		fmt.Fprintf(stderr, "tokens: prompt %d, completion %d, thoughts %d, total %d\n",
+			usage.PromptTokens, usage.CompletionTokens, usage.ThoughtTokens, usage.TotalTokens)
+	}
+	return 0
+}
+
+// stream writes the response text to stdout as it arrives and thoughts to
+// stderr, and returns the usage reported at the end of the stream.
+func stream(ctx context.Context, gen generators.Generator, prompt string, opts []generators.Option, stdout, stderr io.Writer) (*generators.Usage, error) {
+	ch, err := gen.Stream(ctx, prompt, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		usage     *generators.Usage
+		streamErr error
+		endsInNL  = true
+	)
+	for chunk := range ch {
+		if chunk.Error != nil {
+			streamErr = chunk.Error
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		if chunk.Thought != "" {
+			fmt.Fprint(stderr, chunk.Thought)
+		}
+		if chunk.Text != "" {
+			fmt.Fprint(stdout, chunk.Text)
+			endsInNL = strings.HasSuffix(chunk.Text, "\n")
+		}
+	}
+	if !endsInNL {
+		fmt.Fprintln(stdout)
+	}
+	return usage, streamErr
+}
+
+// options converts the flag values into generator options. Empty values
+// leave the provider's defaults in place.
+func options(model, system, temperature, maxTokens, topP, topK string, stops []string, thoughts, jsonOutput bool) ([]generators.Option, error) {
+	var opts []generators.Option
+	if model != "" {
+		opts = append(opts, generators.WithModel(model))
+	}
+	if system != "" {
+		opts = append(opts, generators.WithSystemInstruction(system))
+	}
+	if temperature != "" {
+		v, err := strconv.ParseFloat(temperature, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --temperature %q", temperature)
+		}
+		opts = append(opts, generators.WithTemperature(float32(v)))
+	}
+	if maxTokens != "" {
+		v, err := strconv.Atoi(maxTokens)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --max-tokens %q", maxTokens)
+		}
+		opts = append(opts, generators.WithMaxOutputTokens(v))
+	}
+	if topP != "" {
+		v, err := strconv.ParseFloat(topP, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --top-p %q", topP)
+		}
+		opts = append(opts, generators.WithTopP(float32(v)))
+	}
+	if topK != "" {
+		v, err := strconv.ParseFloat(topK, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --top-k %q", topK)
+		}
+		opts = append(opts, generators.WithTopK(float32(v)))
+	}
+	if len(stops) > 0 {
+		opts = append(opts, generators.WithStopSequences(stops...))
+	}
+	if thoughts {
+		opts = append(opts, generators.WithIncludeThoughts())
+	}
+	if jsonOutput {
+		opts = append(opts, generators.WithJSONOutput())
+	}
+	return opts, nil
+}
+
+// readPrompt returns the prompt from the argument, the file or stdin.
+func readPrompt(arg, file string, stdin io.Reader) (string, error) {
+	if arg != "" {
+		return arg, nil
+	}
+	var (
+		data []byte
+		err  error
+	)
+	if file != "" && file != "-" {
+		data, err = os.ReadFile(file)
+	} else {
+		data, err = io.ReadAll(stdin)
+	}
+	if err != nil {
+		return "", fmt.Errorf("read prompt: %w", err)
+	}
+	prompt := strings.TrimSpace(string(data))
+	if prompt == "" {
+		return "", errors.New("empty prompt")
+	}
+	return prompt, nil
+}
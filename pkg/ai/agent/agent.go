@@ -0,0 +1,280 @@
+// Package agent runs a model in a loop with Go tools until it reaches a
+// final answer.
+//
+// At every step the model is shown the goal, the available tools and the
+// results of the previous calls, and replies with a JSON object that either
+// calls a tool or gives the final answer:
+//
+//	{"tool": "weather", "arguments": {"city": "Madrid"}}
+//	{"answer": "It will be sunny in Madrid."}
+//
+// The protocol is plain text, so it works with every generators.Generator.
+// The loop stops at the final answer or when the step or time budget runs
+// out. Every prompt, reply, call and result is recorded in a Transcript.
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tnotstar/go-minolas/pkg/ai/generators"
+)
+
+const (
+	// defaultMaxSteps is the number of model calls allowed when no budget
+	// is configured.
+	defaultMaxSteps = 10
+)
+
+var (
+	// ErrMaxSteps is returned when the model does not give a final answer
+	// within the step budget.
+	ErrMaxSteps = errors.New("agent: step budget exhausted")
+)
+
+// ApprovalFunc decides whether a call to a dangerous tool may run.
+// Returning an error aborts the run.
+type ApprovalFunc func(ctx context.Context, call Call) (bool, error)
+
+// Call is a tool call requested by the model.
+type Call struct {
+	Tool      string
+	Arguments json.RawMessage
+}
+
+// Option is a functional option for configuring an Agent.
+type Option func(*Agent)
+
+// WithMaxSteps sets the maximum number of model calls per run.
+// Values lower than one are ignored.
+func WithMaxSteps(n int) Option {
+	return func(a *Agent) {
+		if n > 0 {
+			a.maxSteps = n
+		}
+	}
+}
+
+// WithTimeout sets the maximum duration of a run. Zero means no limit
+// beyond the caller's context.
+func WithTimeout(d time.Duration) Option {
+	return func(a *Agent) { a.timeout = d }
+}
+
+// WithApproval sets the hook that approves calls to dangerous tools.
+// Without it, every call to a dangerous tool is denied.
+func WithApproval(fn ApprovalFunc) Option {
+	return func(a *Agent) { a.approve = fn }
+}
+
+// WithInstructions adds text to the system instruction sent to the model,
+// for example a persona or constraints on the answer.
+func WithInstructions(text string) Option {
+	return func(a *Agent) { a.instructions = text }
+}
+
+// WithGenerateOptions sets the generation options passed to every model
+// call.
+func WithGenerateOptions(opts ...generators.Option) Option {
+	return func(a *Agent) { a.genOpts = opts }
+}
+
+// Agent orchestrates a model and a set of tools.
+// An Agent is safe for concurrent use if its tools are.
+type Agent struct {
+	gen          generators.Generator
+	tools        *Registry
+	maxSteps     int
+	timeout      time.Duration
+	approve      ApprovalFunc
+	instructions string
+	genOpts      []generators.Option
+}
+
+// New returns an agent that uses gen and the tools in reg.
+// A nil reg means the agent has no tools.
+func New(gen generators.Generator, reg *Registry, opts ...Option) *Agent {
+	if reg == nil {
+		reg = NewRegistry()
+	}
+	a := &Agent{gen: gen, tools: reg, maxSteps: defaultMaxSteps}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Result is the outcome of a run.
+type Result struct {
+	Answer     string
+	Steps      int
+	Usage      generators.Usage
+	Transcript Transcript
+}
+
+// reply is the JSON object the model answers with.
+type reply struct {
+	Tool      string          `json:"tool"`
+	Arguments json.RawMessage `json:"arguments"`
+	Answer    *string         `json:"answer"`
+}
+
+// Run works towards goal until the model gives a final answer.
+//
+// The returned Result is never nil: when the run fails because the step or
+// time budget ran out, the approval hook failed or the generator failed, it
+// holds the transcript up to that point together with the error.
+//
+// Example:
+//
+//	a := agent.New(gen, agent.NewRegistry(weather, search),
+//	    agent.WithMaxSteps(8),
+//	    agent.WithTimeout(2*time.Minute),
+//	)
+//	res, err := a.Run(ctx, "Should I take an umbrella to Madrid tomorrow?")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	fmt.Println(res.Answer)
+func (a *Agent) Run(ctx context.Context, goal string) (*Result, error) {
+	if a.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.timeout)
+		defer cancel()
+	}
+
+	res := &Result{}
+	system := a.systemInstruction()
+	opts := append([]generators.Option{generators.WithSystemInstruction(system)}, a.genOpts...)
+	res.Transcript.add(Entry{Kind: KindGoal, Content: goal})
+
+	var history strings.Builder
+	for step := 1; step <= a.maxSteps; step++ {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		res.Steps = step
+
+		prompt := "Goal: " + goal + "\n\n" + history.String() + "Reply with a single JSON object."
+		resp, err := a.gen.Generate(ctx, prompt, opts...)
+		if err != nil {
+			res.Transcript.add(Entry{Step: step, Kind: KindError, Error: err.Error()})
+			return res, fmt.Errorf("agent: step %d: %w", step, err)
+		}
+		addUsage(&res.Usage, resp.Usage)
+		res.Transcript.add(Entry{Step: step, Kind: KindModel, Content: resp.Text})
+		fmt.Fprintf(&history, "Assistant: %s\n\n", strings.TrimSpace(resp.Text))
+
+		var r reply
+		if err := decodeReply(resp.Text, &r); err != nil {
+			observation := "Your reply was not a valid JSON object: " + err.Error()
+			res.Transcript.add(Entry{Step: step, Kind: KindError, Error: observation})
+			fmt.Fprintf(&history, "Observation: %s\n\n", observation)
+			continue
+		}
+		if r.Answer != nil && r.Tool == "" {
+			res.Answer = *r.Answer
+			res.Transcript.add(Entry{Step: step, Kind: KindAnswer, Content: res.Answer})
+			return res, nil
+		}
+
+		observation, err := a.call(ctx, step, Call{Tool: r.Tool, Arguments: r.Arguments}, &res.Transcript)
+		if err != nil {
+			return res, err
+		}
+		fmt.Fprintf(&history, "Observation: %s\n\n", observation)
+	}
+	return res, ErrMaxSteps
+}
+
+// call runs a tool call and returns the observation shown to the model.
+// Only failures of the approval hook are returned as errors; tool failures
+// are reported to the model so that it can recover.
+func (a *Agent) call(ctx context.Context, step int, call Call, tr *Transcript) (string, error) {
+	tr.add(Entry{Step: step, Kind: KindToolCall, Tool: call.Tool, Arguments: call.Arguments})
+
+	tool, ok := a.tools.Lookup(call.Tool)
+	if !ok {
+		msg := fmt.Sprintf("unknown tool %q", call.Tool)
+		tr.add(Entry{Step: step, Kind: KindToolResult, Tool: call.Tool, Error: msg})
+		return "error: " + msg, nil
+	}
+
+	if tool.Dangerous {
+		approved := false
+		if a.approve != nil {
+			var err error
+			if approved, err = a.approve(ctx, call); err != nil {
+				tr.add(Entry{Step: step, Kind: KindError, Tool: call.Tool, Error: err.Error()})
+				return "", fmt.Errorf("agent: approve %s: %w", call.Tool, err)
+			}
+		}
+		if !approved {
+			tr.add(Entry{Step: step, Kind: KindDenied, Tool: call.Tool})
+			return "error: the call was denied by the operator", nil
+		}
+	}
+
+	out, err := tool.Call(ctx, call.Arguments)
+	if err != nil {
+		tr.add(Entry{Step: step, Kind: KindToolResult, Tool: call.Tool, Error: err.Error()})
+		return "error: " + err.Error(), nil
+	}
+	tr.add(Entry{Step: step, Kind: KindToolResult, Tool: call.Tool, Content: out})
+	return out, nil
+}
+
+// systemInstruction describes the protocol and the tools to the model.
+func (a *Agent) systemInstruction() string {
+	var b strings.Builder
+	if a.instructions != "" {
+		b.WriteString(a.instructions)
+		b.WriteString("\n\n")
+	}
+	b.WriteString("You solve the user's goal step by step. ")
+	b.WriteString("Each reply must be exactly one JSON object and nothing else.\n")
+	b.WriteString(`To call a tool, reply {"tool": "<name>", "arguments": {...}}. `)
+	b.WriteString("The result is given back to you as an Observation.\n")
+	b.WriteString(`When you know the final answer, reply {"answer": "<text>"}.` + "\n")
+
+	tools := a.tools.Tools()
+	if len(tools) == 0 {
+		b.WriteString("\nNo tools are available.")
+		return b.String()
+	}
+	b.WriteString("\nAvailable tools:\n")
+	for _, t := range tools {
+		fmt.Fprintf(&b, "- %s: %s Arguments: %s\n", t.Name, t.Description, t.Parameters)
+	}
+	return b.String()
+}
+
+// decodeReply extracts the JSON object from the model's text, which may be
+// wrapped in a code fence or surrounded by prose.
+func decodeReply(text string, r *reply) error {
+	start := strings.IndexByte(text, '{')
+	end := strings.LastIndexByte(text, '}')
+	if start < 0 || end < start {
+		return errors.New("no JSON object found")
+	}
+	if err := json.Unmarshal([]byte(text[start:end+1]), r); err != nil {
+		return err
+	}
+	if r.Tool == "" && r.Answer == nil {
+		return errors.New(`the object has neither "tool" nor "answer"`)
+	}
+	return nil
+}
+
+// addUsage adds u to total.
+func addUsage(total *generators.Usage, u generators.Usage) {
+	total.PromptTokens += u.PromptTokens
+	total.CompletionTokens += u.CompletionTokens
+	total.ThoughtTokens += u.ThoughtTokens
+	total.CachedTokens += u.CachedTokens
+	total.TotalTokens += u.TotalTokens
+}
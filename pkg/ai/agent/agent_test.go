@@ -0,0 +1,178 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tnotstar/go-minolas/pkg/ai/generators"
+)
+
+// scriptedGenerator replies with the given texts in order and records the
+// prompts it receives.
+type scriptedGenerator struct {
+	replies []string
+	prompts []string
+	system  string
+}
+
+func (g *scriptedGenerator) Generate(_ context.Context, prompt string, opts ...generators.Option) (*generators.Response, error) {
+	cfg := &generators.Config{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	g.system = cfg.SystemInstruction
+	g.prompts = append(g.prompts, prompt)
+	if len(g.prompts) > len(g.replies) {
+		return nil, errors.New("no more replies")
+	}
+	return &generators.Response{
+		Text:  g.replies[len(g.prompts)-1],
+		Usage: generators.Usage{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12},
+	}, nil
+}
+
+func (g *scriptedGenerator) Stream(context.Context, string, ...generators.Option) (<-chan generators.StreamChunk, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (g *scriptedGenerator) Close() error { return nil }
+
+type cityArgs struct {
+	City string `json:"city"`
+}
+
+func testRegistry(deleted *[]string) *Registry {
+	return NewRegistry(
+		NewTool("weather", "Returns the weather.", func(_ context.Context, a cityArgs) (string, error) {
+			if a.City == "" {
+				return "", errors.New("city is required")
+			}
+			return "sunny in " + a.City, nil
+		}),
+		NewTool("delete", "Deletes a city.", func(_ context.Context, a cityArgs) (string, error) {
+			*deleted = append(*deleted, a.City)
+			return "deleted", nil
+		}, WithDangerous()),
+	)
+}
+
+func TestAgent_Run(t *testing.T) {
+	gen := &scriptedGenerator{replies: []string{
+		"Let me check.\n```json\n{\"tool\": \"weather\", \"arguments\": {}}\n```",
+		`{"tool": "weather", "arguments": {"city": "Madrid"}}`,
+		`{"answer": "It is sunny."}`,
+	}}
+	var deleted []string
+	a := New(gen, testRegistry(&deleted), WithInstructions("Be terse."))
+
+	res, err := a.Run(context.Background(), "Weather in Madrid?")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if res.Answer != "It is sunny." || res.Steps != 3 {
+		t.Errorf("Answer/Steps = %q/%d, want It is sunny./3", res.Answer, res.Steps)
+	}
+	if res.Usage.TotalTokens != 36 {
+		t.Errorf("Usage.TotalTokens = %d, want 36", res.Usage.TotalTokens)
+	}
+	if !strings.HasPrefix(gen.system, "Be terse.") || !strings.Contains(gen.system, "- weather: Returns the weather.") {
+		t.Errorf("system instruction = %q", gen.system)
+	}
+	if !strings.Contains(gen.prompts[1], "Observation: error: city is required") {
+		t.Errorf("second prompt = %q, want tool error observation", gen.prompts[1])
+	}
+	if !strings.Contains(gen.prompts[2], "Observation: sunny in Madrid") {
+		t.Errorf("third prompt = %q, want tool result observation", gen.prompts[2])
+	}
+
+	var kinds []string
+	for _, e := range res.Transcript {
+		kinds = append(kinds, e.Kind)
+	}
+	want := "goal,model,tool_call,tool_result,model,tool_call,tool_result,model,answer"
+	if strings.Join(kinds, ",") != want {
+		t.Errorf("transcript kinds = %s, want %s", strings.Join(kinds, ","), want)
+	}
+	if calls := res.Transcript.Calls(); len(calls) != 2 || string(calls[1].Arguments) != `{"city": "Madrid"}` {
+		t.Errorf("Calls() = %+v", calls)
+	}
+}
+
+func TestAgent_Approval(t *testing.T) {
+	replies := []string{
+		`{"tool": "delete", "arguments": {"city": "Paris"}}`,
+		`{"answer": "done"}`,
+	}
+
+	t.Run("denied without hook", func(t *testing.T) {
+		var deleted []string
+		gen := &scriptedGenerator{replies: replies}
+		res, err := New(gen, testRegistry(&deleted)).Run(context.Background(), "delete Paris")
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		if len(deleted) != 0 || res.Transcript[3].Kind != KindDenied {
+			t.Errorf("deleted = %v, transcript = %+v; want denied call", deleted, res.Transcript)
+		}
+		if !strings.Contains(gen.prompts[1], "denied") {
+			t.Errorf("prompt = %q, want denial observation", gen.prompts[1])
+		}
+	})
+
+	t.Run("approved", func(t *testing.T) {
+		var deleted []string
+		var asked Call
+		approve := func(_ context.Context, c Call) (bool, error) {
+			asked = c
+			return true, nil
+		}
+		gen := &scriptedGenerator{replies: replies}
+		if _, err := New(gen, testRegistry(&deleted), WithApproval(approve)).Run(context.Background(), "delete Paris"); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		if asked.Tool != "delete" || len(deleted) != 1 || deleted[0] != "Paris" {
+			t.Errorf("asked = %+v, deleted = %v", asked, deleted)
+		}
+	})
+
+	t.Run("hook error aborts", func(t *testing.T) {
+		var deleted []string
+		boom := errors.New("boom")
+		approve := func(context.Context, Call) (bool, error) { return false, boom }
+		gen := &scriptedGenerator{replies: replies}
+		_, err := New(gen, testRegistry(&deleted), WithApproval(approve)).Run(context.Background(), "delete Paris")
+		if !errors.Is(err, boom) {
+			t.Errorf("Run() error = %v, want boom", err)
+		}
+	})
+}
+
+func TestAgent_Budgets(t *testing.T) {
+	loop := []string{
+		`{"tool": "weather", "arguments": {"city": "A"}}`,
+		`not json at all`,
+		`{"tool": "missing"}`,
+	}
+	var deleted []string
+
+	gen := &scriptedGenerator{replies: loop}
+	res, err := New(gen, testRegistry(&deleted), WithMaxSteps(3)).Run(context.Background(), "loop")
+	if !errors.Is(err, ErrMaxSteps) {
+		t.Fatalf("Run() error = %v, want ErrMaxSteps", err)
+	}
+	if res.Steps != 3 || !strings.Contains(gen.prompts[2], "not a valid JSON object") {
+		t.Errorf("Steps = %d, prompts = %q", res.Steps, gen.prompts)
+	}
+	if last := res.Transcript[len(res.Transcript)-1]; last.Kind != KindToolResult || !strings.Contains(last.Error, "unknown tool") {
+		t.Errorf("last entry = %+v, want unknown tool result", last)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := New(&scriptedGenerator{}, nil, WithTimeout(time.Minute)).Run(ctx, "x"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() with canceled context error = %v, want context.Canceled", err)
+	}
+}
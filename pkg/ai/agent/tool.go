@@ -0,0 +1,186 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Tool is a Go function the model can call.
+// Tools are created with NewTool, which derives the argument description
+// shown to the model from the handler's argument type.
+type Tool struct {
+	Name        string
+	Description string
+
+	// Parameters describes the arguments as a JSON object mapping each
+	// field name to its type and, if set, its description.
+	Parameters string
+
+	// Dangerous tools are only run after the approval hook accepts the call.
+	Dangerous bool
+
+	call func(ctx context.Context, args json.RawMessage) (string, error)
+}
+
+// ToolOption is a functional option for configuring a Tool.
+type ToolOption func(*Tool)
+
+// WithDangerous marks the tool as dangerous, so every call must be
+// approved (see WithApproval).
+func WithDangerous() ToolOption {
+	return func(t *Tool) { t.Dangerous = true }
+}
+
+// NewTool creates a tool whose arguments are decoded from JSON into a
+// value of type A before fn is called. A is usually a struct; its fields
+// are described to the model using their json tag names and an optional
+// `desc` tag:
+//
+//	type weatherArgs struct {
+//	    City string `json:"city" desc:"city name, e.g. Madrid"`
+//	    Days int    `json:"days,omitempty"`
+//	}
+//
+//	weather := agent.NewTool("weather", "Returns the forecast for a city.",
+//	    func(ctx context.Context, args weatherArgs) (string, error) {
+//	        return forecast(ctx, args.City, args.Days)
+//	    })
+func NewTool[A any](name, description string, fn func(ctx context.Context, args A) (string, error), opts ...ToolOption) Tool {
+	t := Tool{
+		Name:        name,
+		Description: description,
+		Parameters:  describe(reflect.TypeFor[A]()),
+		call: func(ctx context.Context, raw json.RawMessage) (string, error) {
+			var args A
+			if len(raw) > 0 && string(raw) != "null" {
+				if err := json.Unmarshal(raw, &args); err != nil {
+					return "", fmt.Errorf("invalid arguments: %w", err)
+				}
+			}
+			return fn(ctx, args)
+		},
+	}
+	for _, opt := range opts {
+		opt(&t)
+	}
+	return t
+}
+
+// Call decodes args and runs the tool.
+func (t Tool) Call(ctx context.Context, args json.RawMessage) (string, error) {
+	if t.call == nil {
+		return "", fmt.Errorf("agent: tool %q has no handler", t.Name)
+	}
+	return t.call(ctx, args)
+}
+
+// Registry holds the tools available to an agent.
+// A Registry is not safe for concurrent modification.
+type Registry struct {
+	tools map[string]Tool
+}
+
+// NewRegistry returns a registry holding the given tools. It panics if two
+// tools share a name, as that is a programming error.
+func NewRegistry(tools ...Tool) *Registry {
+	r := &Registry{tools: make(map[string]Tool)}
+	for _, t := range tools {
+		if err := r.Register(t); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
+
+// Register adds a tool to the registry.
+func (r *Registry) Register(t Tool) error {
+	if t.Name == "" {
+		return errors.New("agent: tool name cannot be empty")
+	}
+	if _, dup := r.tools[t.Name]; dup {
+		return fmt.Errorf("agent: tool %q already registered", t.Name)
+	}
+	r.tools[t.Name] = t
+	return nil
+}
+
+// Lookup returns the tool with the given name.
+func (r *Registry) Lookup(name string) (Tool, bool) {
+	t, ok := r.tools[name]
+	return t, ok
+}
+
+// Tools returns the registered tools sorted by name.
+func (r *Registry) Tools() []Tool {
+	out := make([]Tool, 0, len(r.tools))
+	for _, t := range r.tools {
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// describe returns a compact description of the JSON form of t.
+func describe(t reflect.Type) string {
+	return describeType(t, make(map[reflect.Type]bool))
+}
+
+// describeType describes t, given the struct types being described in the
+// enclosing fields. A struct that contains itself is described as
+// "object (recursive)" where it repeats.
+func describeType(t reflect.Type, path map[reflect.Type]bool) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		if path[t] {
+			return "object (recursive)"
+		}
+		path[t] = true
+		defer delete(path, t)
+		var fields []string
+		for i := range t.NumField() {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			desc := describeType(f.Type, path)
+			if strings.Contains(opts, "omitempty") {
+				desc += ", optional"
+			}
+			if d := f.Tag.Get("desc"); d != "" {
+				desc += ": " + d
+			}
+			fields = append(fields, fmt.Sprintf("%q: %q", name, desc))
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	case reflect.Slice, reflect.Array:
+		return "array of " + describeType(t.Elem(), path)
+	case reflect.Map:
+		return "object of " + describeType(t.Elem(), path)
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	default:
+		return "any"
+	}
+}
@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+type weatherArgs struct {
+	City   string   `json:"city" desc:"city name"`
+	Days   int      `json:"days,omitempty"`
+	Units  []string `json:"units"`
+	hidden bool
+}
+
+func TestNewTool(t *testing.T) {
+	tool := NewTool("weather", "Forecast.", func(_ context.Context, args weatherArgs) (string, error) {
+		return args.City + strings.Repeat("!", args.Days), nil
+	}, WithDangerous())
+
+	want := `{"city": "string: city name", "days": "integer, optional", "units": "array of string"}`
+	if tool.Parameters != want {
+		t.Errorf("Parameters = %s, want %s", tool.Parameters, want)
+	}
+	if !tool.Dangerous {
+		t.Error("Dangerous = false, want true")
+	}
+
+	out, err := tool.Call(context.Background(), json.RawMessage(`{"city":"Madrid","days":2}`))
+	if err != nil || out != "Madrid!!" {
+		t.Errorf("Call() = %q, %v; want Madrid!!", out, err)
+	}
+	if _, err := tool.Call(context.Background(), json.RawMessage(`{"days":"two"}`)); err == nil {
+		t.Error("expected error for invalid arguments")
+	}
+}
+
+type treeNode struct {
+	Name     string      `json:"name"`
+	Children []*treeNode `json:"children"`
+	Meta     struct {
+		Parent *treeNode `json:"parent"`
+	} `json:"meta"`
+	Sibling weatherArgs `json:"sibling"`
+	Cousin  weatherArgs `json:"cousin"`
+}
+
+func TestNewTool_RecursiveArgs(t *testing.T) {
+	tool := NewTool("tree", "", func(context.Context, treeNode) (string, error) { return "", nil })
+	for _, want := range []string{
+		`"children": "array of object (recursive)"`,
+		`\"parent\": \"object (recursive)\"`,
+		`"cousin": "{\"city\"`,
+	} {
+		if !strings.Contains(tool.Parameters, want) {
+			t.Errorf("Parameters = %s, want it to contain %s", tool.Parameters, want)
+		}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	noop := func(context.Context, struct{}) (string, error) { return "", nil }
+	r := NewRegistry(NewTool("b", "", noop), NewTool("a", "", noop))
+
+	if err := r.Register(NewTool("a", "", noop)); err == nil {
+		t.Error("expected error for duplicate tool")
+	}
+	if err := r.Register(Tool{}); err == nil {
+		t.Error("expected error for unnamed tool")
+	}
+	if _, ok := r.Lookup("b"); !ok {
+		t.Error("Lookup(b) not found")
+	}
+	tools := r.Tools()
+	if len(tools) != 2 || tools[0].Name != "a" {
+		t.Errorf("Tools() = %+v, want a, b", tools)
+	}
+}
@@ -0,0 +1,50 @@
+package agent
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Kinds of transcript entries.
+const (
+	KindGoal       = "goal"
+	KindModel      = "model"
+	KindToolCall   = "tool_call"
+	KindToolResult = "tool_result"
+	KindDenied     = "denied"
+	KindAnswer     = "answer"
+	KindError      = "error"
+)
+
+// Entry is a single event of an agent run.
+// Step is zero for the goal and counts model calls from one afterwards.
+type Entry struct {
+	Step      int             `json:"step"`
+	Time      time.Time       `json:"time"`
+	Kind      string          `json:"kind"`
+	Tool      string          `json:"tool,omitempty"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+	Content   string          `json:"content,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// Transcript is the ordered record of an agent run, kept for auditing.
+// It marshals to JSON as an array of entries.
+type Transcript []Entry
+
+// add appends an entry, stamping it with the current time.
+func (t *Transcript) add(e Entry) {
+	e.Time = time.Now()
+	*t = append(*t, e)
+}
+
+// Calls returns the tool calls made during the run, in order.
+func (t Transcript) Calls() []Call {
+	var calls []Call
+	for _, e := range t {
+		if e.Kind == KindToolCall {
+			calls = append(calls, Call{Tool: e.Tool, Arguments: e.Arguments})
+		}
+	}
+	return calls
+}
@@ -0,0 +1,115 @@
+// Package eval runs prompt test suites against generators and compares
+// the results.
+//
+// A Suite lists cases, each a prompt with an optional expected output and
+// the rules used to score the answer. A Runner sends every case to every
+// Target concurrently, scores the answers and returns a Report with the
+// latency, token usage and scores of each answer. Reports can be stored in
+// a SQL database with a Store and printed as a comparison table.
+//
+// Suites are usually written in JSON:
+//
+//	{
+//	  "name": "capitals",
+//	  "system": "Answer with the name of the city only.",
+//	  "scorers": [{"type": "exact", "ignore_case": true}],
+//	  "cases": [
+//	    {"id": "fr", "prompt": "What is the capital of France?", "expected": "Paris"},
+//	    {"id": "json", "prompt": "Describe Paris as JSON with name and population.",
+//	     "scorers": [{"type": "json_schema", "schema": {"type": "object", "required": ["name"]}}]},
+//	    {"id": "essay", "prompt": "Why is Paris the capital of France?",
+//	     "scorers": [{"type": "judge", "criteria": "Historically accurate and concise."}]}
+//	  ]
+//	}
+package eval
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Suite is a named set of test cases.
+// Scorers apply to the cases that do not list their own.
+type Suite struct {
+	Name    string       `json:"name"`
+	System  string       `json:"system,omitempty"`
+	Scorers []ScorerSpec `json:"scorers,omitempty"`
+	Cases   []Case       `json:"cases"`
+}
+
+// Case is a single prompt to evaluate. System overrides the suite's system
+// instruction and Scorers, when set, replace the suite's scorers.
+type Case struct {
+	ID       string       `json:"id"`
+	Prompt   string       `json:"prompt"`
+	System   string       `json:"system,omitempty"`
+	Expected string       `json:"expected,omitempty"`
+	Scorers  []ScorerSpec `json:"scorers,omitempty"`
+}
+
+// LoadSuite reads a JSON suite from the file at path.
+func LoadSuite(path string) (*Suite, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("eval: %w", err)
+	}
+	defer f.Close()
+	return ReadSuite(f)
+}
+
+// ReadSuite reads a JSON suite from r and validates it. Cases without an
+// ID are numbered from 1 in order.
+func ReadSuite(r io.Reader) (*Suite, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var s Suite
+	if err := dec.Decode(&s); err != nil {
+		return nil, fmt.Errorf("eval: decode suite: %w", err)
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Validate checks that the suite has cases with prompts and unique IDs,
+// assigning IDs to the cases that lack one.
+func (s *Suite) Validate() error {
+	if len(s.Cases) == 0 {
+		return errors.New("eval: suite has no cases")
+	}
+	seen := make(map[string]bool, len(s.Cases))
+	for i := range s.Cases {
+		c := &s.Cases[i]
+		if c.ID == "" {
+			c.ID = fmt.Sprint(i + 1)
+		}
+		if seen[c.ID] {
+			return fmt.Errorf("eval: duplicate case ID %q", c.ID)
+		}
+		seen[c.ID] = true
+		if c.Prompt == "" {
+			return fmt.Errorf("eval: case %q has no prompt", c.ID)
+		}
+	}
+	return nil
+}
+
+// system returns the system instruction for c.
+func (s *Suite) system(c Case) string {
+	if c.System != "" {
+		return c.System
+	}
+	return s.System
+}
+
+// scorerSpecs returns the scoring rules for c.
+func (s *Suite) scorerSpecs(c Case) []ScorerSpec {
+	if len(c.Scorers) > 0 {
+		return c.Scorers
+	}
+	return s.Scorers
+}
@@ -0,0 +1,138 @@
+package eval
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/tnotstar/go-minolas/pkg/ai/generators"
+)
+
+// Report holds the results of running a suite against a set of targets.
+// Results are ordered by target, then by case, as given to Run.
+type Report struct {
+	ID       string
+	Suite    string
+	Started  time.Time
+	Duration time.Duration
+	Targets  []string
+	Results  []Result
+}
+
+// Summary aggregates the results of one target.
+type Summary struct {
+	Target      string
+	Cases       int
+	Passed      int
+	Errors      int
+	MeanScore   float64
+	MeanLatency time.Duration
+	P95Latency  time.Duration
+	Usage       generators.Usage
+}
+
+// PassRate returns the fraction of cases that passed.
+func (s Summary) PassRate() float64 {
+	if s.Cases == 0 {
+		return 0
+	}
+	return float64(s.Passed) / float64(s.Cases)
+}
+
+// Summaries returns one Summary per target, in the report's target order.
+// Latencies only include the cases that did not fail with an error.
+func (r *Report) Summaries() []Summary {
+	out := make([]Summary, len(r.Targets))
+	for i, name := range r.Targets {
+		s := Summary{Target: name}
+		var latencies []time.Duration
+		var score float64
+		for _, res := range r.Results {
+			if res.Target != name {
+				continue
+			}
+			s.Cases++
+			score += res.Score
+			if res.Passed {
+				s.Passed++
+			}
+			if res.Error != "" {
+				s.Errors++
+				continue
+			}
+			latencies = append(latencies, res.Latency)
+			s.Usage.PromptTokens += res.Usage.PromptTokens
+			s.Usage.CompletionTokens += res.Usage.CompletionTokens
+			s.Usage.ThoughtTokens += res.Usage.ThoughtTokens
+			s.Usage.CachedTokens += res.Usage.CachedTokens
+			s.Usage.TotalTokens += res.Usage.TotalTokens
+		}
+		if s.Cases > 0 {
+			s.MeanScore = score / float64(s.Cases)
+		}
+		if len(latencies) > 0 {
+			var sum time.Duration
+			for _, l := range latencies {
+				sum += l
+			}
+			s.MeanLatency = sum / time.Duration(len(latencies))
+			slices.Sort(latencies)
+			s.P95Latency = latencies[(len(latencies)*95+99)/100-1]
+		}
+		out[i] = s
+	}
+	return out
+}
+
+// WriteText writes a plain-text comparison of the targets: a summary
+// table followed by the score of every case on every target. Failed cases
+// are marked with "✗" and errors with "ERR".
+func (r *Report) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "Suite %q, run %s (%s)\n\n", r.Suite, r.ID, r.Duration.Round(time.Millisecond))
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "target\tscore\tpassed\terrors\tmean latency\tp95 latency\tprompt tok\tcompletion tok\ttotal tok\t")
+	for _, s := range r.Summaries() {
+		fmt.Fprintf(tw, "%s\t%.3f\t%d/%d\t%d\t%s\t%s\t%d\t%d\t%d\t\n",
+			s.Target, s.MeanScore, s.Passed, s.Cases, s.Errors,
+			s.MeanLatency.Round(time.Millisecond), s.P95Latency.Round(time.Millisecond),
+			s.Usage.PromptTokens, s.Usage.CompletionTokens, s.Usage.TotalTokens)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	byKey := make(map[[2]string]Result, len(r.Results))
+	var cases []string
+	for _, res := range r.Results {
+		byKey[[2]string{res.Target, res.CaseID}] = res
+		if !slices.Contains(cases, res.CaseID) {
+			cases = append(cases, res.CaseID)
+		}
+	}
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "case\t%s\t\n", strings.Join(r.Targets, "\t"))
+	for _, id := range cases {
+		cells := make([]string, len(r.Targets))
+		for i, t := range r.Targets {
+			res, ok := byKey[[2]string{t, id}]
+			switch {
+			case !ok:
+				cells[i] = "-"
+			case res.Error != "":
+				cells[i] = "ERR"
+			case res.Passed:
+				cells[i] = fmt.Sprintf("%.2f ✓", res.Score)
+			default:
+				cells[i] = fmt.Sprintf("%.2f ✗", res.Score)
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t\n", id, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
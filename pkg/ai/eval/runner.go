@@ -0,0 +1,234 @@
+package eval
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tnotstar/go-minolas/pkg/ai/generators"
+)
+
+// Target is a generator under evaluation.
+type Target struct {
+	Name      string
+	Generator generators.Generator
+	Options   []generators.Option
+}
+
+// OpenTarget opens aiurl with generators.Open. The target is named after
+// the URL if name is empty. Close the target's generator when done.
+func OpenTarget(ctx context.Context, name, aiurl string) (Target, error) {
+	gen, err := generators.Open(ctx, aiurl)
+	if err != nil {
+		return Target{}, fmt.Errorf("eval: open %s: %w", aiurl, err)
+	}
+	if name == "" {
+		name = aiurl
+	}
+	return Target{Name: name, Generator: gen}, nil
+}
+
+// Result is the outcome of one case on one target. Score is the mean of
+// the scores and Passed is set when every scorer passed. A generation
+// error fails the case with a zero score.
+type Result struct {
+	Target  string
+	CaseID  string
+	Output  string
+	Error   string
+	Latency time.Duration
+	Usage   generators.Usage
+	Scores  []Score
+	Score   float64
+	Passed  bool
+}
+
+// RunnerOption configures a Runner.
+type RunnerOption func(*Runner)
+
+// WithConcurrency sets how many generation requests run at once across
+// all targets. The default is 4.
+func WithConcurrency(n int) RunnerOption {
+	return func(r *Runner) {
+		if n > 0 {
+			r.concurrency = n
+		}
+	}
+}
+
+// WithJudge sets the generator used by "judge" scorers.
+func WithJudge(gen generators.Generator) RunnerOption {
+	return func(r *Runner) { r.judge = gen }
+}
+
+// WithStore saves every report produced by Run in s.
+func WithStore(s *Store) RunnerOption {
+	return func(r *Runner) { r.store = s }
+}
+
+// WithGenerateOptions adds options to every generation request, before
+// the target's own options.
+func WithGenerateOptions(opts ...generators.Option) RunnerOption {
+	return func(r *Runner) { r.opts = append(r.opts, opts...) }
+}
+
+// WithProgress calls fn after each case completes. Calls are serialized.
+func WithProgress(fn func(done, total int, res Result)) RunnerOption {
+	return func(r *Runner) { r.progress = fn }
+}
+
+// Runner evaluates suites against targets.
+type Runner struct {
+	concurrency int
+	judge       generators.Generator
+	store       *Store
+	opts        []generators.Option
+	progress    func(done, total int, res Result)
+}
+
+// NewRunner returns a Runner configured by opts.
+func NewRunner(opts ...RunnerOption) *Runner {
+	r := &Runner{concurrency: 4}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Run sends every case of suite to every target and scores the answers.
+// Target names must be unique. Targets and scoring rules are checked
+// before any request is made. If ctx is
+// canceled, Run returns the report of the cases completed so far together
+// with the context's error. The report is saved if a store is configured.
+func (r *Runner) Run(ctx context.Context, suite *Suite, targets ...Target) (*Report, error) {
+	if len(targets) == 0 {
+		return nil, errors.New("eval: no targets")
+	}
+	names := make(map[string]bool, len(targets))
+	for _, t := range targets {
+		if names[t.Name] {
+			return nil, fmt.Errorf("eval: duplicate target name %q", t.Name)
+		}
+		names[t.Name] = true
+	}
+	if err := suite.Validate(); err != nil {
+		return nil, err
+	}
+	scorers := make([][]Scorer, len(suite.Cases))
+	for i, c := range suite.Cases {
+		if len(suite.scorerSpecs(c)) == 0 {
+			return nil, fmt.Errorf("eval: case %q has no scorers", c.ID)
+		}
+		for _, spec := range suite.scorerSpecs(c) {
+			sc, err := spec.build(c, r.judge)
+			if err != nil {
+				return nil, fmt.Errorf("%w (case %q)", err, c.ID)
+			}
+			scorers[i] = append(scorers[i], sc)
+		}
+	}
+
+	report := &Report{ID: newRunID(), Suite: suite.Name, Started: time.Now()}
+	for _, t := range targets {
+		report.Targets = append(report.Targets, t.Name)
+	}
+
+	total := len(targets) * len(suite.Cases)
+	results := make([]*Result, total)
+	sem := make(chan struct{}, r.concurrency)
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		done int
+	)
+loop:
+	for ti, t := range targets {
+		for ci, c := range suite.Cases {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				break loop
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				res := r.runCase(ctx, suite, t, c, scorers[ci])
+				if ctx.Err() != nil {
+					return // interrupted cases are not reported
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				results[ti*len(suite.Cases)+ci] = &res
+				done++
+				if r.progress != nil {
+					r.progress(done, total, res)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	for _, res := range results {
+		if res != nil {
+			report.Results = append(report.Results, *res)
+		}
+	}
+	report.Duration = time.Since(report.Started)
+	if err := ctx.Err(); err != nil {
+		return report, err
+	}
+	if r.store != nil {
+		if err := r.store.Save(ctx, report); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// runCase generates and scores the answer of one target to one case.
+func (r *Runner) runCase(ctx context.Context, suite *Suite, t Target, c Case, scorers []Scorer) Result {
+	res := Result{Target: t.Name, CaseID: c.ID}
+
+	opts := append([]generators.Option(nil), r.opts...)
+	if sys := suite.system(c); sys != "" {
+		opts = append(opts, generators.WithSystemInstruction(sys))
+	}
+	opts = append(opts, t.Options...)
+
+	start := time.Now()
+	resp, err := t.Generator.Generate(ctx, c.Prompt, opts...)
+	res.Latency = time.Since(start)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.Output, res.Usage = resp.Text, resp.Usage
+
+	res.Passed = len(scorers) > 0
+	for _, sc := range scorers {
+		s, err := sc.Score(ctx, c, resp.Text)
+		if err != nil {
+			s = Score{Reason: "error: " + err.Error()}
+		}
+		s.Scorer = sc.Name()
+		res.Scores = append(res.Scores, s)
+		res.Score += s.Value
+		res.Passed = res.Passed && s.Passed
+	}
+	if len(res.Scores) > 0 {
+		res.Score /= float64(len(res.Scores))
+	}
+	return res
+}
+
+// newRunID returns a sortable, unique identifier for a run.
+func newRunID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return time.Now().UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,206 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tnotstar/go-minolas/pkg/ai/generators"
+)
+
+const suiteJSON = `{
+	"name": "capitals",
+	"system": "Answer with the city only.",
+	"scorers": [{"type": "exact", "ignore_case": true}],
+	"cases": [
+		{"id": "fr", "prompt": "Capital of France?", "expected": "Paris"},
+		{"id": "it", "prompt": "Capital of Italy?", "expected": "Rome"},
+		{"prompt": "Capital of Spain?", "expected": "Madrid",
+		 "scorers": [{"type": "contains"}, {"type": "regex", "pattern": "(?i)^madrid$"}]}
+	]
+}`
+
+func TestReadSuite(t *testing.T) {
+	s, err := ReadSuite(strings.NewReader(suiteJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Name != "capitals" || len(s.Cases) != 3 || s.Cases[2].ID != "3" {
+		t.Errorf("suite = %+v", s)
+	}
+
+	for _, bad := range []string{
+		`{"name": "x", "cases": []}`,
+		`{"cases": [{"id": "a", "prompt": "p"}, {"id": "a", "prompt": "q"}]}`,
+		`{"cases": [{"id": "a"}]}`,
+		`{"cases": [{"prompt": "p", "expect": "typo"}]}`,
+	} {
+		if _, err := ReadSuite(strings.NewReader(bad)); err == nil {
+			t.Errorf("ReadSuite(%s) succeeded", bad)
+		}
+	}
+}
+
+// answers returns a generator answering from a map of prompts.
+func answers(m map[string]string, usage generators.Usage) funcGenerator {
+	return func(_ context.Context, prompt string, cfg *generators.Config) (*generators.Response, error) {
+		if cfg.SystemInstruction != "Answer with the city only." {
+			return nil, errors.New("missing system instruction")
+		}
+		a, ok := m[prompt]
+		if !ok {
+			return nil, errors.New("unknown prompt")
+		}
+		return &generators.Response{Text: a, Usage: usage}, nil
+	}
+}
+
+func TestRunner(t *testing.T) {
+	suite, err := ReadSuite(strings.NewReader(suiteJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	good := answers(map[string]string{
+		"Capital of France?": "paris",
+		"Capital of Italy?":  "Rome",
+		"Capital of Spain?":  "Madrid",
+	}, generators.Usage{PromptTokens: 10, CompletionTokens: 1, TotalTokens: 11})
+	bad := answers(map[string]string{
+		"Capital of France?": "Lyon",
+		"Capital of Spain?":  "madrid",
+	}, generators.Usage{})
+
+	store, err := OpenStore(context.Background(), "sqlite:"+filepath.Join(t.TempDir(), "eval.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	var calls atomic.Int32
+	runner := NewRunner(WithConcurrency(2), WithStore(store), WithProgress(func(done, total int, _ Result) {
+		calls.Add(1)
+		if total != 6 {
+			t.Errorf("total = %d", total)
+		}
+	}))
+	report, err := runner.Run(context.Background(), suite,
+		Target{Name: "good", Generator: good},
+		Target{Name: "bad", Generator: bad})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 6 || len(report.Results) != 6 {
+		t.Fatalf("progress calls = %d, results = %d", calls.Load(), len(report.Results))
+	}
+	if r := report.Results[3]; r.Target != "bad" || r.CaseID != "fr" {
+		t.Errorf("results not ordered by target and case: %+v", r)
+	}
+	spain := report.Results[5]
+	if len(spain.Scores) != 2 || spain.Scores[0].Passed || !spain.Scores[1].Passed || spain.Score != 0.5 || spain.Passed {
+		t.Errorf("bad/3 = %+v", spain)
+	}
+	if report.Results[4].Error != "unknown prompt" {
+		t.Errorf("bad/it error = %q", report.Results[4].Error)
+	}
+
+	sums := report.Summaries()
+	if s := sums[0]; s.Passed != 3 || s.MeanScore != 1 || s.Usage.TotalTokens != 33 || s.PassRate() != 1 {
+		t.Errorf("good summary = %+v", s)
+	}
+	if s := sums[1]; s.Passed != 0 || s.Errors != 1 || s.MeanScore != 0.5/3 {
+		t.Errorf("bad summary = %+v", s)
+	}
+
+	var out strings.Builder
+	if err := report.WriteText(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"capitals", "good", "3/3", "0/3", "ERR", "1.00 ✓", "0.50 ✗"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("report lacks %q:\n%s", want, out.String())
+		}
+	}
+
+	loaded, err := store.Load(context.Background(), report.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Suite != "capitals" || len(loaded.Targets) != 2 || len(loaded.Results) != 6 {
+		t.Fatalf("loaded = %+v", loaded)
+	}
+	if got := loaded.Results[5]; len(got.Scores) != 2 || got.Scores[1].Scorer != ScorerRegex || got.Score != 0.5 {
+		t.Errorf("loaded bad/3 = %+v", got)
+	}
+	runs, err := store.Runs(context.Background(), "")
+	if err != nil || len(runs) != 1 || runs[0].ID != report.ID {
+		t.Errorf("runs = %+v, %v", runs, err)
+	}
+}
+
+func TestRunnerConcurrency(t *testing.T) {
+	var running, peak atomic.Int32
+	slow := funcGenerator(func(ctx context.Context, _ string, _ *generators.Config) (*generators.Response, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		return &generators.Response{Text: "x"}, nil
+	})
+	suite := &Suite{Scorers: []ScorerSpec{{Type: ScorerContains}}}
+	for range 6 {
+		suite.Cases = append(suite.Cases, Case{Prompt: "p", Expected: "x"})
+	}
+	_, err := NewRunner(WithConcurrency(3)).Run(context.Background(), suite,
+		Target{Name: "a", Generator: slow}, Target{Name: "b", Generator: slow})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := peak.Load(); p < 2 || p > 3 {
+		t.Errorf("peak concurrency = %d, want 2..3", p)
+	}
+}
+
+func TestRunnerErrors(t *testing.T) {
+	var calls atomic.Int32
+	gen := funcGenerator(func(context.Context, string, *generators.Config) (*generators.Response, error) {
+		calls.Add(1)
+		return &generators.Response{}, nil
+	})
+	suite := &Suite{Cases: []Case{{Prompt: "p"}}}
+	if _, err := NewRunner().Run(context.Background(), suite, Target{Generator: gen}); err == nil {
+		t.Error("case without scorers accepted")
+	}
+	suite.Scorers = []ScorerSpec{{Type: ScorerJudge}}
+	if _, err := NewRunner().Run(context.Background(), suite, Target{Generator: gen}); err == nil {
+		t.Error("judge scorer accepted without a judge")
+	}
+	if _, err := NewRunner().Run(context.Background(), suite); err == nil {
+		t.Error("run without targets accepted")
+	}
+	suite.Scorers = []ScorerSpec{{Type: ScorerExact}}
+	if _, err := NewRunner().Run(context.Background(), suite, Target{Name: "a", Generator: gen}, Target{Name: "a", Generator: gen}); err == nil {
+		t.Error("duplicate target names accepted")
+	}
+	if n := calls.Load(); n != 0 {
+		t.Errorf("%d requests sent by rejected runs", n)
+	}
+	if _, err := OpenStore(context.Background(), "sqlserver://sa:pw@localhost/eval"); err == nil {
+		t.Error("OpenStore() accepted a SQL Server URL")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report, err := NewRunner().Run(ctx, suite, Target{Generator: gen})
+	if !errors.Is(err, context.Canceled) || report == nil {
+		t.Errorf("canceled run: report %v, err %v", report, err)
+	}
+}
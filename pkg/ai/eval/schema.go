@@ -0,0 +1,194 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"unicode/utf8"
+)
+
+// validateSchema checks v, as decoded by encoding/json, against a JSON
+// Schema and returns the violations found, prefixed with their path.
+//
+// It implements the subset of JSON Schema used to describe model output:
+// type, enum, const, properties, required, additionalProperties, items,
+// minItems, maxItems, minLength, maxLength, pattern, minimum, maximum,
+// exclusiveMinimum, exclusiveMaximum, allOf, anyOf, oneOf and not. Other
+// keywords, including $ref, are ignored.
+func validateSchema(schema, v any, path string) []string {
+	switch s := schema.(type) {
+	case bool:
+		if !s {
+			return []string{path + ": no value is allowed"}
+		}
+		return nil
+	case map[string]any:
+		return validateObjectSchema(s, v, path)
+	}
+	return nil
+}
+
+func validateObjectSchema(s map[string]any, v any, path string) []string {
+	var errs []string
+	fail := func(format string, args ...any) {
+		errs = append(errs, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	if t, ok := s["type"]; ok && !matchesType(t, v) {
+		fail("expected %v, got %s", t, typeName(v))
+		return errs // the other keywords would only add noise
+	}
+	if enum, ok := s["enum"].([]any); ok && !slices.ContainsFunc(enum, func(e any) bool { return reflect.DeepEqual(e, v) }) {
+		fail("value is not one of %v", enum)
+	}
+	if c, ok := s["const"]; ok && !reflect.DeepEqual(c, v) {
+		fail("value must be %v", c)
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		props, _ := s["properties"].(map[string]any)
+		if req, ok := s["required"].([]any); ok {
+			for _, r := range req {
+				if name, ok := r.(string); ok {
+					if _, ok := v[name]; !ok {
+						fail("missing required property %q", name)
+					}
+				}
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if ps, ok := props[k]; ok {
+				errs = append(errs, validateSchema(ps, v[k], path+"."+k)...)
+			} else if ap, ok := s["additionalProperties"]; ok {
+				if b, ok := ap.(bool); ok && !b {
+					fail("unexpected property %q", k)
+				} else {
+					errs = append(errs, validateSchema(ap, v[k], path+"."+k)...)
+				}
+			}
+		}
+	case []any:
+		if n, ok := number(s["minItems"]); ok && float64(len(v)) < n {
+			fail("expected at least %v items, got %d", n, len(v))
+		}
+		if n, ok := number(s["maxItems"]); ok && float64(len(v)) > n {
+			fail("expected at most %v items, got %d", n, len(v))
+		}
+		if items, ok := s["items"]; ok {
+			for i, item := range v {
+				errs = append(errs, validateSchema(items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	case string:
+		n := float64(utf8.RuneCountInString(v))
+		if lo, ok := number(s["minLength"]); ok && n < lo {
+			fail("string shorter than %v", lo)
+		}
+		if hi, ok := number(s["maxLength"]); ok && n > hi {
+			fail("string longer than %v", hi)
+		}
+		if p, ok := s["pattern"].(string); ok {
+			if re, err := regexp.Compile(p); err == nil && !re.MatchString(v) {
+				fail("string does not match %s", p)
+			}
+		}
+	case float64:
+		if lo, ok := number(s["minimum"]); ok && v < lo {
+			fail("%v is less than %v", v, lo)
+		}
+		if hi, ok := number(s["maximum"]); ok && v > hi {
+			fail("%v is greater than %v", v, hi)
+		}
+		if lo, ok := number(s["exclusiveMinimum"]); ok && v <= lo {
+			fail("%v is not greater than %v", v, lo)
+		}
+		if hi, ok := number(s["exclusiveMaximum"]); ok && v >= hi {
+			fail("%v is not less than %v", v, hi)
+		}
+	}
+
+	if all, ok := s["allOf"].([]any); ok {
+		for _, sub := range all {
+			errs = append(errs, validateSchema(sub, v, path)...)
+		}
+	}
+	if anyOf, ok := s["anyOf"].([]any); ok && countValid(anyOf, v, path) == 0 {
+		fail("value does not match any of the anyOf schemas")
+	}
+	if oneOf, ok := s["oneOf"].([]any); ok {
+		if n := countValid(oneOf, v, path); n != 1 {
+			fail("value matches %d of the oneOf schemas, want exactly 1", n)
+		}
+	}
+	if not, ok := s["not"]; ok && len(validateSchema(not, v, path)) == 0 {
+		fail("value must not match the not schema")
+	}
+	return errs
+}
+
+// countValid returns how many of schemas v is valid against.
+func countValid(schemas []any, v any, path string) int {
+	n := 0
+	for _, sub := range schemas {
+		if len(validateSchema(sub, v, path)) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// matchesType reports whether v has the type t, which is a type name or a
+// list of them.
+func matchesType(t, v any) bool {
+	switch t := t.(type) {
+	case string:
+		if t == "integer" {
+			f, ok := v.(float64)
+			return ok && f == math.Trunc(f)
+		}
+		return t == typeName(v) || (t == "number" && typeName(v) == "integer")
+	case []any:
+		return slices.ContainsFunc(t, func(e any) bool { return matchesType(e, v) })
+	}
+	return true
+}
+
+// typeName returns the JSON Schema type name of v.
+func typeName(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// number returns v as a float64 if it is a JSON number.
+func number(v any) (float64, bool) {
+	f, ok := v.(float64)
+	return f, ok
+}
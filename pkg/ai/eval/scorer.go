@@ -0,0 +1,235 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/tnotstar/go-minolas/pkg/ai/generators"
+	"github.com/tnotstar/go-minolas/pkg/ai/parse"
+)
+
+// Score is the result of one scoring rule. Value ranges from 0 to 1.
+type Score struct {
+	Scorer string  `json:"scorer"`
+	Value  float64 `json:"value"`
+	Passed bool    `json:"passed"`
+	Reason string  `json:"reason,omitempty"`
+}
+
+// Scorer rates the output produced for a case.
+type Scorer interface {
+	// Name identifies the scorer in reports. Names should be unique
+	// within a case.
+	Name() string
+
+	// Score rates output. An error means the output could not be rated,
+	// not that it is wrong.
+	Score(ctx context.Context, c Case, output string) (Score, error)
+}
+
+// Scorer types accepted in ScorerSpec.Type.
+const (
+	ScorerExact      = "exact"
+	ScorerContains   = "contains"
+	ScorerRegex      = "regex"
+	ScorerJSONSchema = "json_schema"
+	ScorerJudge      = "judge"
+)
+
+// ScorerSpec describes a scoring rule in a suite file. The fields used
+// depend on Type:
+//
+//   - "exact" and "contains" compare the output with the case's expected
+//     output, ignoring case if IgnoreCase is set;
+//   - "regex" matches Pattern, or the expected output if Pattern is empty;
+//   - "json_schema" requires the output to contain JSON valid against
+//     Schema, or any JSON if Schema is empty;
+//   - "judge" asks the runner's judge generator to rate the output against
+//     Criteria, passing at Threshold (default 0.7).
+type ScorerSpec struct {
+	Type       string          `json:"type"`
+	Name       string          `json:"name,omitempty"`
+	IgnoreCase bool            `json:"ignore_case,omitempty"`
+	Pattern    string          `json:"pattern,omitempty"`
+	Schema     json.RawMessage `json:"schema,omitempty"`
+	Criteria   string          `json:"criteria,omitempty"`
+	Threshold  float64         `json:"threshold,omitempty"`
+}
+
+// build returns the Scorer described by the spec. judge may be nil if the
+// spec does not need it.
+func (s ScorerSpec) build(c Case, judge generators.Generator) (Scorer, error) {
+	var (
+		sc  Scorer
+		err error
+	)
+	switch s.Type {
+	case ScorerExact:
+		sc = ExactMatch(s.IgnoreCase)
+	case ScorerContains:
+		sc = Contains(s.IgnoreCase)
+	case ScorerRegex:
+		pattern := s.Pattern
+		if pattern == "" {
+			pattern = c.Expected
+		}
+		sc, err = Regex(pattern)
+	case ScorerJSONSchema:
+		sc, err = JSONSchema(s.Schema)
+	case ScorerJudge:
+		if judge == nil {
+			return nil, errors.New("eval: judge scorer requires a judge generator")
+		}
+		sc = Judge(judge, s.Criteria, s.Threshold)
+	default:
+		return nil, fmt.Errorf("eval: unknown scorer type %q", s.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if s.Name != "" {
+		sc = named{Scorer: sc, name: s.Name}
+	}
+	return sc, nil
+}
+
+// named overrides the name of a Scorer.
+type named struct {
+	Scorer
+	name string
+}
+
+func (n named) Name() string { return n.name }
+
+// ScorerFunc adapts a function to the Scorer interface.
+type ScorerFunc struct {
+	ID string
+	Fn func(ctx context.Context, c Case, output string) (Score, error)
+}
+
+// Name implements Scorer.
+func (f ScorerFunc) Name() string { return f.ID }
+
+// Score implements Scorer.
+func (f ScorerFunc) Score(ctx context.Context, c Case, output string) (Score, error) {
+	return f.Fn(ctx, c, output)
+}
+
+// passFail returns a Score of 1 or 0.
+func passFail(name string, ok bool, reason string) Score {
+	if ok {
+		return Score{Scorer: name, Value: 1, Passed: true}
+	}
+	return Score{Scorer: name, Reason: reason}
+}
+
+// ExactMatch passes when the output equals the expected output, ignoring
+// surrounding whitespace and, if ignoreCase is set, case.
+func ExactMatch(ignoreCase bool) Scorer {
+	return ScorerFunc{ID: ScorerExact, Fn: func(_ context.Context, c Case, output string) (Score, error) {
+		got, want := strings.TrimSpace(output), strings.TrimSpace(c.Expected)
+		ok := got == want || (ignoreCase && strings.EqualFold(got, want))
+		return passFail(ScorerExact, ok, fmt.Sprintf("got %.80q, want %.80q", got, want)), nil
+	}}
+}
+
+// Contains passes when the output contains the expected output, ignoring
+// case if ignoreCase is set.
+func Contains(ignoreCase bool) Scorer {
+	return ScorerFunc{ID: ScorerContains, Fn: func(_ context.Context, c Case, output string) (Score, error) {
+		got, want := output, strings.TrimSpace(c.Expected)
+		if ignoreCase {
+			got, want = strings.ToLower(got), strings.ToLower(want)
+		}
+		return passFail(ScorerContains, strings.Contains(got, want), fmt.Sprintf("output does not contain %.80q", c.Expected)), nil
+	}}
+}
+
+// Regex passes when the output matches pattern.
+func Regex(pattern string) (Scorer, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("eval: invalid regex scorer: %w", err)
+	}
+	return ScorerFunc{ID: ScorerRegex, Fn: func(_ context.Context, _ Case, output string) (Score, error) {
+		return passFail(ScorerRegex, re.MatchString(output), fmt.Sprintf("output does not match %s", re)), nil
+	}}, nil
+}
+
+// JSONSchema passes when the output contains a JSON value, found as by
+// parse.ExtractJSON, that is valid against schema. An empty schema accepts
+// any JSON. See validateSchema for the supported keywords.
+func JSONSchema(schema []byte) (Scorer, error) {
+	var s any = true
+	if len(schema) > 0 {
+		if err := json.Unmarshal(schema, &s); err != nil {
+			return nil, fmt.Errorf("eval: invalid JSON schema: %w", err)
+		}
+	}
+	return ScorerFunc{ID: ScorerJSONSchema, Fn: func(_ context.Context, _ Case, output string) (Score, error) {
+		raw, err := parse.ExtractJSON(output)
+		if err != nil {
+			return passFail(ScorerJSONSchema, false, err.Error()), nil
+		}
+		var v any
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return passFail(ScorerJSONSchema, false, err.Error()), nil
+		}
+		errs := validateSchema(s, v, "$")
+		return passFail(ScorerJSONSchema, len(errs) == 0, strings.Join(errs, "; ")), nil
+	}}, nil
+}
+
+// defaultCriteria is used by Judge when no criteria are given.
+const defaultCriteria = "The answer is correct, complete and relevant to the question."
+
+// judgePrompt is the prompt sent to the judge generator.
+const judgePrompt = `You are grading the answer an AI assistant gave to a question.
+
+Criteria: %s
+
+Question:
+%s
+%s
+Answer to grade:
+%s
+
+Rate how well the answer meets the criteria on a scale from 0 (not at all) to 10 (perfectly).
+Reply with JSON only, in the form {"score": <0-10>, "reason": "<one sentence>"}.`
+
+// Judge rates the output by asking gen, usually a stronger model, how well
+// it meets criteria. The judge's 0–10 rating is scaled to 0–1 and passes
+// at threshold, or 0.7 if threshold is zero. The expected output, if any,
+// is given to the judge as a reference answer.
+func Judge(gen generators.Generator, criteria string, threshold float64) Scorer {
+	if criteria == "" {
+		criteria = defaultCriteria
+	}
+	if threshold == 0 {
+		threshold = 0.7
+	}
+	return ScorerFunc{ID: ScorerJudge, Fn: func(ctx context.Context, c Case, output string) (Score, error) {
+		reference := ""
+		if c.Expected != "" {
+			reference = "\nReference answer:\n" + c.Expected + "\n"
+		}
+		prompt := fmt.Sprintf(judgePrompt, criteria, c.Prompt, reference, output)
+		resp, err := gen.Generate(ctx, prompt, generators.WithTemperature(0))
+		if err != nil {
+			return Score{}, fmt.Errorf("eval: judge: %w", err)
+		}
+		var verdict struct {
+			Score  float64 `json:"score"`
+			Reason string  `json:"reason"`
+		}
+		if err := parse.DecodeJSON(resp.Text, &verdict); err != nil {
+			return Score{}, fmt.Errorf("eval: judge reply: %w", err)
+		}
+		value := min(max(verdict.Score/10, 0), 1)
+		return Score{Scorer: ScorerJudge, Value: value, Passed: value >= threshold, Reason: verdict.Reason}, nil
+	}}
+}
@@ -0,0 +1,156 @@
+package eval
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/tnotstar/go-minolas/pkg/ai/generators"
+)
+
+// funcGenerator answers every prompt with fn.
+type funcGenerator func(ctx context.Context, prompt string, cfg *generators.Config) (*generators.Response, error)
+
+func (f funcGenerator) Generate(ctx context.Context, prompt string, opts ...generators.Option) (*generators.Response, error) {
+	cfg := &generators.Config{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return f(ctx, prompt, cfg)
+}
+
+func (f funcGenerator) Stream(ctx context.Context, prompt string, opts ...generators.Option) (<-chan generators.StreamChunk, error) {
+	panic("not used")
+}
+
+func (f funcGenerator) Close() error { return nil }
+
+func TestSimpleScorers(t *testing.T) {
+	re, err := Regex(`^\d+$`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := Case{Expected: "Paris"}
+	tests := []struct {
+		name   string
+		scorer Scorer
+		output string
+		want   bool
+	}{
+		{"exact", ExactMatch(false), " Paris\n", true},
+		{"exact case", ExactMatch(false), "paris", false},
+		{"exact ignore case", ExactMatch(true), "paris", true},
+		{"contains", Contains(false), "It is Paris.", true},
+		{"contains ignore case", Contains(true), "it is PARIS", true},
+		{"contains missing", Contains(false), "Lyon", false},
+		{"regex", re, "42", true},
+		{"regex mismatch", re, "42a", false},
+	}
+	for _, tt := range tests {
+		s, err := tt.scorer.Score(context.Background(), c, tt.output)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if s.Passed != tt.want || (s.Value == 1) != tt.want {
+			t.Errorf("%s: got %+v, want passed=%v", tt.name, s, tt.want)
+		}
+	}
+
+	if _, err := Regex("("); err == nil {
+		t.Error("Regex accepted an invalid pattern")
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	schema := `{
+		"type": "object",
+		"required": ["name", "tags"],
+		"additionalProperties": false,
+		"properties": {
+			"name": {"type": "string", "minLength": 2},
+			"age": {"type": "integer", "minimum": 0},
+			"tags": {"type": "array", "items": {"enum": ["a", "b"]}, "maxItems": 2},
+			"kind": {"anyOf": [{"const": "x"}, {"type": "null"}]}
+		}
+	}`
+	sc, err := JSONSchema([]byte(schema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		output string
+		want   bool
+		reason string
+	}{
+		{"```json\n{\"name\": \"Ada\", \"age\": 36, \"tags\": [\"a\"]}\n```", true, ""},
+		{`Sure: {"name": "Ada", "tags": [], "kind": null}`, true, ""},
+		{`{"name": "Ada"}`, false, `missing required property "tags"`},
+		{`{"name": "A", "tags": []}`, false, "$.name: string shorter than 2"},
+		{`{"name": "Ada", "age": 3.5, "tags": []}`, false, "$.age: expected integer"},
+		{`{"name": "Ada", "tags": ["c"]}`, false, "$.tags[0]: value is not one of"},
+		{`{"name": "Ada", "tags": [], "extra": 1}`, false, `unexpected property "extra"`},
+		{`{"name": "Ada", "tags": [], "kind": "y"}`, false, "anyOf"},
+		{`no json here`, false, "no JSON"},
+	}
+	for _, tt := range tests {
+		s, err := sc.Score(context.Background(), Case{}, tt.output)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.output, err)
+		}
+		if s.Passed != tt.want || !strings.Contains(s.Reason, tt.reason) {
+			t.Errorf("%s: got %+v, want passed=%v with reason containing %q", tt.output, s, tt.want, tt.reason)
+		}
+	}
+
+	anyJSON, _ := JSONSchema(nil)
+	if s, _ := anyJSON.Score(context.Background(), Case{}, `[1, 2]`); !s.Passed {
+		t.Errorf("empty schema rejected valid JSON: %+v", s)
+	}
+}
+
+func TestJudge(t *testing.T) {
+	var got string
+	judge := funcGenerator(func(_ context.Context, prompt string, cfg *generators.Config) (*generators.Response, error) {
+		got = prompt
+		if cfg.Temperature == nil || *cfg.Temperature != 0 {
+			t.Error("judge not run at temperature 0")
+		}
+		return &generators.Response{Text: "```json\n{\"score\": 8, \"reason\": \"Mostly right.\"}\n```"}, nil
+	})
+
+	c := Case{Prompt: "Capital of France?", Expected: "Paris"}
+	s, err := Judge(judge, "Be accurate.", 0).Score(context.Background(), c, "Paris, I think")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Value != 0.8 || !s.Passed || s.Reason != "Mostly right." {
+		t.Errorf("score = %+v", s)
+	}
+	for _, want := range []string{"Be accurate.", "Capital of France?", "Reference answer:\nParis", "Paris, I think"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("judge prompt lacks %q:\n%s", want, got)
+		}
+	}
+
+	s, _ = Judge(judge, "", 0.9).Score(context.Background(), c, "x")
+	if s.Passed {
+		t.Errorf("score 0.8 passed a 0.9 threshold")
+	}
+}
+
+func TestScorerSpecBuild(t *testing.T) {
+	if _, err := (ScorerSpec{Type: ScorerJudge}).build(Case{}, nil); err == nil {
+		t.Error("judge spec built without a judge")
+	}
+	if _, err := (ScorerSpec{Type: "fuzzy"}).build(Case{}, nil); err == nil {
+		t.Error("unknown type accepted")
+	}
+	sc, err := (ScorerSpec{Type: ScorerRegex, Name: "city"}).build(Case{Expected: "Par.s"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, _ := sc.Score(context.Background(), Case{}, "Paris")
+	if sc.Name() != "city" || !s.Passed {
+		t.Errorf("regex spec: name %q, score %+v", sc.Name(), s)
+	}
+}
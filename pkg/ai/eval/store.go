@@ -0,0 +1,294 @@
+package eval
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/tnotstar/go-minolas/pkg/db/sqlt"
+)
+
+// schema creates the tables used to persist reports. Times are stored as
+// Unix milliseconds and durations as milliseconds.
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS eval_runs (
+	id          VARCHAR(64)  NOT NULL PRIMARY KEY,
+	suite       VARCHAR(255) NOT NULL,
+	started_at  BIGINT       NOT NULL,
+	duration_ms BIGINT       NOT NULL
+)`,
+	`CREATE TABLE IF NOT EXISTS eval_targets (
+	run_id  VARCHAR(64)  NOT NULL,
+	ord     INTEGER      NOT NULL,
+	target  VARCHAR(255) NOT NULL,
+	PRIMARY KEY (run_id, ord)
+)`,
+	`CREATE TABLE IF NOT EXISTS eval_results (
+	run_id            VARCHAR(64)  NOT NULL,
+	ord               INTEGER      NOT NULL,
+	target            VARCHAR(255) NOT NULL,
+	case_id           VARCHAR(255) NOT NULL,
+	output            TEXT         NOT NULL,
+	error             TEXT         NOT NULL,
+	latency_ms        BIGINT       NOT NULL,
+	prompt_tokens     BIGINT       NOT NULL,
+	completion_tokens BIGINT       NOT NULL,
+	thought_tokens    BIGINT       NOT NULL,
+	total_tokens      BIGINT       NOT NULL,
+	score             DOUBLE PRECISION NOT NULL,
+	passed            BOOLEAN      NOT NULL,
+	PRIMARY KEY (run_id, target, case_id)
+)`,
+	`CREATE TABLE IF NOT EXISTS eval_scores (
+	run_id  VARCHAR(64)  NOT NULL,
+	target  VARCHAR(255) NOT NULL,
+	case_id VARCHAR(255) NOT NULL,
+	ord     INTEGER      NOT NULL,
+	scorer  VARCHAR(255) NOT NULL,
+	value   DOUBLE PRECISION NOT NULL,
+	passed  BOOLEAN      NOT NULL,
+	reason  TEXT         NOT NULL,
+	PRIMARY KEY (run_id, target, case_id, ord)
+)`,
+}
+
+// Store persists evaluation reports in a SQL database.
+// The statements use "?" placeholders, as supported by SQLite.
+type Store struct {
+	db *sql.DB
+}
+
+// RunInfo describes a stored run.
+type RunInfo struct {
+	ID       string
+	Suite    string
+	Started  time.Time
+	Duration time.Duration
+}
+
+// OpenStore opens the SQLite database at dburl through sqlt.Open and
+// prepares the evaluation tables. URLs of other databases are rejected,
+// as the statements are written for SQLite.
+//
+// Example:
+//
+//	store, err := eval.OpenStore(ctx, "sqlite:eval.db")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer store.Close()
+//	runner := eval.NewRunner(eval.WithStore(store))
+func OpenStore(ctx context.Context, dburl string) (*Store, error) {
+	u, err := url.Parse(dburl)
+	if err != nil {
+		return nil, fmt.Errorf("eval: open store: %w", err)
+	}
+	if !(&sqlt.SqliteOpener{}).CanOpen(u) {
+		return nil, fmt.Errorf("eval: open store: scheme %q not supported (expected sqlite or sqlite3)", u.Scheme)
+	}
+	db, err := sqlt.Open(dburl)
+	if err != nil {
+		return nil, fmt.Errorf("eval: open store: %w", err)
+	}
+	s, err := NewStore(ctx, db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewStore prepares the evaluation tables in an already open database.
+// Closing the returned Store closes db.
+func NewStore(ctx context.Context, db *sql.DB) (*Store, error) {
+	if db == nil {
+		return nil, errors.New("eval: database cannot be nil")
+	}
+	for _, stmt := range schema {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return nil, fmt.Errorf("eval: create schema: %w", err)
+		}
+	}
+	return &Store{db: db}, nil
+}
+
+// Save stores the report, replacing any earlier report with the same ID.
+func (s *Store) Save(ctx context.Context, r *Report) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("eval: begin save: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"eval_scores", "eval_results", "eval_targets"} {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE run_id = ?", r.ID); err != nil {
+			return fmt.Errorf("eval: save run: %w", err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM eval_runs WHERE id = ?", r.ID); err != nil {
+		return fmt.Errorf("eval: save run: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO eval_runs (id, suite, started_at, duration_ms) VALUES (?, ?, ?, ?)",
+		r.ID, r.Suite, r.Started.UnixMilli(), r.Duration.Milliseconds()); err != nil {
+		return fmt.Errorf("eval: save run: %w", err)
+	}
+	for i, t := range r.Targets {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO eval_targets (run_id, ord, target) VALUES (?, ?, ?)", r.ID, i, t); err != nil {
+			return fmt.Errorf("eval: save target: %w", err)
+		}
+	}
+	for i, res := range r.Results {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO eval_results (run_id, ord, target, case_id, output, error, latency_ms,
+				prompt_tokens, completion_tokens, thought_tokens, total_tokens, score, passed)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			r.ID, i, res.Target, res.CaseID, res.Output, res.Error, res.Latency.Milliseconds(),
+			res.Usage.PromptTokens, res.Usage.CompletionTokens, res.Usage.ThoughtTokens, res.Usage.TotalTokens,
+			res.Score, res.Passed); err != nil {
+			return fmt.Errorf("eval: save result: %w", err)
+		}
+		for j, sc := range res.Scores {
+			if _, err := tx.ExecContext(ctx,
+				`INSERT INTO eval_scores (run_id, target, case_id, ord, scorer, value, passed, reason)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+				r.ID, res.Target, res.CaseID, j, sc.Scorer, sc.Value, sc.Passed, sc.Reason); err != nil {
+				return fmt.Errorf("eval: save score: %w", err)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("eval: commit save: %w", err)
+	}
+	return nil
+}
+
+// Runs lists the stored runs, most recent first. An empty suite lists the
+// runs of every suite.
+func (s *Store) Runs(ctx context.Context, suite string) ([]RunInfo, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, suite, started_at, duration_ms FROM eval_runs
+		WHERE ? = '' OR suite = ? ORDER BY started_at DESC, id DESC`, suite, suite)
+	if err != nil {
+		return nil, fmt.Errorf("eval: list runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []RunInfo
+	for rows.Next() {
+		var (
+			ri             RunInfo
+			started, durMS int64
+		)
+		if err := rows.Scan(&ri.ID, &ri.Suite, &started, &durMS); err != nil {
+			return nil, fmt.Errorf("eval: scan run: %w", err)
+		}
+		ri.Started = time.UnixMilli(started)
+		ri.Duration = time.Duration(durMS) * time.Millisecond
+		runs = append(runs, ri)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("eval: list runs: %w", err)
+	}
+	return runs, nil
+}
+
+// Load returns the stored report with the given run ID, or sql.ErrNoRows
+// if there is none.
+func (s *Store) Load(ctx context.Context, id string) (*Report, error) {
+	var (
+		r              = &Report{ID: id}
+		started, durMS int64
+	)
+	err := s.db.QueryRowContext(ctx,
+		"SELECT suite, started_at, duration_ms FROM eval_runs WHERE id = ?", id).Scan(&r.Suite, &started, &durMS)
+	if err != nil {
+		return nil, fmt.Errorf("eval: load run %s: %w", id, err)
+	}
+	r.Started = time.UnixMilli(started)
+	r.Duration = time.Duration(durMS) * time.Millisecond
+
+	if err := s.loadTargets(ctx, r); err != nil {
+		return nil, err
+	}
+	if err := s.loadResults(ctx, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (s *Store) loadTargets(ctx context.Context, r *Report) error {
+	rows, err := s.db.QueryContext(ctx, "SELECT target FROM eval_targets WHERE run_id = ? ORDER BY ord", r.ID)
+	if err != nil {
+		return fmt.Errorf("eval: load targets: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t string
+		if err := rows.Scan(&t); err != nil {
+			return fmt.Errorf("eval: scan target: %w", err)
+		}
+		r.Targets = append(r.Targets, t)
+	}
+	return rows.Err()
+}
+
+func (s *Store) loadResults(ctx context.Context, r *Report) error {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT target, case_id, output, error, latency_ms, prompt_tokens, completion_tokens,
+			thought_tokens, total_tokens, score, passed
+		FROM eval_results WHERE run_id = ? ORDER BY ord`, r.ID)
+	if err != nil {
+		return fmt.Errorf("eval: load results: %w", err)
+	}
+	defer rows.Close()
+
+	index := make(map[[2]string]int)
+	for rows.Next() {
+		var (
+			res       Result
+			latencyMS int64
+		)
+		if err := rows.Scan(&res.Target, &res.CaseID, &res.Output, &res.Error, &latencyMS,
+			&res.Usage.PromptTokens, &res.Usage.CompletionTokens, &res.Usage.ThoughtTokens, &res.Usage.TotalTokens,
+			&res.Score, &res.Passed); err != nil {
+			return fmt.Errorf("eval: scan result: %w", err)
+		}
+		res.Latency = time.Duration(latencyMS) * time.Millisecond
+		index[[2]string{res.Target, res.CaseID}] = len(r.Results)
+		r.Results = append(r.Results, res)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("eval: load results: %w", err)
+	}
+
+	srows, err := s.db.QueryContext(ctx,
+		`SELECT target, case_id, scorer, value, passed, reason
+		FROM eval_scores WHERE run_id = ? ORDER BY target, case_id, ord`, r.ID)
+	if err != nil {
+		return fmt.Errorf("eval: load scores: %w", err)
+	}
+	defer srows.Close()
+	for srows.Next() {
+		var (
+			target, caseID string
+			sc             Score
+		)
+		if err := srows.Scan(&target, &caseID, &sc.Scorer, &sc.Value, &sc.Passed, &sc.Reason); err != nil {
+			return fmt.Errorf("eval: scan score: %w", err)
+		}
+		if i, ok := index[[2]string{target, caseID}]; ok {
+			r.Results[i].Scores = append(r.Results[i].Scores, sc)
+		}
+	}
+	return srows.Err()
+}
+
+// Close closes the underlying database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
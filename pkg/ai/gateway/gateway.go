@@ -0,0 +1,244 @@
+// Package gateway serves generators behind an OpenAI-compatible HTTP API.
+//
+// A Gateway implements http.Handler with the endpoints most OpenAI clients
+// use:
+//
+//	POST /v1/chat/completions
+//	POST /v1/completions
+//	GET  /v1/models
+//
+// Requests name a model, which is routed to a generator. A route whose
+// pattern is a plain name is an alias: the generator uses its own model,
+// whatever the name. A pattern containing wildcards, as understood by
+// path.Match, forwards the requested name to the generator with
+// generators.WithModel, so that "gemini-*" can serve every Gemini model:
+//
+//	gw, err := gateway.Open(ctx, map[string]string{
+//		"gpt-4o-mini": "ollama://localhost:11434/llama3.2",
+//		"gemini-*":    "gemini://",
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	defer gw.Close()
+//	http.ListenAndServe(":8080", gw)
+//
+// Generators have no notion of chat messages, so conversations are rendered
+// into a single prompt with memory.Render and system messages become the
+// system instruction.
+package gateway
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/tnotstar/go-minolas/pkg/ai/generators"
+)
+
+// Option configures a Gateway.
+type Option func(*Gateway)
+
+// WithRoute routes the model names matching pattern to gen. Routes are
+// tried in the order they were added, so more specific patterns should come
+// first. The gateway does not close generators added with WithRoute.
+func WithRoute(pattern string, gen generators.Generator) Option {
+	return func(g *Gateway) { g.routes = append(g.routes, route{pattern: pattern, gen: gen}) }
+}
+
+// WithAPIKeys requires requests to carry one of keys as a bearer token in
+// the Authorization header. Without this option the gateway is open.
+func WithAPIKeys(keys ...string) Option {
+	return func(g *Gateway) { g.keys = append(g.keys, keys...) }
+}
+
+// WithOwner sets the owned_by field reported for models. The default is
+// "minolas".
+func WithOwner(owner string) Option {
+	return func(g *Gateway) { g.owner = owner }
+}
+
+// route maps a model name pattern to a generator.
+type route struct {
+	pattern string
+	gen     generators.Generator
+}
+
+// wildcard reports whether the route forwards the requested model name.
+func (r route) wildcard() bool {
+	return strings.ContainsAny(r.pattern, `*?[\`)
+}
+
+// Gateway is an http.Handler exposing generators through an
+// OpenAI-compatible API. It is safe for concurrent use.
+type Gateway struct {
+	routes []route
+	keys   []string
+	owner  string
+	mux    *http.ServeMux
+
+	mu     sync.Mutex
+	owned  []generators.Generator // opened by Open, closed by Close
+	closed bool
+}
+
+// New returns a Gateway configured by opts.
+func New(opts ...Option) *Gateway {
+	g := &Gateway{owner: "minolas"}
+	for _, opt := range opts {
+		opt(g)
+	}
+	g.mux = http.NewServeMux()
+	g.mux.HandleFunc("POST /v1/chat/completions", g.chatCompletions)
+	g.mux.HandleFunc("POST /v1/completions", g.completions)
+	g.mux.HandleFunc("GET /v1/models", g.models)
+	g.mux.HandleFunc("GET /v1/models/{model...}", g.model)
+	return g
+}
+
+// Open returns a Gateway routing model name patterns to the generators
+// opened from the provider URLs in routes. Each distinct URL is opened once
+// with generators.Open. Plain names are routed before wildcard patterns;
+// within each group, patterns are ordered by decreasing length so that the
+// most specific one wins. Close closes the opened generators.
+func Open(ctx context.Context, routes map[string]string, opts ...Option) (*Gateway, error) {
+	patterns := make([]string, 0, len(routes))
+	for p := range routes {
+		patterns = append(patterns, p)
+	}
+	slices.SortFunc(patterns, func(a, b string) int {
+		wa, wb := route{pattern: a}.wildcard(), route{pattern: b}.wildcard()
+		switch {
+		case wa != wb && !wa:
+			return -1
+		case wa != wb:
+			return 1
+		case len(a) != len(b):
+			return len(b) - len(a)
+		}
+		return strings.Compare(a, b)
+	})
+
+	opened := make(map[string]generators.Generator)
+	var owned []generators.Generator
+	var routeOpts []Option
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			closeAll(owned)
+			return nil, fmt.Errorf("gateway: invalid route %q: %w", p, err)
+		}
+		aiurl := routes[p]
+		gen, ok := opened[aiurl]
+		if !ok {
+			var err error
+			if gen, err = generators.Open(ctx, aiurl); err != nil {
+				closeAll(owned)
+				return nil, fmt.Errorf("gateway: route %q: %w", p, err)
+			}
+			opened[aiurl] = gen
+			owned = append(owned, gen)
+		}
+		routeOpts = append(routeOpts, WithRoute(p, gen))
+	}
+
+	g := New(append(opts, routeOpts...)...)
+	g.owned = owned
+	return g, nil
+}
+
+// Close closes the generators opened by Open.
+func (g *Gateway) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closed {
+		return nil
+	}
+	g.closed = true
+	return closeAll(g.owned)
+}
+
+func closeAll(gens []generators.Generator) error {
+	var errs []error
+	for _, gen := range gens {
+		if err := gen.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ServeHTTP implements http.Handler.
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !g.authorized(r) {
+		writeError(w, http.StatusUnauthorized, "invalid_request_error", "invalid_api_key", "missing or invalid API key")
+		return
+	}
+	g.mux.ServeHTTP(w, r)
+}
+
+// authorized reports whether r carries one of the configured API keys.
+func (g *Gateway) authorized(r *http.Request) bool {
+	if len(g.keys) == 0 {
+		return true
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	for _, k := range g.keys {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(k)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve returns the generator for the requested model and the options
+// that select the model on it.
+func (g *Gateway) resolve(model string) (generators.Generator, []generators.Option, bool) {
+	for _, r := range g.routes {
+		if !r.wildcard() {
+			if r.pattern == model {
+				return r.gen, nil, true
+			}
+			continue
+		}
+		if ok, _ := path.Match(r.pattern, model); ok {
+			return r.gen, []generators.Option{generators.WithModel(model)}, true
+		}
+	}
+	return nil, nil, false
+}
+
+// models lists the plain model names the gateway routes. Wildcard patterns
+// cannot be enumerated and are not listed.
+func (g *Gateway) models(w http.ResponseWriter, r *http.Request) {
+	list := modelList{Object: "list", Data: []modelInfo{}}
+	for _, rt := range g.routes {
+		if !rt.wildcard() {
+			list.Data = append(list.Data, g.modelInfo(rt.pattern))
+		}
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// model describes a single model, if the gateway routes it.
+func (g *Gateway) model(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("model")
+	if _, _, ok := g.resolve(name); !ok {
+		writeError(w, http.StatusNotFound, "invalid_request_error", "model_not_found",
+			fmt.Sprintf("the model %q does not exist", name))
+		return
+	}
+	writeJSON(w, http.StatusOK, g.modelInfo(name))
+}
+
+func (g *Gateway) modelInfo(name string) modelInfo {
+	return modelInfo{ID: name, Object: "model", OwnedBy: g.owner}
+}
@@ -0,0 +1,417 @@
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tnotstar/go-minolas/pkg/ai/generators"
+)
+
+// fakeGenerator records the last request and replays a fixed answer.
+type fakeGenerator struct {
+	text     string
+	usage    generators.Usage
+	logprobs []generators.TokenLogprob
+	tail     string // sent along with the usage when streaming
+	err      error
+	prompt   string
+	cfg      generators.Config
+}
+
+func (f *fakeGenerator) record(prompt string, opts []generators.Option) {
+	f.prompt = prompt
+	f.cfg = generators.Config{}
+	for _, opt := range opts {
+		opt(&f.cfg)
+	}
+}
+
+func (f *fakeGenerator) Generate(ctx context.Context, prompt string, opts ...generators.Option) (*generators.Response, error) {
+	f.record(prompt, opts)
+	if f.err != nil {
+		return nil, f.err
+	}
+	resp := &generators.Response{Text: f.text, FinishReason: "MAX_TOKENS", Usage: f.usage}
+	if f.logprobs != nil {
+		resp.Candidates = []generators.Candidate{{Text: f.text, FinishReason: resp.FinishReason, Logprobs: f.logprobs}}
+	}
+	return resp, nil
+}
+
+func (f *fakeGenerator) Stream(ctx context.Context, prompt string, opts ...generators.Option) (<-chan generators.StreamChunk, error) {
+	f.record(prompt, opts)
+	ch := make(chan generators.StreamChunk, 8)
+	go func() {
+		defer close(ch)
+		for _, word := range strings.SplitAfter(f.text, " ") {
+			ch <- generators.StreamChunk{Text: word}
+		}
+		if f.err != nil {
+			ch <- generators.StreamChunk{Error: f.err}
+			return
+		}
+		u := f.usage
+		ch <- generators.StreamChunk{Text: f.tail, Usage: &u, FinishReason: "MAX_TOKENS"}
+	}()
+	return ch, nil
+}
+
+func (f *fakeGenerator) Close() error { return nil }
+
+func post(t *testing.T, srv *httptest.Server, path, body string) *http.Response {
+	t.Helper()
+	resp, err := http.Post(srv.URL+path, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST %s: %v", path, err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func decodeBody(t *testing.T, resp *http.Response, v any) {
+	t.Helper()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+}
+
+// events returns the data payloads of a server-sent event stream.
+func events(t *testing.T, r io.Reader) []string {
+	t.Helper()
+	var out []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		if data, ok := strings.CutPrefix(sc.Text(), "data: "); ok {
+			out = append(out, data)
+		}
+	}
+	return out
+}
+
+func TestChatCompletions(t *testing.T) {
+	gen := &fakeGenerator{text: "Hi there", usage: generators.Usage{PromptTokens: 5, CompletionTokens: 2, ThoughtTokens: 3, TotalTokens: 10}}
+	srv := httptest.NewServer(New(WithRoute("gpt-4o", gen)))
+	defer srv.Close()
+
+	resp := post(t, srv, "/v1/chat/completions", `{
+		"model": "gpt-4o",
+		"messages": [
+			{"role": "system", "content": "Be brief."},
+			{"role": "user", "content": "Hello"},
+			{"role": "assistant", "content": "Hi!"},
+			{"role": "user", "content": [{"type": "text", "text": "How are you?"}]}
+		],
+		"temperature": 0,
+		"max_tokens": 50,
+		"stop": "END",
+		"seed": 7
+	}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	var out chatResponse
+	decodeBody(t, resp, &out)
+
+	if out.Object != "chat.completion" || out.Model != "gpt-4o" || !strings.HasPrefix(out.ID, "chatcmpl-") {
+		t.Errorf("envelope = %+v", out)
+	}
+	if len(out.Choices) != 1 || *out.Choices[0].Message.Content != "Hi there" || *out.Choices[0].FinishReason != "length" {
+		t.Errorf("choices = %+v", out.Choices)
+	}
+	if u := out.Usage; u.PromptTokens != 5 || u.CompletionTokens != 5 || u.TotalTokens != 10 || u.CompletionTokensDetails.ReasoningTokens != 3 {
+		t.Errorf("usage = %+v", u)
+	}
+
+	want := "User: Hello\n\nAssistant: Hi!\n\nUser: How are you?\n\nAssistant:"
+	if gen.prompt != want {
+		t.Errorf("prompt = %q, want %q", gen.prompt, want)
+	}
+	cfg := gen.cfg
+	if cfg.SystemInstruction != "Be brief." || cfg.Model != "" {
+		t.Errorf("system = %q, model = %q", cfg.SystemInstruction, cfg.Model)
+	}
+	if cfg.Temperature == nil || *cfg.Temperature != 0 || *cfg.MaxOutputTokens != 50 || *cfg.Seed != 7 {
+		t.Errorf("sampling options not mapped: %+v", cfg)
+	}
+	if len(cfg.StopSequences) != 1 || cfg.StopSequences[0] != "END" {
+		t.Errorf("stop = %v", cfg.StopSequences)
+	}
+}
+
+func TestChatCompletions_SingleMessagePassedThrough(t *testing.T) {
+	gen := &fakeGenerator{text: "ok"}
+	srv := httptest.NewServer(New(WithRoute("m", gen)))
+	defer srv.Close()
+
+	post(t, srv, "/v1/chat/completions", `{"model":"m","messages":[{"role":"user","content":"Ping"}]}`)
+	if gen.prompt != "Ping" {
+		t.Errorf("prompt = %q, want %q", gen.prompt, "Ping")
+	}
+}
+
+func TestChatCompletions_Stream(t *testing.T) {
+	gen := &fakeGenerator{text: "one two three", tail: ".", usage: generators.Usage{PromptTokens: 1, CompletionTokens: 3, TotalTokens: 4}}
+	srv := httptest.NewServer(New(WithRoute("m", gen)))
+	defer srv.Close()
+
+	resp := post(t, srv, "/v1/chat/completions",
+		`{"model":"m","stream":true,"stream_options":{"include_usage":true},"messages":[{"role":"user","content":"Count"}]}`)
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	evs := events(t, resp.Body)
+	if len(evs) < 3 || evs[len(evs)-1] != "[DONE]" {
+		t.Fatalf("events = %q", evs)
+	}
+
+	var text strings.Builder
+	var finish string
+	var u *usage
+	for _, data := range evs[:len(evs)-1] {
+		var c chatResponse
+		if err := json.Unmarshal([]byte(data), &c); err != nil {
+			t.Fatalf("event %q: %v", data, err)
+		}
+		if c.Object != "chat.completion.chunk" {
+			t.Errorf("object = %q", c.Object)
+		}
+		if c.Usage != nil {
+			u = c.Usage
+		}
+		for _, ch := range c.Choices {
+			if ch.Delta.Content != nil {
+				text.WriteString(*ch.Delta.Content)
+			}
+			if ch.FinishReason != nil {
+				finish = *ch.FinishReason
+			}
+		}
+	}
+	if text.String() != "one two three." || finish != "length" {
+		t.Errorf("text = %q, finish = %q", text.String(), finish)
+	}
+	if u == nil || u.TotalTokens != 4 {
+		t.Errorf("usage = %+v", u)
+	}
+}
+
+func TestChatCompletions_StreamError(t *testing.T) {
+	gen := &fakeGenerator{text: "partial", err: errors.New("boom")}
+	srv := httptest.NewServer(New(WithRoute("m", gen)))
+	defer srv.Close()
+
+	resp := post(t, srv, "/v1/chat/completions", `{"model":"m","stream":true,"messages":[{"role":"user","content":"x"}]}`)
+	evs := events(t, resp.Body)
+	last := evs[len(evs)-1]
+	if !strings.Contains(last, `"error"`) || !strings.Contains(last, "boom") {
+		t.Errorf("last event = %q, want an error", last)
+	}
+}
+
+func TestCompletions(t *testing.T) {
+	gen := &fakeGenerator{text: "world"}
+	srv := httptest.NewServer(New(WithRoute("m", gen)))
+	defer srv.Close()
+
+	resp := post(t, srv, "/v1/completions", `{"model":"m","prompt":["hello"],"top_p":0.5,"n":2}`)
+	var out completionResponse
+	decodeBody(t, resp, &out)
+	if out.Object != "text_completion" || len(out.Choices) != 1 || out.Choices[0].Text != "world" {
+		t.Errorf("response = %+v", out)
+	}
+	if gen.prompt != "hello" || *gen.cfg.TopP != 0.5 || *gen.cfg.CandidateCount != 2 {
+		t.Errorf("prompt = %q, cfg = %+v", gen.prompt, gen.cfg)
+	}
+
+	resp = post(t, srv, "/v1/completions", `{"model":"m","prompt":"a b","stream":true}`)
+	evs := events(t, resp.Body)
+	var text strings.Builder
+	var finish string
+	for _, data := range evs[:len(evs)-1] {
+		var c completionResponse
+		json.Unmarshal([]byte(data), &c)
+		for _, ch := range c.Choices {
+			text.WriteString(ch.Text)
+			if ch.FinishReason != nil {
+				finish = *ch.FinishReason
+			}
+		}
+	}
+	if text.String() != "world" || finish != "length" {
+		t.Errorf("streamed text = %q, finish = %q", text.String(), finish)
+	}
+}
+
+func TestResponseOptions(t *testing.T) {
+	gen := &fakeGenerator{text: "Yes", logprobs: []generators.TokenLogprob{{
+		Token: "Yes", Logprob: -0.1, TopLogprobs: []generators.TokenLogprob{{Token: "Yes", Logprob: -0.1}, {Token: "No", Logprob: -2.5}},
+	}}}
+	srv := httptest.NewServer(New(WithRoute("m", gen)))
+	defer srv.Close()
+
+	resp := post(t, srv, "/v1/chat/completions", `{"model":"m","messages":[{"role":"user","content":"x"}],`+
+		`"response_format":{"type":"json_object"},"logprobs":true,"top_logprobs":2}`)
+	var chat chatResponse
+	decodeBody(t, resp, &chat)
+	if !gen.cfg.JSONOutput || !gen.cfg.ResponseLogprobs || gen.cfg.TopLogprobs == nil || *gen.cfg.TopLogprobs != 2 {
+		t.Errorf("chat cfg = %+v", gen.cfg)
+	}
+	if lp := chat.Choices[0].Logprobs; lp == nil || len(lp.Content) != 1 || len(lp.Content[0].TopLogprobs) != 2 || lp.Content[0].TopLogprobs[1].Token != "No" {
+		t.Errorf("chat logprobs = %+v", lp)
+	}
+
+	resp = post(t, srv, "/v1/completions", `{"model":"m","prompt":"x","logprobs":3}`)
+	var cmpl completionResponse
+	decodeBody(t, resp, &cmpl)
+	if gen.cfg.JSONOutput || !gen.cfg.ResponseLogprobs || *gen.cfg.TopLogprobs != 3 {
+		t.Errorf("completion cfg = %+v", gen.cfg)
+	}
+	lp := cmpl.Choices[0].Logprobs
+	if lp == nil || lp.Tokens[0] != "Yes" || lp.TokenLogprobs[0] != -0.1 || lp.TopLogprobs[0]["No"] != -2.5 || lp.TextOffset[0] != 0 {
+		t.Errorf("completion logprobs = %+v", lp)
+	}
+
+	post(t, srv, "/v1/chat/completions", `{"model":"m","messages":[{"role":"user","content":"x"}],"logprobs":false}`)
+	if gen.cfg.ResponseLogprobs {
+		t.Error("logprobs requested with logprobs false")
+	}
+}
+
+func TestRouting(t *testing.T) {
+	alias := &fakeGenerator{text: "alias"}
+	gemini := &fakeGenerator{text: "gemini"}
+	srv := httptest.NewServer(New(WithRoute("fast", alias), WithRoute("gemini-*", gemini)))
+	defer srv.Close()
+
+	post(t, srv, "/v1/completions", `{"model":"gemini-2.0-flash","prompt":"x"}`)
+	if gemini.cfg.Model != "gemini-2.0-flash" {
+		t.Errorf("wildcard route model = %q, want the requested name", gemini.cfg.Model)
+	}
+	post(t, srv, "/v1/completions", `{"model":"fast","prompt":"x"}`)
+	if alias.prompt != "x" || alias.cfg.Model != "" {
+		t.Errorf("alias route: prompt = %q, model = %q", alias.prompt, alias.cfg.Model)
+	}
+
+	resp := post(t, srv, "/v1/completions", `{"model":"gpt-5","prompt":"x"}`)
+	var body errorBody
+	decodeBody(t, resp, &body)
+	if resp.StatusCode != http.StatusNotFound || body.Error.Code == nil || *body.Error.Code != "model_not_found" {
+		t.Errorf("unknown model: status %d, error %+v", resp.StatusCode, body.Error)
+	}
+
+	r, err := http.Get(srv.URL + "/v1/models")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Body.Close()
+	var list modelList
+	decodeBody(t, r, &list)
+	if len(list.Data) != 1 || list.Data[0].ID != "fast" {
+		t.Errorf("models = %+v", list.Data)
+	}
+}
+
+func TestGeneratorErrors(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{&generators.APIError{Provider: "x", StatusCode: 429}, http.StatusTooManyRequests},
+		{&generators.APIError{Provider: "x", StatusCode: 401}, http.StatusBadGateway},
+		{&generators.APIError{Provider: "x", StatusCode: 503}, http.StatusBadGateway},
+		{&generators.BlockedError{Provider: "x", Reason: "SAFETY"}, http.StatusBadRequest},
+		{errors.New("boom"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		srv := httptest.NewServer(New(WithRoute("m", &fakeGenerator{err: tt.err})))
+		resp := post(t, srv, "/v1/completions", `{"model":"m","prompt":"x"}`)
+		if resp.StatusCode != tt.status {
+			t.Errorf("%v: status = %d, want %d", tt.err, resp.StatusCode, tt.status)
+		}
+		srv.Close()
+	}
+}
+
+func TestBadRequests(t *testing.T) {
+	srv := httptest.NewServer(New(WithRoute("m", &fakeGenerator{})))
+	defer srv.Close()
+
+	for _, body := range []string{
+		`not json`,
+		`{"messages":[{"role":"user","content":"x"}]}`,
+		`{"model":"m","messages":[{"role":"system","content":"x"}]}`,
+		`{"model":"m","messages":[{"role":"tool","content":"x"}]}`,
+		`{"model":"m","messages":[{"role":"user","content":[{"type":"image_url"}]}]}`,
+		`{"model":"m","response_format":{"type":"json_schema"},"messages":[{"role":"user","content":"x"}]}`,
+		`{"model":"m","top_logprobs":2,"messages":[{"role":"user","content":"x"}]}`,
+		`{"model":"m","logprobs":"yes","messages":[{"role":"user","content":"x"}]}`,
+	} {
+		if resp := post(t, srv, "/v1/chat/completions", body); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", body, resp.StatusCode)
+		}
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	srv := httptest.NewServer(New(WithRoute("m", &fakeGenerator{}), WithAPIKeys("secret")))
+	defer srv.Close()
+
+	resp := post(t, srv, "/v1/completions", `{"model":"m","prompt":"x"}`)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("without key: status = %d", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/v1/models", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	r, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		t.Errorf("with key: status = %d", r.StatusCode)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"model": "llama", "response": "from ollama", "done": true})
+	}))
+	defer ollama.Close()
+	host := strings.TrimPrefix(ollama.URL, "http://")
+
+	gw, err := Open(context.Background(), map[string]string{
+		"local-*": "ollama://" + host + "/llama",
+		"chat":    "ollama://" + host + "/llama",
+	})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer gw.Close()
+	if len(gw.owned) != 1 {
+		t.Errorf("opened %d generators, want 1 per distinct URL", len(gw.owned))
+	}
+	if gw.routes[0].pattern != "chat" {
+		t.Errorf("first route = %q, want plain names first", gw.routes[0].pattern)
+	}
+
+	srv := httptest.NewServer(gw)
+	defer srv.Close()
+	resp := post(t, srv, "/v1/chat/completions", `{"model":"chat","messages":[{"role":"user","content":"hi"}]}`)
+	var out chatResponse
+	decodeBody(t, resp, &out)
+	if len(out.Choices) != 1 || *out.Choices[0].Message.Content != "from ollama" {
+		t.Errorf("response = %+v", out)
+	}
+
+	if _, err := Open(context.Background(), map[string]string{"x": "nope://"}); err == nil {
+		t.Error("Open with an unknown scheme succeeded")
+	}
+}
@@ -0,0 +1,260 @@
+package gateway
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/tnotstar/go-minolas/pkg/ai/generators"
+)
+
+// maxRequestBytes bounds the size of request bodies.
+const maxRequestBytes = 8 << 20
+
+// decode reads the JSON request body into v and resolves its model. It
+// writes the error response and returns false on failure.
+func (g *Gateway) decode(w http.ResponseWriter, r *http.Request, v any, p *samplingParams) (generators.Generator, []generators.Option, bool) {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "", "invalid request body: "+err.Error())
+		return nil, nil, false
+	}
+	if p.Model == "" {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "", "model is required")
+		return nil, nil, false
+	}
+	gen, opts, ok := g.resolve(p.Model)
+	if !ok {
+		writeError(w, http.StatusNotFound, "invalid_request_error", "model_not_found",
+			fmt.Sprintf("the model %q does not exist", p.Model))
+		return nil, nil, false
+	}
+	more, err := p.options()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "", err.Error())
+		return nil, nil, false
+	}
+	return gen, append(opts, more...), true
+}
+
+func (g *Gateway) chatCompletions(w http.ResponseWriter, r *http.Request) {
+	var req chatRequest
+	gen, opts, ok := g.decode(w, r, &req, &req.samplingParams)
+	if !ok {
+		return
+	}
+	system, prompt, err := req.prompt()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "", err.Error())
+		return
+	}
+	if system != "" {
+		opts = append(opts, generators.WithSystemInstruction(system))
+	}
+
+	id, created := "chatcmpl-"+newID(), time.Now().Unix()
+	if req.Stream {
+		g.streamChat(r.Context(), w, gen, prompt, opts, &req.samplingParams, id, created)
+		return
+	}
+
+	resp, err := gen.Generate(r.Context(), prompt, opts...)
+	if err != nil {
+		writeGeneratorError(w, err)
+		return
+	}
+	out := chatResponse{
+		ID:      id,
+		Object:  "chat.completion",
+		Created: created,
+		Model:   req.Model,
+		Usage:   newUsage(resp.Usage),
+	}
+	for i, c := range candidates(resp) {
+		text := c.Text
+		out.Choices = append(out.Choices, chatChoice{
+			Index:        i,
+			Message:      &chatDelta{Role: "assistant", Content: &text, ReasoningContent: c.Thoughts},
+			Logprobs:     newLogprobs(c.Logprobs),
+			FinishReason: finishReason(c.FinishReason),
+		})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (g *Gateway) completions(w http.ResponseWriter, r *http.Request) {
+	var req completionRequest
+	gen, opts, ok := g.decode(w, r, &req, &req.samplingParams)
+	if !ok {
+		return
+	}
+	if len(req.Prompt) != 1 {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "", "prompt must be a single string")
+		return
+	}
+	prompt := req.Prompt[0]
+
+	id, created := "cmpl-"+newID(), time.Now().Unix()
+	if req.Stream {
+		g.streamCompletion(r.Context(), w, gen, prompt, opts, &req.samplingParams, id, created)
+		return
+	}
+
+	resp, err := gen.Generate(r.Context(), prompt, opts...)
+	if err != nil {
+		writeGeneratorError(w, err)
+		return
+	}
+	out := completionResponse{
+		ID:      id,
+		Object:  "text_completion",
+		Created: created,
+		Model:   req.Model,
+		Usage:   newUsage(resp.Usage),
+	}
+	for i, c := range candidates(resp) {
+		out.Choices = append(out.Choices, completionChoice{
+			Index:        i,
+			Text:         c.Text,
+			Logprobs:     newCompletionLogprobs(c.Logprobs),
+			FinishReason: finishReason(c.FinishReason),
+		})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// candidates returns the candidates of resp, synthesizing one from the
+// top-level fields for providers that do not list them.
+func candidates(resp *generators.Response) []generators.Candidate {
+	if len(resp.Candidates) > 0 {
+		return resp.Candidates
+	}
+	return []generators.Candidate{{Text: resp.Text, Thoughts: resp.Thoughts, FinishReason: resp.FinishReason}}
+}
+
+func (g *Gateway) streamChat(ctx context.Context, w http.ResponseWriter, gen generators.Generator, prompt string, opts []generators.Option, p *samplingParams, id string, created int64) {
+	chunk := func(choices []chatChoice, u *usage) chatResponse {
+		return chatResponse{ID: id, Object: "chat.completion.chunk", Created: created, Model: p.Model, Choices: choices, Usage: u}
+	}
+	empty := ""
+	g.stream(ctx, w, gen, prompt, opts, p, streamEvents{
+		start: chunk([]chatChoice{{Delta: &chatDelta{Role: "assistant", Content: &empty}}}, nil),
+		text: func(s string) any {
+			return chunk([]chatChoice{{Delta: &chatDelta{Content: &s}}}, nil)
+		},
+		thought: func(s string) any {
+			return chunk([]chatChoice{{Delta: &chatDelta{ReasoningContent: s}}}, nil)
+		},
+		finish: func(reason string) any {
+			return chunk([]chatChoice{{Delta: &chatDelta{}, FinishReason: finishReason(reason)}}, nil)
+		},
+		usage: func(u *usage) any {
+			return chunk([]chatChoice{}, u)
+		},
+	})
+}
+
+func (g *Gateway) streamCompletion(ctx context.Context, w http.ResponseWriter, gen generators.Generator, prompt string, opts []generators.Option, p *samplingParams, id string, created int64) {
+	chunk := func(choices []completionChoice, u *usage) completionResponse {
+		return completionResponse{ID: id, Object: "text_completion", Created: created, Model: p.Model, Choices: choices, Usage: u}
+	}
+	g.stream(ctx, w, gen, prompt, opts, p, streamEvents{
+		text: func(s string) any {
+			return chunk([]completionChoice{{Text: s}}, nil)
+		},
+		finish: func(reason string) any {
+			return chunk([]completionChoice{{FinishReason: finishReason(reason)}}, nil)
+		},
+		usage: func(u *usage) any {
+			return chunk([]completionChoice{}, u)
+		},
+	})
+}
+
+// streamEvents builds the server-sent events of a stream. start and
+// thought are optional. finish receives the provider's finish reason.
+type streamEvents struct {
+	start   any
+	text    func(string) any
+	thought func(string) any
+	finish  func(string) any
+	usage   func(*usage) any
+}
+
+// stream runs gen.Stream and relays it as server-sent events terminated by
+// "data: [DONE]". Errors before the first chunk are reported with a normal
+// error response; later ones as an error event.
+func (g *Gateway) stream(ctx context.Context, w http.ResponseWriter, gen generators.Generator, prompt string, opts []generators.Option, p *samplingParams, ev streamEvents) {
+	ch, err := gen.Stream(ctx, prompt, opts...)
+	if err != nil {
+		writeGeneratorError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	send := func(v any) {
+		data, _ := json.Marshal(v)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	if ev.start != nil {
+		send(ev.start)
+	}
+	var last *generators.Usage
+	var reason string
+	for chunk := range ch {
+		if chunk.FinishReason != "" {
+			reason = chunk.FinishReason
+		}
+		if chunk.Error != nil {
+			_, typ, code := errorStatus(chunk.Error)
+			body := errorBody{Error: apiError{Message: chunk.Error.Error(), Type: typ}}
+			if code != "" {
+				body.Error.Code = &code
+			}
+			send(body)
+			// Drain so the provider goroutine can finish.
+			for range ch {
+			}
+			return
+		}
+		if chunk.Usage != nil {
+			last = chunk.Usage
+		}
+		if chunk.Thought != "" && ev.thought != nil {
+			send(ev.thought(chunk.Thought))
+		}
+		if chunk.Text != "" {
+			send(ev.text(chunk.Text))
+		}
+	}
+	send(ev.finish(reason))
+	if p.includeUsage() {
+		var u generators.Usage
+		if last != nil {
+			u = *last
+		}
+		send(ev.usage(newUsage(u)))
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+// newID returns a random identifier for a response.
+func newID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
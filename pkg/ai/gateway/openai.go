@@ -0,0 +1,438 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/tnotstar/go-minolas/pkg/ai/generators"
+	"github.com/tnotstar/go-minolas/pkg/ai/memory"
+)
+
+// samplingParams holds the request fields shared by chat and text
+// completions.
+type samplingParams struct {
+	Model               string          `json:"model"`
+	Temperature         *float32        `json:"temperature"`
+	TopP                *float32        `json:"top_p"`
+	MaxTokens           *int            `json:"max_tokens"`
+	MaxCompletionTokens *int            `json:"max_completion_tokens"`
+	Stop                stringList      `json:"stop"`
+	Seed                *int            `json:"seed"`
+	PresencePenalty     *float32        `json:"presence_penalty"`
+	FrequencyPenalty    *float32        `json:"frequency_penalty"`
+	N                   *int            `json:"n"`
+	Logprobs            logprobsParam   `json:"logprobs"`
+	TopLogprobs         *int            `json:"top_logprobs"`
+	ReasoningEffort     string          `json:"reasoning_effort"`
+	Stream              bool            `json:"stream"`
+	StreamOptions       *streamOptions  `json:"stream_options"`
+	ResponseFormat      *responseFormat `json:"response_format"`
+}
+
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type responseFormat struct {
+	Type string `json:"type"`
+}
+
+// logprobsParam decodes the logprobs field, a boolean in chat completions
+// and the number of alternatives to report per token in text completions.
+type logprobsParam struct {
+	enabled bool
+	top     int
+}
+
+func (l *logprobsParam) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*l = logprobsParam{}
+		return nil
+	}
+	if err := json.Unmarshal(data, &l.enabled); err == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, &l.top); err != nil || l.top < 0 {
+		return errors.New("logprobs must be a boolean or a non-negative integer")
+	}
+	l.enabled = true
+	return nil
+}
+
+// options maps the request fields onto generator options.
+func (p *samplingParams) options() ([]generators.Option, error) {
+	var opts []generators.Option
+	if p.Temperature != nil {
+		opts = append(opts, generators.WithTemperature(*p.Temperature))
+	}
+	if p.TopP != nil {
+		opts = append(opts, generators.WithTopP(*p.TopP))
+	}
+	if p.MaxCompletionTokens != nil {
+		opts = append(opts, generators.WithMaxOutputTokens(*p.MaxCompletionTokens))
+	} else if p.MaxTokens != nil {
+		opts = append(opts, generators.WithMaxOutputTokens(*p.MaxTokens))
+	}
+	if len(p.Stop) > 0 {
+		opts = append(opts, generators.WithStopSequences(p.Stop...))
+	}
+	if p.Seed != nil {
+		opts = append(opts, generators.WithSeed(*p.Seed))
+	}
+	if p.PresencePenalty != nil {
+		opts = append(opts, generators.WithPresencePenalty(*p.PresencePenalty))
+	}
+	if p.FrequencyPenalty != nil {
+		opts = append(opts, generators.WithFrequencyPenalty(*p.FrequencyPenalty))
+	}
+	if p.N != nil && *p.N != 1 {
+		if p.Stream {
+			return nil, errors.New("n greater than 1 is not supported when streaming")
+		}
+		opts = append(opts, generators.WithCandidateCount(*p.N))
+	}
+	if p.ReasoningEffort != "" {
+		opts = append(opts, generators.WithThinkingLevel(p.ReasoningEffort))
+	}
+	if p.Logprobs.enabled {
+		top := p.Logprobs.top
+		if p.TopLogprobs != nil {
+			top = *p.TopLogprobs
+		}
+		opts = append(opts, generators.WithResponseLogprobs(top))
+	} else if p.TopLogprobs != nil {
+		return nil, errors.New("top_logprobs requires logprobs")
+	}
+	if p.ResponseFormat != nil {
+		switch p.ResponseFormat.Type {
+		case "", "text":
+		case "json_object":
+			opts = append(opts, generators.WithJSONOutput())
+		default:
+			return nil, fmt.Errorf("response_format %q is not supported", p.ResponseFormat.Type)
+		}
+	}
+	return opts, nil
+}
+
+// includeUsage reports whether a stream should end with a usage chunk.
+func (p *samplingParams) includeUsage() bool {
+	return p.StreamOptions != nil && p.StreamOptions.IncludeUsage
+}
+
+// stringList decodes either a JSON string or an array of strings.
+type stringList []string
+
+func (l *stringList) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*l = stringList{s}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return errors.New("expected a string or an array of strings")
+	}
+	*l = list
+	return nil
+}
+
+type chatRequest struct {
+	samplingParams
+	Messages []chatMessage `json:"messages"`
+}
+
+type chatMessage struct {
+	Role    string         `json:"role"`
+	Content messageContent `json:"content"`
+}
+
+// messageContent decodes message content given either as a string or as
+// an array of parts. Only text parts are accepted.
+type messageContent string
+
+func (c *messageContent) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*c = ""
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*c = messageContent(s)
+		return nil
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return errors.New("content must be a string or an array of parts")
+	}
+	var texts []string
+	for _, p := range parts {
+		if p.Type != "text" {
+			return fmt.Errorf("content part type %q is not supported", p.Type)
+		}
+		texts = append(texts, p.Text)
+	}
+	*c = messageContent(strings.Join(texts, "\n"))
+	return nil
+}
+
+// prompt converts the messages into a system instruction and a prompt.
+// A conversation of a single user message is passed through unchanged;
+// longer ones are rendered with memory.Render.
+func (r *chatRequest) prompt() (system, prompt string, err error) {
+	var turns []memory.Turn
+	var sys, rest int
+	for _, m := range r.Messages {
+		t := memory.Turn{Content: string(m.Content)}
+		switch m.Role {
+		case "system", "developer":
+			t.Role = memory.RoleSystem
+			sys++
+		case "user":
+			t.Role = memory.RoleUser
+			rest++
+		case "assistant":
+			t.Role = memory.RoleAssistant
+			rest++
+		default:
+			return "", "", fmt.Errorf("message role %q is not supported", m.Role)
+		}
+		turns = append(turns, t)
+	}
+	if rest == 0 {
+		return "", "", errors.New("messages must include a user message")
+	}
+	system, prompt = memory.Render(turns)
+	if rest == 1 && turns[len(turns)-1].Role == memory.RoleUser {
+		prompt = turns[len(turns)-1].Content
+	}
+	return system, prompt, nil
+}
+
+type completionRequest struct {
+	samplingParams
+	Prompt stringList `json:"prompt"`
+}
+
+type chatResponse struct {
+	ID      string       `json:"id"`
+	Object  string       `json:"object"`
+	Created int64        `json:"created"`
+	Model   string       `json:"model"`
+	Choices []chatChoice `json:"choices"`
+	Usage   *usage       `json:"usage,omitempty"`
+}
+
+type chatChoice struct {
+	Index        int          `json:"index"`
+	Message      *chatDelta   `json:"message,omitempty"`
+	Delta        *chatDelta   `json:"delta,omitempty"`
+	Logprobs     *logprobList `json:"logprobs"`
+	FinishReason *string      `json:"finish_reason"`
+}
+
+type chatDelta struct {
+	Role             string  `json:"role,omitempty"`
+	Content          *string `json:"content,omitempty"`
+	ReasoningContent string  `json:"reasoning_content,omitempty"`
+}
+
+type logprobList struct {
+	Content []tokenLogprob `json:"content"`
+}
+
+type tokenLogprob struct {
+	Token       string         `json:"token"`
+	Logprob     float64        `json:"logprob"`
+	TopLogprobs []tokenLogprob `json:"top_logprobs,omitempty"`
+}
+
+type completionResponse struct {
+	ID      string             `json:"id"`
+	Object  string             `json:"object"`
+	Created int64              `json:"created"`
+	Model   string             `json:"model"`
+	Choices []completionChoice `json:"choices"`
+	Usage   *usage             `json:"usage,omitempty"`
+}
+
+type completionChoice struct {
+	Index        int                 `json:"index"`
+	Text         string              `json:"text"`
+	Logprobs     *completionLogprobs `json:"logprobs"`
+	FinishReason *string             `json:"finish_reason"`
+}
+
+// completionLogprobs is the log probability report of text completions,
+// with one entry per token in each list.
+type completionLogprobs struct {
+	Tokens        []string             `json:"tokens"`
+	TokenLogprobs []float64            `json:"token_logprobs"`
+	TopLogprobs   []map[string]float64 `json:"top_logprobs"`
+	TextOffset    []int                `json:"text_offset"`
+}
+
+type usage struct {
+	PromptTokens            int                `json:"prompt_tokens"`
+	CompletionTokens        int                `json:"completion_tokens"`
+	TotalTokens             int                `json:"total_tokens"`
+	PromptTokensDetails     *promptDetails     `json:"prompt_tokens_details,omitempty"`
+	CompletionTokensDetails *completionDetails `json:"completion_tokens_details,omitempty"`
+}
+
+type promptDetails struct {
+	CachedTokens int `json:"cached_tokens"`
+}
+
+type completionDetails struct {
+	ReasoningTokens int `json:"reasoning_tokens"`
+}
+
+type modelList struct {
+	Object string      `json:"object"`
+	Data   []modelInfo `json:"data"`
+}
+
+type modelInfo struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
+type errorBody struct {
+	Error apiError `json:"error"`
+}
+
+type apiError struct {
+	Message string  `json:"message"`
+	Type    string  `json:"type"`
+	Param   *string `json:"param"`
+	Code    *string `json:"code"`
+}
+
+// newUsage converts generator usage to its wire form. Reasoning tokens are
+// counted as completion tokens, as OpenAI does.
+func newUsage(u generators.Usage) *usage {
+	out := &usage{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens + u.ThoughtTokens,
+		TotalTokens:      u.TotalTokens,
+	}
+	if out.TotalTokens == 0 {
+		out.TotalTokens = out.PromptTokens + out.CompletionTokens
+	}
+	if u.CachedTokens > 0 {
+		out.PromptTokensDetails = &promptDetails{CachedTokens: u.CachedTokens}
+	}
+	if u.ThoughtTokens > 0 {
+		out.CompletionTokensDetails = &completionDetails{ReasoningTokens: u.ThoughtTokens}
+	}
+	return out
+}
+
+// newLogprobs converts generator log probabilities to their wire form.
+func newLogprobs(in []generators.TokenLogprob) *logprobList {
+	if len(in) == 0 {
+		return nil
+	}
+	out := &logprobList{Content: make([]tokenLogprob, len(in))}
+	for i, lp := range in {
+		out.Content[i] = tokenLogprob{Token: lp.Token, Logprob: lp.Logprob}
+		for _, alt := range lp.TopLogprobs {
+			out.Content[i].TopLogprobs = append(out.Content[i].TopLogprobs, tokenLogprob{Token: alt.Token, Logprob: alt.Logprob})
+		}
+	}
+	return out
+}
+
+// newCompletionLogprobs converts generator log probabilities to the text
+// completion wire form.
+func newCompletionLogprobs(in []generators.TokenLogprob) *completionLogprobs {
+	if len(in) == 0 {
+		return nil
+	}
+	out := &completionLogprobs{}
+	offset := 0
+	for _, lp := range in {
+		top := make(map[string]float64, len(lp.TopLogprobs))
+		for _, alt := range lp.TopLogprobs {
+			top[alt.Token] = alt.Logprob
+		}
+		out.Tokens = append(out.Tokens, lp.Token)
+		out.TokenLogprobs = append(out.TokenLogprobs, lp.Logprob)
+		out.TopLogprobs = append(out.TopLogprobs, top)
+		out.TextOffset = append(out.TextOffset, offset)
+		offset += len(lp.Token)
+	}
+	return out
+}
+
+// finishReason maps a provider finish reason onto the OpenAI values.
+func finishReason(reason string) *string {
+	var r string
+	switch strings.ToUpper(reason) {
+	case "", "STOP", "FINISH_REASON_UNSPECIFIED", "UNLOAD", "END_TURN", "STOP_SEQUENCE":
+		r = "stop"
+	case "MAX_TOKENS", "LENGTH":
+		r = "length"
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII", "IMAGE_SAFETY",
+		"CONTENT_FILTERED", "GUARDRAIL_INTERVENED":
+		r = "content_filter"
+	default:
+		r = strings.ToLower(reason)
+	}
+	return &r
+}
+
+// writeJSON writes v as a JSON response.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error in the OpenAI format.
+func writeError(w http.ResponseWriter, status int, typ, code, msg string) {
+	body := errorBody{Error: apiError{Message: msg, Type: typ}}
+	if code != "" {
+		body.Error.Code = &code
+	}
+	writeJSON(w, status, body)
+}
+
+// writeGeneratorError writes an error returned by a generator, keeping the
+// status of provider API errors.
+func writeGeneratorError(w http.ResponseWriter, err error) {
+	status, typ, code := errorStatus(err)
+	writeError(w, status, typ, code, err.Error())
+}
+
+// errorStatus classifies a generator error.
+func errorStatus(err error) (status int, typ, code string) {
+	var (
+		apiErr     *generators.APIError
+		blockedErr *generators.BlockedError
+	)
+	switch {
+	case errors.As(err, &blockedErr):
+		return http.StatusBadRequest, "invalid_request_error", "content_filter"
+	case errors.Is(err, generators.ErrUnsupportedOption):
+		return http.StatusBadRequest, "invalid_request_error", "unsupported_parameter"
+	case errors.As(err, &apiErr) && apiErr.StatusCode >= 400 && apiErr.StatusCode < 600:
+		// The provider rejecting the gateway's credentials is not the
+		// client's fault.
+		if apiErr.StatusCode < 500 && !generators.IsAuthError(err) {
+			return apiErr.StatusCode, "invalid_request_error", ""
+		}
+		return http.StatusBadGateway, "api_error", ""
+	default:
+		return http.StatusInternalServerError, "api_error", ""
+	}
+}
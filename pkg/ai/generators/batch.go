@@ -0,0 +1,171 @@
+package generators
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+const (
+	// defaultBatchConcurrency is the number of concurrent requests used by
+	// GenerateBatch when no concurrency is configured.
+	defaultBatchConcurrency = 4
+)
+
+var (
+	// ErrBatchAborted is set as the error of batch items that were not run
+	// because the batch stopped early on a fatal error.
+	ErrBatchAborted = errors.New("batch aborted")
+
+	// errNoResponse is recorded for items whose Generate call returned
+	// neither a response nor an error.
+	errNoResponse = errors.New("generators: generator returned no response")
+)
+
+// BatchResult holds the outcome of a single prompt in a batch.
+// Exactly one of Response and Err is non-nil.
+type BatchResult struct {
+	Index    int
+	Prompt   string
+	Response *Response
+	Err      error
+}
+
+// BatchOption is a functional option for configuring GenerateBatch.
+type BatchOption func(*batchConfig)
+
+// batchConfig holds configuration for a GenerateBatch call.
+type batchConfig struct {
+	concurrency int
+	genOpts     []Option
+	progress    func(completed, total int, result BatchResult)
+	isFatal     func(error) bool
+}
+
+// WithBatchConcurrency sets the maximum number of concurrent Generate calls.
+// Values lower than one are ignored.
+func WithBatchConcurrency(n int) BatchOption {
+	return func(c *batchConfig) {
+		if n > 0 {
+			c.concurrency = n
+		}
+	}
+}
+
+// WithBatchOptions sets the generation options applied to every prompt.
+func WithBatchOptions(opts ...Option) BatchOption {
+	return func(c *batchConfig) { c.genOpts = opts }
+}
+
+// WithBatchProgress sets a callback invoked after each prompt completes.
+// Calls are serialized, so the callback does not need to be safe for
+// concurrent use.
+func WithBatchProgress(fn func(completed, total int, result BatchResult)) BatchOption {
+	return func(c *batchConfig) { c.progress = fn }
+}
+
+// WithFatalErrorFunc sets the function that decides whether an item error
+// aborts the whole batch. The default treats authentication failures as fatal.
+func WithFatalErrorFunc(fn func(error) bool) BatchOption {
+	return func(c *batchConfig) { c.isFatal = fn }
+}
+
+// GenerateBatch runs Generate for every prompt using a bounded pool of
+// workers and returns one result per prompt, in the same order as prompts.
+//
+// Per-item failures are recorded in BatchResult.Err and do not stop the
+// batch. A fatal error (see WithFatalErrorFunc) or cancellation of ctx stops
+// scheduling new prompts; items that were never run get ErrBatchAborted and
+// GenerateBatch returns the error that caused the abort.
+//
+// Example:
+//
+//	results, err := generators.GenerateBatch(ctx, gen, prompts,
+//	    generators.WithBatchConcurrency(8),
+//	    generators.WithBatchOptions(generators.WithTemperature(0)),
+//	)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	for _, r := range results {
+//	    if r.Err != nil {
+//	        log.Printf("prompt %d failed: %v", r.Index, r.Err)
+//	    }
+//	}
+func GenerateBatch(ctx context.Context, gen Generator, prompts []string, opts ...BatchOption) ([]BatchResult, error) {
+	cfg := &batchConfig{
+		concurrency: defaultBatchConcurrency,
+		isFatal:     IsAuthError,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]BatchResult, len(prompts))
+	for i, p := range prompts {
+		results[i] = BatchResult{Index: i, Prompt: p, Err: ErrBatchAborted}
+	}
+
+	var (
+		mu        sync.Mutex
+		completed int
+		fatalErr  error
+	)
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(cfg.concurrency, len(prompts)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				// The dispatcher may hand out an item after a fatal error
+				// cancelled the batch; leave it aborted.
+				if ctx.Err() != nil {
+					continue
+				}
+				resp, err := gen.Generate(ctx, prompts[i], cfg.genOpts...)
+
+				mu.Lock()
+				if err != nil {
+					resp = nil
+				} else if resp == nil {
+					err = errNoResponse
+				}
+				results[i].Response = resp
+				results[i].Err = err
+				if err != nil && fatalErr == nil && cfg.isFatal != nil && cfg.isFatal(err) {
+					fatalErr = err
+					cancel()
+				}
+				completed++
+				if cfg.progress != nil {
+					cfg.progress(completed, len(prompts), results[i])
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+dispatch:
+	for i := range prompts {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if fatalErr != nil {
+		return results, fatalErr
+	}
+	if err := ctx.Err(); err != nil {
+		return results, err
+	}
+	return results, nil
+}
@@ -0,0 +1,145 @@
+package generators
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+// funcGenerator is a Generator whose Generate behavior is provided by a function.
+type funcGenerator struct {
+	generate func(ctx context.Context, prompt string, opts ...Option) (*Response, error)
+}
+
+func (f *funcGenerator) Generate(ctx context.Context, prompt string, opts ...Option) (*Response, error) {
+	return f.generate(ctx, prompt, opts...)
+}
+
+func (f *funcGenerator) Stream(_ context.Context, _ string, _ ...Option) (<-chan StreamChunk, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *funcGenerator) Close() error { return nil }
+
+func TestGenerateBatch_OrderedResultsAndItemErrors(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	gen := &funcGenerator{generate: func(_ context.Context, prompt string, opts ...Option) (*Response, error) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		if newConfig(opts).Temperature == nil {
+			return nil, errors.New("options not forwarded")
+		}
+		if prompt == "p3" {
+			return nil, &APIError{Provider: "mock", StatusCode: 500, Body: "boom"}
+		}
+		return &Response{Text: "echo " + prompt}, nil
+	}}
+
+	prompts := make([]string, 10)
+	for i := range prompts {
+		prompts[i] = fmt.Sprintf("p%d", i)
+	}
+
+	var progressCalls int
+	results, err := GenerateBatch(context.Background(), gen, prompts,
+		WithBatchConcurrency(3),
+		WithBatchOptions(WithTemperature(0)),
+		WithBatchProgress(func(completed, total int, _ BatchResult) {
+			progressCalls++
+			if total != len(prompts) || completed != progressCalls {
+				t.Errorf("progress(%d, %d) after %d calls", completed, total, progressCalls)
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatalf("GenerateBatch() error = %v", err)
+	}
+	if progressCalls != len(prompts) {
+		t.Errorf("progress called %d times, want %d", progressCalls, len(prompts))
+	}
+	if got := maxInFlight.Load(); got > 3 {
+		t.Errorf("max concurrent calls = %d, want <= 3", got)
+	}
+	for i, r := range results {
+		if r.Index != i || r.Prompt != prompts[i] {
+			t.Errorf("results[%d] = {Index: %d, Prompt: %q}, out of order", i, r.Index, r.Prompt)
+		}
+		if i == 3 {
+			if r.Err == nil || r.Response != nil {
+				t.Errorf("results[3] = %+v, want item error", r)
+			}
+			continue
+		}
+		if r.Err != nil || r.Response.Text != "echo "+prompts[i] {
+			t.Errorf("results[%d] = %+v, want echo response", i, r)
+		}
+	}
+}
+
+func TestGenerateBatch_FatalErrorAborts(t *testing.T) {
+	var calls atomic.Int32
+	gen := &funcGenerator{generate: func(ctx context.Context, prompt string, _ ...Option) (*Response, error) {
+		calls.Add(1)
+		return nil, &APIError{Provider: "mock", StatusCode: 401, Body: "unauthorized"}
+	}}
+
+	prompts := make([]string, 50)
+	results, err := GenerateBatch(context.Background(), gen, prompts, WithBatchConcurrency(1))
+	if !IsAuthError(err) {
+		t.Fatalf("GenerateBatch() error = %v, want auth error", err)
+	}
+	if len(results) != len(prompts) {
+		t.Fatalf("len(results) = %d, want %d", len(results), len(prompts))
+	}
+	if got := calls.Load(); got >= int32(len(prompts)) {
+		t.Errorf("Generate called %d times, want early abort", got)
+	}
+	if !errors.Is(results[len(results)-1].Err, ErrBatchAborted) {
+		t.Errorf("last result error = %v, want ErrBatchAborted", results[len(results)-1].Err)
+	}
+}
+
+func TestGenerateBatch_NoRunAfterFatalError(t *testing.T) {
+	for range 20 {
+		var calls atomic.Int32
+		gen := &funcGenerator{generate: func(ctx context.Context, prompt string, _ ...Option) (*Response, error) {
+			calls.Add(1)
+			return nil, &APIError{Provider: "mock", StatusCode: 403, Body: "forbidden"}
+		}}
+		var progress int
+		results, _ := GenerateBatch(context.Background(), gen, make([]string, 5), WithBatchConcurrency(1),
+			WithBatchProgress(func(int, int, BatchResult) { progress++ }))
+		if calls.Load() != 1 || progress != 1 {
+			t.Fatalf("Generate called %d times, progress %d times; want 1 and 1", calls.Load(), progress)
+		}
+		for _, r := range results[1:] {
+			if !errors.Is(r.Err, ErrBatchAborted) {
+				t.Fatalf("result %d error = %v, want ErrBatchAborted", r.Index, r.Err)
+			}
+		}
+	}
+}
+
+func TestGenerateBatch_NilResponse(t *testing.T) {
+	gen := &funcGenerator{generate: func(context.Context, string, ...Option) (*Response, error) { return nil, nil }}
+	results, err := GenerateBatch(context.Background(), gen, []string{"a"})
+	if err != nil || results[0].Response != nil || results[0].Err == nil {
+		t.Errorf("GenerateBatch() = %+v, %v; want an item error", results, err)
+	}
+}
+
+func TestGenerateBatch_Empty(t *testing.T) {
+	gen := &funcGenerator{}
+	results, err := GenerateBatch(context.Background(), gen, nil)
+	if err != nil || len(results) != 0 {
+		t.Errorf("GenerateBatch(nil) = %v, %v; want empty, nil", results, err)
+	}
+}
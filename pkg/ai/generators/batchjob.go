@@ -0,0 +1,121 @@
+package generators
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// defaultBatchJobPollInterval is the interval used by WaitBatchJob when
+// none is given.
+const defaultBatchJobPollInterval = 30 * time.Second
+
+// ErrBatchNoResult is set as the error of batch job items for which the
+// provider returned no result.
+var ErrBatchNoResult = errors.New("no result returned for batch item")
+
+// States of a batch job.
+const (
+	BatchJobPending   = "PENDING"
+	BatchJobRunning   = "RUNNING"
+	BatchJobSucceeded = "SUCCEEDED"
+	BatchJobFailed    = "FAILED"
+	BatchJobCancelled = "CANCELLED"
+	BatchJobExpired   = "EXPIRED"
+)
+
+// BatchJob describes an asynchronous batch job. Name identifies the job in
+// later calls, such as "batches/abc123" on Gemini.
+type BatchJob struct {
+	Name        string
+	DisplayName string
+	Model       string
+	State       string
+	Error       string
+
+	// Requests is the number of requests in the job, of which Succeeded
+	// and Failed have completed.
+	Requests  int
+	Succeeded int
+	Failed    int
+
+	CreateTime time.Time
+	UpdateTime time.Time
+	EndTime    time.Time
+}
+
+// Done reports whether the job reached a final state.
+func (j *BatchJob) Done() bool {
+	switch j.State {
+	case BatchJobSucceeded, BatchJobFailed, BatchJobCancelled, BatchJobExpired:
+		return true
+	}
+	return false
+}
+
+// BatchJobs is implemented by generators that can run prompts as an
+// asynchronous batch job, which providers bill at a discount in exchange
+// for a turnaround of up to a day. Use GetBatchJobs to check.
+//
+// Unlike GenerateBatch, a batch job outlives the process that submitted
+// it: its results can be fetched later given the job's name.
+type BatchJobs interface {
+
+	// SubmitBatchJob submits a job that runs Generate for every prompt with
+	// the given options.
+	SubmitBatchJob(ctx context.Context, prompts []string, opts ...Option) (*BatchJob, error)
+
+	// GetBatchJob returns the current status of a job.
+	GetBatchJob(ctx context.Context, name string) (*BatchJob, error)
+
+	// BatchJobResults returns the results of a succeeded job, one per
+	// prompt and in the same order as the prompts. Prompt is not set in the
+	// results; per-item failures are recorded in BatchResult.Err, which is
+	// ErrBatchNoResult for prompts missing from the job's output.
+	BatchJobResults(ctx context.Context, name string) ([]BatchResult, error)
+
+	// CancelBatchJob asks the provider to stop a pending or running job.
+	CancelBatchJob(ctx context.Context, name string) error
+}
+
+// GetBatchJobs returns gen as a BatchJobs if it supports batch jobs.
+func GetBatchJobs(gen Generator) (BatchJobs, bool) {
+	b, ok := gen.(BatchJobs)
+	return b, ok
+}
+
+// WaitBatchJob polls a job every interval, or every 30 seconds when
+// interval is not positive, until it reaches a final state or ctx is done.
+//
+// Example:
+//
+//	job, err := jobs.SubmitBatchJob(ctx, prompts, generators.WithTemperature(0))
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	job, err = generators.WaitBatchJob(ctx, jobs, job.Name, time.Minute)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	results, err := jobs.BatchJobResults(ctx, job.Name)
+func WaitBatchJob(ctx context.Context, jobs BatchJobs, name string, interval time.Duration) (*BatchJob, error) {
+	if interval <= 0 {
+		interval = defaultBatchJobPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		job, err := jobs.GetBatchJob(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if job.Done() {
+			return job, nil
+		}
+		select {
+		case <-ctx.Done():
+			return job, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
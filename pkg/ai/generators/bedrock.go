@@ -0,0 +1,504 @@
+package generators
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// bedrockService is the service name used to sign Bedrock requests.
+	bedrockService = "bedrock"
+
+	// envAWSRegion and envAWSDefaultRegion name the region used when the
+	// URL does not set one.
+	envAWSRegion        = "AWS_REGION"
+	envAWSDefaultRegion = "AWS_DEFAULT_REGION"
+)
+
+// BedrockOpener implements the Opener interface for models served by AWS
+// Bedrock. It supports the "bedrock" URL scheme and uses the Converse and
+// ConverseStream APIs, which share one request format across model
+// families.
+//
+// URL format: bedrock://{region}/{model-id}[?params]
+//
+// The region defaults to AWS_REGION or AWS_DEFAULT_REGION. Requests are
+// signed with AWS Signature Version 4, using the keys in AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN or, if those are not set, the
+// profile named by AWS_PROFILE (or "default") in the shared credentials
+// file.
+//
+// Parameters:
+//   - profile:  profile of the shared credentials file to use, even if the
+//     environment holds credentials
+//   - endpoint: base URL of the Bedrock runtime API, such as
+//     http://localhost:8080, overriding https://bedrock-runtime.{region}.amazonaws.com
+//
+// Examples:
+//   - bedrock://us-east-1/anthropic.claude-3-5-haiku-20241022-v1:0
+//   - bedrock://eu-west-1/mistral.mistral-large-2402-v1:0?profile=prod
+//   - bedrock:///amazon.nova-lite-v1:0   (region from the environment)
+type BedrockOpener struct{}
+
+// Id returns the unique identifier for the Bedrock opener.
+func (o *BedrockOpener) Id() string {
+	return "bedrock"
+}
+
+// CanOpen reports whether this opener can handle the given URL.
+// It returns true for the "bedrock" scheme.
+func (o *BedrockOpener) CanOpen(u *url.URL) bool {
+	return u.Scheme == "bedrock"
+}
+
+// Open creates a Bedrock generator client using the provided URL.
+// Credentials are read here and used for the life of the generator.
+func (o *BedrockOpener) Open(_ context.Context, u *url.URL) (Generator, error) {
+	if u == nil {
+		return nil, errors.New("generators: URL cannot be nil for BedrockOpener")
+	}
+	if !o.CanOpen(u) {
+		return nil, fmt.Errorf("generators: scheme %q not supported by BedrockOpener (expected bedrock)", u.Scheme)
+	}
+
+	var profile, endpoint string
+	for key, values := range u.Query() {
+		if len(values) == 0 {
+			continue
+		}
+		v := values[len(values)-1]
+		switch key {
+		case "profile":
+			profile = v
+		case "endpoint":
+			endpoint = strings.TrimSuffix(v, "/")
+		default:
+			return nil, fmt.Errorf("generators: unknown bedrock URL parameter %q", key)
+		}
+	}
+
+	region := u.Host
+	if region == "" {
+		region = os.Getenv(envAWSRegion)
+	}
+	if region == "" {
+		region = os.Getenv(envAWSDefaultRegion)
+	}
+	if region == "" {
+		return nil, fmt.Errorf("generators: bedrock URL has no region and %s is not set", envAWSRegion)
+	}
+	model := strings.TrimPrefix(u.Path, "/")
+	if model == "" {
+		return nil, errors.New("generators: bedrock URL has no model ID")
+	}
+	creds, err := loadAWSCredentials(profile)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint == "" {
+		endpoint = "https://bedrock-runtime." + region + ".amazonaws.com"
+	}
+
+	return &BedrockGenerator{
+		httpClient: &http.Client{},
+		baseURL:    endpoint,
+		region:     region,
+		model:      model,
+		creds:      creds,
+	}, nil
+}
+
+func init() {
+	RegisterOpener(&BedrockOpener{})
+}
+
+// --- Internal JSON types for the Bedrock Converse API ---
+
+type bedrockRequest struct {
+	Messages                     []bedrockMessage        `json:"messages"`
+	System                       []bedrockContentBlock   `json:"system,omitempty"`
+	InferenceConfig              *bedrockInferenceConfig `json:"inferenceConfig,omitempty"`
+	AdditionalModelRequestFields map[string]any          `json:"additionalModelRequestFields,omitempty"`
+}
+
+type bedrockMessage struct {
+	Role    string                `json:"role"`
+	Content []bedrockContentBlock `json:"content"`
+}
+
+type bedrockContentBlock struct {
+	Text             string                   `json:"text,omitempty"`
+	ReasoningContent *bedrockReasoningContent `json:"reasoningContent,omitempty"`
+}
+
+type bedrockReasoningContent struct {
+	ReasoningText *struct {
+		Text string `json:"text"`
+	} `json:"reasoningText,omitempty"`
+}
+
+type bedrockInferenceConfig struct {
+	MaxTokens     *int     `json:"maxTokens,omitempty"`
+	Temperature   *float32 `json:"temperature,omitempty"`
+	TopP          *float32 `json:"topP,omitempty"`
+	StopSequences []string `json:"stopSequences,omitempty"`
+}
+
+type bedrockResponse struct {
+	Output struct {
+		Message bedrockMessage `json:"message"`
+	} `json:"output"`
+	StopReason string        `json:"stopReason"`
+	Usage      *bedrockUsage `json:"usage"`
+}
+
+type bedrockUsage struct {
+	InputTokens  int `json:"inputTokens"`
+	OutputTokens int `json:"outputTokens"`
+	TotalTokens  int `json:"totalTokens"`
+}
+
+// bedrockStreamEvent is the payload of a ConverseStream event; the fields
+// set depend on the event type.
+type bedrockStreamEvent struct {
+	Delta *struct {
+		Text             string `json:"text"`
+		ReasoningContent *struct {
+			Text string `json:"text"`
+		} `json:"reasoningContent"`
+	} `json:"delta"`
+	StopReason string        `json:"stopReason"`
+	Usage      *bedrockUsage `json:"usage"`
+	Message    string        `json:"message"`
+}
+
+// --- BedrockGenerator ---
+
+// BedrockGenerator implements the Generator interface for AWS Bedrock
+// using the Converse REST API directly via net/http.
+type BedrockGenerator struct {
+	httpClient *http.Client
+	baseURL    string
+	region     string
+	model      string
+	creds      awsCredentials
+}
+
+// Generate produces a text completion for the given prompt using the
+// Converse API.
+func (g *BedrockGenerator) Generate(ctx context.Context, prompt string, opts ...Option) (out *Response, err error) {
+	cfg := newConfig(opts)
+	model := g.resolveModel(cfg)
+	trace := startTrace(ctx, cfg, RequestInfo{Provider: "bedrock", Model: model, Operation: OperationGenerate})
+	ctx = trace.ctx
+	defer func() { trace.end(out, err) }()
+	ctx, wd := startWatchdog(ctx, cfg, "bedrock")
+	defer wd.stop()
+
+	if err := cfg.checkStrict(g.capabilitiesFor(model), model); err != nil {
+		return nil, err
+	}
+	req, err := g.newRequest(ctx, cfg, model, prompt, "converse")
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return nil, timeoutCause(ctx, fmt.Errorf("generators: bedrock request failed: %w", err))
+	}
+	defer resp.Body.Close()
+	wd.first()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{Provider: "bedrock", StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+
+	var brResp bedrockResponse
+	if err := json.NewDecoder(wd.reader(resp.Body)).Decode(&brResp); err != nil {
+		return nil, timeoutCause(ctx, fmt.Errorf("generators: bedrock decode response: %w", err))
+	}
+
+	return g.mapResponse(&brResp, model), nil
+}
+
+// Stream produces a streaming text completion for the given prompt using
+// the ConverseStream API. Returns a read-only channel that yields response
+// chunks as event-stream messages arrive.
+func (g *BedrockGenerator) Stream(ctx context.Context, prompt string, opts ...Option) (_ <-chan StreamChunk, err error) {
+	cfg := newConfig(opts)
+	model := g.resolveModel(cfg)
+	trace := startTrace(ctx, cfg, RequestInfo{Provider: "bedrock", Model: model, Operation: OperationStream})
+	ctx = trace.ctx
+	ctx, wd := startWatchdog(ctx, cfg, "bedrock")
+	defer func() {
+		if err != nil {
+			wd.stop()
+			trace.end(nil, err)
+		}
+	}()
+
+	if err := cfg.checkStrict(g.capabilitiesFor(model), model); err != nil {
+		return nil, err
+	}
+	req, err := g.newRequest(ctx, cfg, model, prompt, "converse-stream")
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return nil, timeoutCause(ctx, fmt.Errorf("generators: bedrock stream request failed: %w", err))
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, &APIError{Provider: "bedrock", StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+
+	ch := make(chan StreamChunk)
+
+	go func() {
+		defer close(ch)
+		defer resp.Body.Close()
+		defer wd.stop()
+		g.consumeEventStream(ctx, wd.reader(resp.Body), wd.sender(trace.sender(ch)))
+		trace.end(nil, nil)
+	}()
+
+	return ch, nil
+}
+
+// newRequest builds and signs a request to the given Converse operation.
+func (g *BedrockGenerator) newRequest(ctx context.Context, cfg *Config, model, prompt, operation string) (*http.Request, error) {
+	body, err := json.Marshal(g.buildRequest(cfg, prompt))
+	if err != nil {
+		return nil, fmt.Errorf("generators: bedrock marshal request: %w", err)
+	}
+
+	// Model IDs may contain ':' and, for ARNs, '/', which must reach the
+	// API escaped.
+	endpoint := g.baseURL + "/model/" + awsURIEncode(model) + "/" + operation
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("generators: bedrock create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := signAWSRequest(req, body, g.creds, g.region, bedrockService, time.Now()); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// Model returns the model used when a request does not set one.
+func (g *BedrockGenerator) Model() string {
+	return g.model
+}
+
+// Close releases the resources held by the Bedrock generator.
+func (g *BedrockGenerator) Close() error {
+	return nil
+}
+
+// Capabilities returns the features available for the generator's model.
+func (g *BedrockGenerator) Capabilities() Capabilities {
+	return g.capabilitiesFor(g.model)
+}
+
+// capabilitiesFor returns the features available for the given model.
+// Top-K and extended thinking are passed as model-specific fields, which
+// only Anthropic models are known to accept.
+func (g *BedrockGenerator) capabilitiesFor(model string) Capabilities {
+	caps := Capabilities{
+		Streaming: true,
+		SupportedOptions: []string{
+			OptionTemperature, OptionMaxOutputTokens, OptionTopP,
+			OptionSystemInstruction, OptionStopSequences,
+		},
+	}
+	if isBedrockAnthropic(model) {
+		caps.SupportedOptions = append(caps.SupportedOptions, OptionTopK, OptionThinkingBudget, OptionIncludeThoughts)
+	}
+	return caps
+}
+
+// isBedrockAnthropic reports whether model, a model ID, inference profile
+// or ARN, names an Anthropic model.
+func isBedrockAnthropic(model string) bool {
+	return strings.Contains(model, "anthropic.")
+}
+
+// resolveModel returns the model from the config if set, otherwise the default.
+func (g *BedrockGenerator) resolveModel(cfg *Config) string {
+	if cfg.Model != "" {
+		return cfg.Model
+	}
+	return g.model
+}
+
+// buildRequest converts the config and prompt into a Converse request.
+func (g *BedrockGenerator) buildRequest(cfg *Config, prompt string) bedrockRequest {
+	req := bedrockRequest{
+		Messages: []bedrockMessage{{Role: "user", Content: []bedrockContentBlock{{Text: prompt}}}},
+	}
+	if cfg.SystemInstruction != "" {
+		req.System = []bedrockContentBlock{{Text: cfg.SystemInstruction}}
+	}
+
+	inf := &bedrockInferenceConfig{
+		MaxTokens:     cfg.MaxOutputTokens,
+		Temperature:   cfg.Temperature,
+		TopP:          cfg.TopP,
+		StopSequences: cfg.StopSequences,
+	}
+	if inf.MaxTokens != nil || inf.Temperature != nil || inf.TopP != nil || len(inf.StopSequences) > 0 {
+		req.InferenceConfig = inf
+	}
+
+	if isBedrockAnthropic(g.resolveModel(cfg)) {
+		fields := map[string]any{}
+		if cfg.TopK != nil {
+			fields["top_k"] = int(*cfg.TopK)
+		}
+		if cfg.ThinkingBudget != nil && *cfg.ThinkingBudget > 0 {
+			fields["thinking"] = map[string]any{"type": "enabled", "budget_tokens": *cfg.ThinkingBudget}
+		}
+		if len(fields) > 0 {
+			req.AdditionalModelRequestFields = fields
+		}
+	}
+	return req
+}
+
+// mapResponse converts a bedrockResponse into a generators.Response.
+// Reasoning blocks are returned in Thoughts.
+func (g *BedrockGenerator) mapResponse(resp *bedrockResponse, model string) *Response {
+	var text, thoughts strings.Builder
+	for _, block := range resp.Output.Message.Content {
+		text.WriteString(block.Text)
+		if rc := block.ReasoningContent; rc != nil && rc.ReasoningText != nil {
+			thoughts.WriteString(rc.ReasoningText.Text)
+		}
+	}
+	out := &Response{
+		Model:        model,
+		Text:         text.String(),
+		Thoughts:     thoughts.String(),
+		FinishReason: resp.StopReason,
+	}
+	out.Candidates = []Candidate{{Text: out.Text, Thoughts: out.Thoughts, FinishReason: out.FinishReason}}
+	if resp.Usage != nil {
+		out.Usage = resp.Usage.usage()
+	}
+	return out
+}
+
+// usage converts Bedrock token counts. Bedrock includes reasoning tokens
+// in the output count without reporting them apart.
+func (u *bedrockUsage) usage() Usage {
+	return Usage{
+		PromptTokens:     u.InputTokens,
+		CompletionTokens: u.OutputTokens,
+		TotalTokens:      u.TotalTokens,
+	}
+}
+
+// bedrockExceptionStatus maps the exceptions sent inside a stream to the
+// HTTP status the API uses for them.
+var bedrockExceptionStatus = map[string]int{
+	"validationException":         http.StatusBadRequest,
+	"accessDeniedException":       http.StatusForbidden,
+	"resourceNotFoundException":   http.StatusNotFound,
+	"throttlingException":         http.StatusTooManyRequests,
+	"modelStreamErrorException":   http.StatusFailedDependency,
+	"modelTimeoutException":       http.StatusRequestTimeout,
+	"serviceUnavailableException": http.StatusServiceUnavailable,
+	"internalServerException":     http.StatusInternalServerError,
+}
+
+// consumeEventStream reads ConverseStream messages from the response body
+// and sends parsed chunks on the channel. Usage, reported in the final
+// metadata event, is sent in a trailing chunk together with the stop reason
+// of the preceding messageStop event.
+func (g *BedrockGenerator) consumeEventStream(ctx context.Context, body io.Reader, send func(StreamChunk)) {
+	var stopReason string
+	for {
+		if ctx.Err() != nil {
+			send(StreamChunk{Error: timeoutCause(ctx, ctx.Err())})
+			return
+		}
+
+		msg, err := readEventStreamMessage(body)
+		if err == io.EOF {
+			if stopReason != "" {
+				send(StreamChunk{FinishReason: stopReason})
+			}
+			return
+		}
+		if err != nil {
+			send(StreamChunk{Error: timeoutCause(ctx, fmt.Errorf("generators: bedrock %w", err))})
+			return
+		}
+
+		// Error messages may carry their details in headers only.
+		var event bedrockStreamEvent
+		if len(msg.Payload) > 0 {
+			if err := json.Unmarshal(msg.Payload, &event); err != nil {
+				send(StreamChunk{Error: fmt.Errorf("generators: bedrock event unmarshal: %w", err)})
+				return
+			}
+		}
+
+		switch msg.Headers[":message-type"] {
+		case "event":
+		case "exception":
+			kind := msg.Headers[":exception-type"]
+			status, ok := bedrockExceptionStatus[kind]
+			if !ok {
+				status = http.StatusInternalServerError
+			}
+			send(StreamChunk{Error: &APIError{Provider: "bedrock", StatusCode: status, Body: kind + ": " + event.Message}})
+			return
+		default:
+			send(StreamChunk{Error: fmt.Errorf("generators: bedrock stream error %s: %s",
+				msg.Headers[":error-code"], msg.Headers[":error-message"])})
+			return
+		}
+
+		switch msg.Headers[":event-type"] {
+		case "contentBlockDelta":
+			if d := event.Delta; d != nil {
+				if d.ReasoningContent != nil && d.ReasoningContent.Text != "" {
+					send(StreamChunk{Thought: d.ReasoningContent.Text})
+				}
+				if d.Text != "" {
+					send(StreamChunk{Text: d.Text})
+				}
+			}
+		case "messageStop":
+			stopReason = event.StopReason
+		case "metadata":
+			chunk := StreamChunk{FinishReason: stopReason}
+			if event.Usage != nil {
+				u := event.Usage.usage()
+				chunk.Usage = &u
+			}
+			if chunk.Usage != nil || chunk.FinishReason != "" {
+				send(chunk)
+			}
+			stopReason = ""
+		}
+	}
+}
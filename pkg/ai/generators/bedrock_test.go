@@ -0,0 +1,312 @@
+package generators
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// encodeEventStream encodes a message in the AWS event-stream framing with
+// string headers.
+func encodeEventStream(headers map[string]string, payload string) []byte {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var hdr bytes.Buffer
+	for _, name := range names {
+		hdr.WriteByte(byte(len(name)))
+		hdr.WriteString(name)
+		hdr.WriteByte(7)
+		binary.Write(&hdr, binary.BigEndian, uint16(len(headers[name])))
+		hdr.WriteString(headers[name])
+	}
+	total := eventStreamPreludeLen + hdr.Len() + len(payload) + 4
+	msg := binary.BigEndian.AppendUint32(nil, uint32(total))
+	msg = binary.BigEndian.AppendUint32(msg, uint32(hdr.Len()))
+	msg = binary.BigEndian.AppendUint32(msg, crc32.ChecksumIEEE(msg))
+	msg = append(msg, hdr.Bytes()...)
+	msg = append(msg, payload...)
+	return binary.BigEndian.AppendUint32(msg, crc32.ChecksumIEEE(msg))
+}
+
+// bedrockEvent encodes a ConverseStream event.
+func bedrockEvent(eventType, payload string) []byte {
+	return encodeEventStream(map[string]string{
+		":message-type": "event",
+		":event-type":   eventType,
+		":content-type": "application/json",
+	}, payload)
+}
+
+var testAWSCredentials = awsCredentials{AccessKeyID: "AKIDTEST", SecretAccessKey: "test-secret"}
+
+// bedrockServer is a stand-in for the Bedrock runtime API that checks the
+// request signature and calls handle.
+func bedrockServer(t *testing.T, handle func(w http.ResponseWriter, r *http.Request, req bedrockRequest)) *BedrockGenerator {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		// Sign a copy of the request as received and compare.
+		signed, _ := time.Parse(awsTimeFormat, r.Header.Get("X-Amz-Date"))
+		check := r.Clone(context.Background())
+		check.URL.Host = r.Host
+		check.Header.Del("Authorization")
+		signAWSRequest(check, body, testAWSCredentials, "us-east-1", bedrockService, signed)
+		if got, want := r.Header.Get("Authorization"), check.Header.Get("Authorization"); got == "" || got != want {
+			t.Errorf("Authorization = %q, want %q", got, want)
+		}
+
+		var req bedrockRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Errorf("request body: %v", err)
+		}
+		handle(w, r, req)
+	}))
+	t.Cleanup(server.Close)
+	return &BedrockGenerator{
+		httpClient: server.Client(),
+		baseURL:    server.URL,
+		region:     "us-east-1",
+		model:      "anthropic.claude-3-5-haiku-20241022-v1:0",
+		creds:      testAWSCredentials,
+	}
+}
+
+func TestBedrockOpener_Open(t *testing.T) {
+	t.Setenv(envAWSAccessKeyID, "AKIDENV")
+	t.Setenv(envAWSSecretAccessKey, "secret")
+	t.Setenv(envAWSRegion, "eu-west-1")
+
+	tests := []struct {
+		rawurl  string
+		region  string
+		model   string
+		baseURL string
+	}{
+		{"bedrock://us-east-1/anthropic.claude-3-5-haiku-20241022-v1:0", "us-east-1", "anthropic.claude-3-5-haiku-20241022-v1:0", "https://bedrock-runtime.us-east-1.amazonaws.com"},
+		{"bedrock:///amazon.nova-lite-v1:0", "eu-west-1", "amazon.nova-lite-v1:0", "https://bedrock-runtime.eu-west-1.amazonaws.com"},
+		{"bedrock://us-west-2/m?endpoint=http://127.0.0.1:9/", "us-west-2", "m", "http://127.0.0.1:9"},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.rawurl)
+		gen, err := (&BedrockOpener{}).Open(context.Background(), u)
+		if err != nil {
+			t.Fatalf("Open(%q) error = %v", tt.rawurl, err)
+		}
+		g := gen.(*BedrockGenerator)
+		if g.region != tt.region || g.model != tt.model || g.baseURL != tt.baseURL || g.creds.AccessKeyID != "AKIDENV" {
+			t.Errorf("Open(%q) = region %q, model %q, baseURL %q", tt.rawurl, g.region, g.model, g.baseURL)
+		}
+	}
+
+	for _, rawurl := range []string{"bedrock://us-east-1/", "bedrock://us-east-1/m?x=1", "bedrock://us-east-1/m?profile=nope"} {
+		t.Setenv(envAWSSharedCredentialsFile, "/does/not/exist")
+		u, _ := url.Parse(rawurl)
+		if _, err := (&BedrockOpener{}).Open(context.Background(), u); err == nil {
+			t.Errorf("Open(%q) succeeded", rawurl)
+		}
+	}
+}
+
+func TestBedrockBuildRequest(t *testing.T) {
+	g := &BedrockGenerator{model: "anthropic.claude-3-7-sonnet-20250219-v1:0"}
+	cfg := newConfig([]Option{
+		WithSystemInstruction("be brief"), WithTemperature(0), WithMaxOutputTokens(100),
+		WithStopSequences("END"), WithTopK(5), WithThinkingBudget(1024),
+	})
+	data, _ := json.Marshal(g.buildRequest(cfg, "hi"))
+	want := `{"messages":[{"role":"user","content":[{"text":"hi"}]}],"system":[{"text":"be brief"}],` +
+		`"inferenceConfig":{"maxTokens":100,"temperature":0,"stopSequences":["END"]},` +
+		`"additionalModelRequestFields":{"thinking":{"budget_tokens":1024,"type":"enabled"},"top_k":5}}`
+	if string(data) != want {
+		t.Errorf("request =\n%s\nwant\n%s", data, want)
+	}
+
+	g.model = "amazon.nova-lite-v1:0"
+	data, _ = json.Marshal(g.buildRequest(newConfig([]Option{WithTopK(5)}), "hi"))
+	if want := `{"messages":[{"role":"user","content":[{"text":"hi"}]}]}`; string(data) != want {
+		t.Errorf("request = %s, want %s", data, want)
+	}
+	if _, err := g.Generate(context.Background(), "hi", WithStrict(), WithTopK(5)); !errors.Is(err, ErrUnsupportedOption) {
+		t.Errorf("strict Generate error = %v", err)
+	}
+}
+
+func TestBedrockGenerate_HTTPTestServer(t *testing.T) {
+	gen := bedrockServer(t, func(w http.ResponseWriter, r *http.Request, req bedrockRequest) {
+		if want := "/model/anthropic.claude-3-5-haiku-20241022-v1%3A0/converse"; r.URL.EscapedPath() != want {
+			t.Errorf("path = %q, want %q", r.URL.EscapedPath(), want)
+		}
+		if req.Messages[0].Content[0].Text != "hello" {
+			t.Errorf("request = %+v", req)
+		}
+		fmt.Fprint(w, `{"output":{"message":{"role":"assistant","content":[`+
+			`{"reasoningContent":{"reasoningText":{"text":"thinking","signature":"s"}}},{"text":"Hi there"}]}},`+
+			`"stopReason":"end_turn","usage":{"inputTokens":3,"outputTokens":5,"totalTokens":8}}`)
+	})
+
+	resp, err := gen.Generate(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if resp.Text != "Hi there" || resp.Thoughts != "thinking" || resp.FinishReason != "end_turn" {
+		t.Errorf("response = %+v", resp)
+	}
+	if resp.Usage != (Usage{PromptTokens: 3, CompletionTokens: 5, TotalTokens: 8}) {
+		t.Errorf("usage = %+v", resp.Usage)
+	}
+}
+
+func TestBedrockGenerate_APIError(t *testing.T) {
+	gen := bedrockServer(t, func(w http.ResponseWriter, r *http.Request, req bedrockRequest) {
+		w.Header().Set("x-amzn-ErrorType", "AccessDeniedException")
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"message":"no access"}`)
+	})
+	_, err := gen.Generate(context.Background(), "hello")
+	if !IsAuthError(err) {
+		t.Errorf("error = %v, want auth APIError", err)
+	}
+}
+
+func TestBedrockStream_HTTPTestServer(t *testing.T) {
+	gen := bedrockServer(t, func(w http.ResponseWriter, r *http.Request, req bedrockRequest) {
+		if !strings.HasSuffix(r.URL.EscapedPath(), "/converse-stream") {
+			t.Errorf("path = %q", r.URL.EscapedPath())
+		}
+		w.Header().Set("Content-Type", "application/vnd.amazon.eventstream")
+		w.Write(bedrockEvent("messageStart", `{"role":"assistant"}`))
+		w.Write(bedrockEvent("contentBlockDelta", `{"contentBlockIndex":0,"delta":{"reasoningContent":{"text":"hmm"}}}`))
+		w.Write(bedrockEvent("contentBlockDelta", `{"contentBlockIndex":1,"delta":{"text":"Hello "}}`))
+		w.Write(bedrockEvent("contentBlockDelta", `{"contentBlockIndex":1,"delta":{"text":"world"}}`))
+		w.Write(bedrockEvent("contentBlockStop", `{"contentBlockIndex":1}`))
+		w.Write(bedrockEvent("messageStop", `{"stopReason":"end_turn"}`))
+		w.Write(bedrockEvent("metadata", `{"usage":{"inputTokens":2,"outputTokens":4,"totalTokens":6},"metrics":{"latencyMs":10}}`))
+	})
+
+	ch, err := gen.Stream(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	var text, thought, reason string
+	var usage *Usage
+	for chunk := range ch {
+		if chunk.Error != nil {
+			t.Fatalf("chunk error = %v", chunk.Error)
+		}
+		text += chunk.Text
+		thought += chunk.Thought
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+			reason = chunk.FinishReason
+		}
+	}
+	if text != "Hello world" || thought != "hmm" {
+		t.Errorf("text = %q, thought = %q", text, thought)
+	}
+	if usage == nil || usage.TotalTokens != 6 || reason != "end_turn" {
+		t.Errorf("usage = %+v, finish reason = %q", usage, reason)
+	}
+}
+
+func TestBedrockStream_Exception(t *testing.T) {
+	gen := bedrockServer(t, func(w http.ResponseWriter, r *http.Request, req bedrockRequest) {
+		w.Write(bedrockEvent("contentBlockDelta", `{"delta":{"text":"partial"}}`))
+		w.Write(encodeEventStream(map[string]string{
+			":message-type":   "exception",
+			":exception-type": "throttlingException",
+		}, `{"message":"slow down"}`))
+	})
+
+	ch, err := gen.Stream(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	var last error
+	for chunk := range ch {
+		if chunk.Error != nil {
+			last = chunk.Error
+		}
+	}
+	var apiErr *APIError
+	if !errors.As(last, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests || !strings.Contains(apiErr.Body, "slow down") {
+		t.Errorf("error = %v, want throttling APIError", last)
+	}
+}
+
+func TestReadEventStreamMessage(t *testing.T) {
+	msg := encodeEventStream(map[string]string{":event-type": "metadata"}, `{"a":1}`)
+	got, err := readEventStreamMessage(bytes.NewReader(msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Headers[":event-type"] != "metadata" || string(got.Payload) != `{"a":1}` {
+		t.Errorf("message = %+v", got)
+	}
+
+	// Headers of other types are skipped.
+	var hdr []byte
+	hdr = append(hdr, 4)
+	hdr = append(hdr, "flag"...)
+	hdr = append(hdr, 0)
+	hdr = append(hdr, 2)
+	hdr = append(hdr, "id"...)
+	hdr = append(hdr, 9)
+	hdr = append(hdr, make([]byte, 16)...)
+	if h, err := parseEventStreamHeaders(hdr); err != nil || len(h) != 0 {
+		t.Errorf("parseEventStreamHeaders = %v, %v", h, err)
+	}
+
+	corrupt := bytes.Clone(msg)
+	corrupt[len(corrupt)-6] ^= 1
+	if _, err := readEventStreamMessage(bytes.NewReader(corrupt)); err == nil {
+		t.Error("corrupt message accepted")
+	}
+	if _, err := readEventStreamMessage(bytes.NewReader(msg[:20])); err == nil || err == io.EOF {
+		t.Errorf("truncated message error = %v", err)
+	}
+	if _, err := readEventStreamMessage(bytes.NewReader(nil)); err != io.EOF {
+		t.Errorf("empty stream error = %v, want io.EOF", err)
+	}
+
+	// A headers length that would wrap the bound check around is rejected.
+	huge := binary.BigEndian.AppendUint32(nil, 16)
+	huge = binary.BigEndian.AppendUint32(huge, 0xFFFFFFF8)
+	huge = binary.BigEndian.AppendUint32(huge, crc32.ChecksumIEEE(huge))
+	huge = append(huge, 0, 0, 0, 0)
+	if _, err := readEventStreamMessage(bytes.NewReader(huge)); err == nil || !strings.Contains(err.Error(), "invalid message length") {
+		t.Errorf("huge headers length error = %v", err)
+	}
+}
+
+func FuzzReadEventStreamMessage(f *testing.F) {
+	f.Add(encodeEventStream(map[string]string{":event-type": "metadata"}, `{"a":1}`))
+	f.Add(encodeEventStream(nil, ""))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Fix up the prelude checksum so that the fuzzer gets past it.
+		if len(data) >= eventStreamPreludeLen {
+			binary.BigEndian.PutUint32(data[8:12], crc32.ChecksumIEEE(data[:8]))
+		}
+		r := bytes.NewReader(data)
+		for {
+			if _, err := readEventStreamMessage(r); err != nil {
+				return
+			}
+		}
+	})
+}
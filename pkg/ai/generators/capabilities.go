@@ -0,0 +1,120 @@
+package generators
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var (
+	// ErrUnsupportedOption is returned in strict mode when a request sets an
+	// option that the provider or model cannot honor.
+	ErrUnsupportedOption = errors.New("option not supported by provider")
+)
+
+// Option names reported in Capabilities.SupportedOptions and in
+// ErrUnsupportedOption errors.
+const (
+	OptionTemperature       = "temperature"
+	OptionMaxOutputTokens   = "max_output_tokens"
+	OptionTopP              = "top_p"
+	OptionTopK              = "top_k"
+	OptionSystemInstruction = "system_instruction"
+	OptionStopSequences     = "stop_sequences"
+	OptionSeed              = "seed"
+	OptionPresencePenalty   = "presence_penalty"
+	OptionFrequencyPenalty  = "frequency_penalty"
+	OptionCandidateCount    = "candidate_count"
+	OptionResponseLogprobs  = "response_logprobs"
+	OptionThinkingBudget    = "thinking_budget"
+	OptionThinkingLevel     = "thinking_level"
+	OptionIncludeThoughts   = "include_thoughts"
+	OptionJSONOutput        = "json_output"
+	OptionSafetySettings    = "safety_settings"
+	OptionGoogleSearch      = "google_search"
+	OptionCachedContent     = "cached_content"
+	OptionFileData          = "file_data"
+)
+
+// Capabilities describes the features a generator can provide for a model.
+// A MaxContextTokens of zero means the context window is unknown.
+type Capabilities struct {
+	Streaming        bool
+	Tools            bool
+	JSONSchema       bool
+	Vision           bool
+	Embeddings       bool
+	MaxContextTokens int
+	SupportedOptions []string
+}
+
+// Supports reports whether the named option is honored.
+func (c Capabilities) Supports(option string) bool {
+	return slices.Contains(c.SupportedOptions, option)
+}
+
+// CapabilityReporter is implemented by generators that can describe the
+// features available for their configured model.
+type CapabilityReporter interface {
+
+	// Capabilities returns the features available for the generator's model.
+	Capabilities() Capabilities
+}
+
+// GetCapabilities returns the capabilities reported by the generator.
+// It returns false if the generator does not implement CapabilityReporter.
+func GetCapabilities(gen Generator) (Capabilities, bool) {
+	if r, ok := gen.(CapabilityReporter); ok {
+		return r.Capabilities(), true
+	}
+	return Capabilities{}, false
+}
+
+// setOptions returns the names of the options explicitly set in the config.
+func (c *Config) setOptions() []string {
+	var names []string
+	add := func(set bool, name string) {
+		if set {
+			names = append(names, name)
+		}
+	}
+	add(c.Temperature != nil, OptionTemperature)
+	add(c.MaxOutputTokens != nil, OptionMaxOutputTokens)
+	add(c.TopP != nil, OptionTopP)
+	add(c.TopK != nil, OptionTopK)
+	add(c.SystemInstruction != "", OptionSystemInstruction)
+	add(len(c.StopSequences) > 0, OptionStopSequences)
+	add(c.Seed != nil, OptionSeed)
+	add(c.PresencePenalty != nil, OptionPresencePenalty)
+	add(c.FrequencyPenalty != nil, OptionFrequencyPenalty)
+	add(c.CandidateCount != nil, OptionCandidateCount)
+	add(c.ResponseLogprobs, OptionResponseLogprobs)
+	add(c.ThinkingBudget != nil, OptionThinkingBudget)
+	add(c.ThinkingLevel != "", OptionThinkingLevel)
+	add(c.IncludeThoughts, OptionIncludeThoughts)
+	add(c.JSONOutput, OptionJSONOutput)
+	add(len(c.SafetySettings) > 0, OptionSafetySettings)
+	add(c.GoogleSearch, OptionGoogleSearch)
+	add(c.CachedContent != "", OptionCachedContent)
+	add(len(c.Files) > 0, OptionFileData)
+	return append(names, c.Ollama.setOptions()...)
+}
+
+// checkStrict returns an ErrUnsupportedOption error if the config is in
+// strict mode and sets any option not listed in caps.
+func (c *Config) checkStrict(caps Capabilities, model string) error {
+	if !c.Strict {
+		return nil
+	}
+	var unsupported []string
+	for _, name := range c.setOptions() {
+		if !caps.Supports(name) {
+			unsupported = append(unsupported, name)
+		}
+	}
+	if len(unsupported) > 0 {
+		return fmt.Errorf("generators: %w: %s (model %s)", ErrUnsupportedOption, strings.Join(unsupported, ", "), model)
+	}
+	return nil
+}
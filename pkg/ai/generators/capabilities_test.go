@@ -0,0 +1,108 @@
+package generators
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetCapabilities(t *testing.T) {
+	testCases := []struct {
+		name       string
+		gen        Generator
+		wantOK     bool
+		wantOption string
+		wantMax    int
+	}{
+		{name: "mock generator does not report", gen: &mockGenerator{}, wantOK: false},
+		{name: "gemini reports context window", gen: &GeminiGenerator{model: "gemini-2.0-flash"}, wantOK: true, wantOption: OptionCandidateCount, wantMax: 1048576},
+		{name: "gemini unknown model", gen: &GeminiGenerator{model: "custom"}, wantOK: true, wantOption: OptionSeed, wantMax: 0},
+		{name: "ollama reports options", gen: &OllamaGenerator{model: "llama3.2"}, wantOK: true, wantOption: OptionSeed, wantMax: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			caps, ok := GetCapabilities(tc.gen)
+			if ok != tc.wantOK {
+				t.Fatalf("GetCapabilities() ok = %v, want %v", ok, tc.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if !caps.Streaming {
+				t.Error("expected Streaming capability")
+			}
+			if !caps.Supports(tc.wantOption) {
+				t.Errorf("Supports(%q) = false, want true", tc.wantOption)
+			}
+			if caps.MaxContextTokens != tc.wantMax {
+				t.Errorf("MaxContextTokens = %d, want %d", caps.MaxContextTokens, tc.wantMax)
+			}
+		})
+	}
+}
+
+func TestConfigCheckStrict(t *testing.T) {
+	caps := Capabilities{SupportedOptions: []string{OptionTemperature}}
+
+	testCases := []struct {
+		name    string
+		opts    []Option
+		wantErr string
+	}{
+		{name: "non-strict ignores unsupported", opts: []Option{WithSeed(1)}},
+		{name: "strict with supported option", opts: []Option{WithStrict(), WithTemperature(0)}},
+		{name: "strict with unsupported options", opts: []Option{WithStrict(), WithSeed(1), WithCandidateCount(2)}, wantErr: "seed, candidate_count"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := newConfig(tc.opts).checkStrict(caps, "m")
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrUnsupportedOption) {
+				t.Fatalf("error = %v, want ErrUnsupportedOption", err)
+			}
+			if !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("error = %q, should mention %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestOllamaGenerate_Strict(t *testing.T) {
+	var called bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.Write([]byte(`{"response":"ok","done":true}`))
+	}))
+	defer server.Close()
+
+	gen := &OllamaGenerator{httpClient: server.Client(), baseURL: server.URL, model: "llama3.2"}
+
+	if _, err := gen.Generate(context.Background(), "hi", WithCandidateCount(2)); err != nil {
+		t.Fatalf("non-strict Generate() error = %v", err)
+	}
+	if !called {
+		t.Fatal("expected non-strict request to reach the server")
+	}
+
+	called = false
+	_, err := gen.Generate(context.Background(), "hi", WithCandidateCount(2), WithStrict())
+	if !errors.Is(err, ErrUnsupportedOption) {
+		t.Fatalf("strict Generate() error = %v, want ErrUnsupportedOption", err)
+	}
+	if _, err := gen.Stream(context.Background(), "hi", WithCandidateCount(2), WithStrict()); !errors.Is(err, ErrUnsupportedOption) {
+		t.Fatalf("strict Stream() error = %v, want ErrUnsupportedOption", err)
+	}
+	if called {
+		t.Error("strict mode should fail before sending the request")
+	}
+}
@@ -1,18 +1,44 @@
 package generators
 
+import "time"
+
 // Option is a functional option for configuring generation requests.
 type Option func(*Config)
 
 // Config holds configuration for a single generation request.
-// Zero-value fields mean "use the provider's default".
+//
+// Numeric sampling fields are pointers so that an explicit zero (for
+// example a temperature of 0 for deterministic output) can be told apart
+// from "not set". A nil pointer, empty string or nil slice means "use the
+// provider's default".
 type Config struct {
 	Model             string
-	Temperature       float32
-	MaxOutputTokens   int
-	TopP              float32
-	TopK              float32
+	Temperature       *float32
+	MaxOutputTokens   *int
+	TopP              *float32
+	TopK              *float32
 	SystemInstruction string
 	StopSequences     []string
+	Seed              *int
+	PresencePenalty   *float32
+	FrequencyPenalty  *float32
+	CandidateCount    *int
+	ResponseLogprobs  bool
+	TopLogprobs       *int
+	ThinkingBudget    *int
+	ThinkingLevel     string
+	IncludeThoughts   bool
+	JSONOutput        bool
+	SafetySettings    []SafetySetting
+	GoogleSearch      bool
+	CachedContent     string
+	Files             []FileData
+	Strict            bool
+	Hooks             []Hook
+	Timeout           time.Duration
+	FirstTokenTimeout time.Duration
+	IdleTimeout       time.Duration
+	Ollama            OllamaConfig
 }
 
 // newConfig applies the given options to a zero-value Config and returns it.
@@ -30,23 +56,24 @@ func WithModel(model string) Option {
 }
 
 // WithTemperature sets the sampling temperature. Higher values increase randomness.
+// A temperature of 0 is sent to the provider as-is.
 func WithTemperature(t float32) Option {
-	return func(c *Config) { c.Temperature = t }
+	return func(c *Config) { c.Temperature = &t }
 }
 
 // WithMaxOutputTokens sets the maximum number of tokens in the response.
 func WithMaxOutputTokens(n int) Option {
-	return func(c *Config) { c.MaxOutputTokens = n }
+	return func(c *Config) { c.MaxOutputTokens = &n }
 }
 
 // WithTopP sets the nucleus sampling probability threshold.
 func WithTopP(p float32) Option {
-	return func(c *Config) { c.TopP = p }
+	return func(c *Config) { c.TopP = &p }
 }
 
 // WithTopK sets the top-K sampling threshold.
 func WithTopK(k float32) Option {
-	return func(c *Config) { c.TopK = k }
+	return func(c *Config) { c.TopK = &k }
 }
 
 // WithSystemInstruction sets the system-level instruction for the generation.
@@ -58,3 +85,138 @@ func WithSystemInstruction(instruction string) Option {
 func WithStopSequences(seqs ...string) Option {
 	return func(c *Config) { c.StopSequences = seqs }
 }
+
+// WithSeed sets the random seed used for sampling. Repeating a request with
+// the same seed and parameters yields reproducible output where the provider
+// supports it.
+func WithSeed(seed int) Option {
+	return func(c *Config) { c.Seed = &seed }
+}
+
+// WithPresencePenalty penalizes tokens that have already appeared in the
+// output, encouraging the model to introduce new content.
+func WithPresencePenalty(p float32) Option {
+	return func(c *Config) { c.PresencePenalty = &p }
+}
+
+// WithFrequencyPenalty penalizes tokens proportionally to how often they
+// have already appeared in the output, reducing repetition.
+func WithFrequencyPenalty(p float32) Option {
+	return func(c *Config) { c.FrequencyPenalty = &p }
+}
+
+// WithCandidateCount sets the number of alternative responses to generate.
+// The extra candidates are returned in Response.Candidates.
+func WithCandidateCount(n int) Option {
+	return func(c *Config) { c.CandidateCount = &n }
+}
+
+// WithResponseLogprobs requests per-token log probabilities for the output.
+// topN is the number of most likely alternatives to report for each token;
+// zero reports only the chosen tokens.
+func WithResponseLogprobs(topN int) Option {
+	return func(c *Config) {
+		c.ResponseLogprobs = true
+		c.TopLogprobs = &topN
+	}
+}
+
+// WithThinkingBudget sets the number of tokens the model may spend on
+// internal reasoning before answering. Zero disables thinking on models that
+// allow it and -1 lets the model choose the budget dynamically.
+func WithThinkingBudget(tokens int) Option {
+	return func(c *Config) { c.ThinkingBudget = &tokens }
+}
+
+// WithThinkingLevel sets the relative amount of reasoning, such as "low" or
+// "high", on models that use thinking levels instead of token budgets.
+func WithThinkingLevel(level string) Option {
+	return func(c *Config) { c.ThinkingLevel = level }
+}
+
+// WithIncludeThoughts requests the model's reasoning summaries. They are
+// returned in Response.Thoughts and StreamChunk.Thought, separate from the
+// answer text.
+func WithIncludeThoughts() Option {
+	return func(c *Config) { c.IncludeThoughts = true }
+}
+
+// WithJSONOutput constrains the response to a single JSON value. The
+// prompt should still describe the expected shape.
+func WithJSONOutput() Option {
+	return func(c *Config) { c.JSONOutput = true }
+}
+
+// Harm categories accepted in SafetySetting.Category.
+const (
+	HarmCategoryHarassment       = "HARM_CATEGORY_HARASSMENT"
+	HarmCategoryHateSpeech       = "HARM_CATEGORY_HATE_SPEECH"
+	HarmCategorySexuallyExplicit = "HARM_CATEGORY_SEXUALLY_EXPLICIT"
+	HarmCategoryDangerousContent = "HARM_CATEGORY_DANGEROUS_CONTENT"
+	HarmCategoryCivicIntegrity   = "HARM_CATEGORY_CIVIC_INTEGRITY"
+)
+
+// Block thresholds accepted in SafetySetting.Threshold.
+const (
+	BlockNone           = "BLOCK_NONE"
+	BlockOnlyHigh       = "BLOCK_ONLY_HIGH"
+	BlockMediumAndAbove = "BLOCK_MEDIUM_AND_ABOVE"
+	BlockLowAndAbove    = "BLOCK_LOW_AND_ABOVE"
+	BlockOff            = "OFF"
+)
+
+// SafetySetting sets the blocking threshold for a harm category.
+type SafetySetting struct {
+	Category  string
+	Threshold string
+}
+
+// WithSafetySettings sets the safety filter thresholds for the request.
+func WithSafetySettings(settings ...SafetySetting) Option {
+	return func(c *Config) { c.SafetySettings = settings }
+}
+
+// WithGoogleSearch lets the model ground its answer on Google Search
+// results. The sources used are returned in Candidate.Grounding.
+func WithGoogleSearch() Option {
+	return func(c *Config) { c.GoogleSearch = true }
+}
+
+// WithCachedContent makes the request reuse content cached with
+// GeminiGenerator.CreateCachedContent, such as a long system instruction or
+// reference documents, instead of sending it again. name is the cache's
+// resource name or ID. The cached prompt tokens are reported in
+// Usage.CachedTokens.
+func WithCachedContent(name string) Option {
+	return func(c *Config) { c.CachedContent = name }
+}
+
+// FileData references media stored outside the request, such as a file
+// uploaded with GeminiGenerator.UploadFile or, on Vertex AI, a gs:// URI.
+type FileData struct {
+	URI      string
+	MIMEType string
+}
+
+// WithFileData attaches files to the prompt, for multimodal models to
+// read along with it. Use it for media too large to send inline.
+func WithFileData(files ...FileData) Option {
+	return func(c *Config) { c.Files = append(c.Files, files...) }
+}
+
+// WithStrict makes Generate and Stream fail with ErrUnsupportedOption when
+// the request sets an option the provider or model cannot honor, instead of
+// silently ignoring it.
+func WithStrict() Option {
+	return func(c *Config) { c.Strict = true }
+}
+
+// ptrFloat32 returns a pointer to the given float32 value.
+func ptrFloat32(v float32) *float32 {
+	return &v
+}
+
+// ptrInt returns a pointer to the given int value.
+func ptrInt(v int) *int {
+	return &v
+}
@@ -0,0 +1,48 @@
+package generators
+
+import (
+	"context"
+	"fmt"
+)
+
+// Embedder is implemented by generators that can compute text embeddings.
+// Not every provider or model supports embeddings; use GetEmbedder to check.
+type Embedder interface {
+
+	// Embed returns one embedding vector per input text, in the same order.
+	// WithModel selects the embedding model for the request.
+	Embed(ctx context.Context, texts []string, opts ...Option) ([][]float32, error)
+
+	// Close releases any resources held by the embedder.
+	Close() error
+}
+
+// GetEmbedder returns gen as an Embedder if it supports embeddings.
+func GetEmbedder(gen Generator) (Embedder, bool) {
+	e, ok := gen.(Embedder)
+	return e, ok
+}
+
+// OpenEmbedder opens a generator with Open and returns it as an Embedder.
+// It fails if the provider does not support embeddings.
+//
+// Example:
+//
+//	emb, err := generators.OpenEmbedder(ctx, "ollama://localhost:11434/nomic-embed-text")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer emb.Close()
+//	vectors, err := emb.Embed(ctx, []string{"hello", "world"})
+func OpenEmbedder(ctx context.Context, aiurl string) (Embedder, error) {
+	gen, err := Open(ctx, aiurl)
+	if err != nil {
+		return nil, err
+	}
+	e, ok := GetEmbedder(gen)
+	if !ok {
+		gen.Close()
+		return nil, fmt.Errorf("generators: %T does not support embeddings", gen)
+	}
+	return e, nil
+}
@@ -0,0 +1,50 @@
+package generators
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// APIError is returned when a provider API responds with a non-success
+// HTTP status code.
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Body       string
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	return fmt.Sprintf("generators: %s API error (status %d): %s", e.Provider, e.StatusCode, e.Body)
+}
+
+// BlockedError is returned when a provider refuses to process a prompt
+// because of its safety or policy filters. Streams also end with a
+// BlockedError when the filters stop the response midway; the text
+// received before it is partial.
+type BlockedError struct {
+	Provider      string
+	Reason        string
+	Message       string
+	SafetyRatings []SafetyRating
+}
+
+// Error implements the error interface.
+func (e *BlockedError) Error() string {
+	msg := fmt.Sprintf("generators: %s blocked the prompt (reason %s)", e.Provider, e.Reason)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+// IsAuthError reports whether err is an APIError caused by missing or
+// rejected credentials.
+func IsAuthError(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package generators
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+const (
+	// eventStreamPreludeLen is the size of the total length, headers
+	// length and prelude checksum that start every message.
+	eventStreamPreludeLen = 12
+
+	// eventStreamMaxMessageLen bounds the size of a single message.
+	eventStreamMaxMessageLen = 16 << 20
+)
+
+// eventStreamMessage is a message of the AWS binary event-stream framing
+// used by streaming AWS APIs. Only string header values are kept; headers
+// of other types are skipped.
+type eventStreamMessage struct {
+	Headers map[string]string
+	Payload []byte
+}
+
+// readEventStreamMessage reads the next message from r, verifying its
+// checksums. It returns io.EOF when r ends between messages.
+func readEventStreamMessage(r io.Reader) (eventStreamMessage, error) {
+	prelude := make([]byte, eventStreamPreludeLen)
+	if _, err := io.ReadFull(r, prelude); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return eventStreamMessage{}, errors.New("event stream: truncated prelude")
+		}
+		return eventStreamMessage{}, err
+	}
+	totalLen := binary.BigEndian.Uint32(prelude[0:4])
+	headersLen := binary.BigEndian.Uint32(prelude[4:8])
+	if crc32.ChecksumIEEE(prelude[:8]) != binary.BigEndian.Uint32(prelude[8:12]) {
+		return eventStreamMessage{}, errors.New("event stream: prelude checksum mismatch")
+	}
+	// The bound is computed in 64 bits so that a huge headers length cannot
+	// wrap around and pass.
+	if uint64(totalLen) < eventStreamPreludeLen+4+uint64(headersLen) || totalLen > eventStreamMaxMessageLen {
+		return eventStreamMessage{}, fmt.Errorf("event stream: invalid message length %d", totalLen)
+	}
+
+	msg := make([]byte, totalLen)
+	copy(msg, prelude)
+	if _, err := io.ReadFull(r, msg[eventStreamPreludeLen:]); err != nil {
+		return eventStreamMessage{}, fmt.Errorf("event stream: truncated message: %w", err)
+	}
+	end := totalLen - 4
+	if crc32.ChecksumIEEE(msg[:end]) != binary.BigEndian.Uint32(msg[end:]) {
+		return eventStreamMessage{}, errors.New("event stream: message checksum mismatch")
+	}
+
+	headers, err := parseEventStreamHeaders(msg[eventStreamPreludeLen : eventStreamPreludeLen+headersLen])
+	if err != nil {
+		return eventStreamMessage{}, err
+	}
+	return eventStreamMessage{Headers: headers, Payload: msg[eventStreamPreludeLen+headersLen : end]}, nil
+}
+
+// eventStreamValueLen is the size of the fixed-length header values, by
+// type: true, false, byte, short, int, long, timestamp and UUID. Types 6
+// and 7, bytes and string, carry their own length.
+var eventStreamValueLen = [...]int{0: 0, 1: 0, 2: 1, 3: 2, 4: 4, 5: 8, 8: 8, 9: 16}
+
+// parseEventStreamHeaders decodes the headers section of a message.
+func parseEventStreamHeaders(b []byte) (map[string]string, error) {
+	errTruncated := errors.New("event stream: truncated headers")
+	headers := make(map[string]string)
+	for len(b) > 0 {
+		nameLen := int(b[0])
+		if len(b) < 1+nameLen+1 {
+			return nil, errTruncated
+		}
+		name := string(b[1 : 1+nameLen])
+		typ := b[1+nameLen]
+		b = b[2+nameLen:]
+
+		switch typ {
+		case 6, 7: // bytes, string
+			if len(b) < 2 {
+				return nil, errTruncated
+			}
+			n := int(binary.BigEndian.Uint16(b))
+			if len(b) < 2+n {
+				return nil, errTruncated
+			}
+			if typ == 7 {
+				headers[name] = string(b[2 : 2+n])
+			}
+			b = b[2+n:]
+		case 0, 1, 2, 3, 4, 5, 8, 9:
+			n := eventStreamValueLen[typ]
+			if len(b) < n {
+				return nil, errTruncated
+			}
+			b = b[n:]
+		default:
+			return nil, fmt.Errorf("event stream: unknown header type %d", typ)
+		}
+	}
+	return headers, nil
+}
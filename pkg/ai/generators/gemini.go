@@ -27,6 +27,10 @@
 	// defaultGeminiModel is the default model used when none is specified in the URL.
 	defaultGeminiModel = "gemini-2.0-flash"
 
+	// defaultGeminiEmbeddingModel is the model used by Embed when neither the
+	// request nor the URL names an embedding model.
+	defaultGeminiEmbeddingModel = "gemini-embedding-001"
+
 	// envGeminiAPIKey is the primary environment variable for the Gemini API key.
 	envGeminiAPIKey = "GEMINI_API_KEY"
 
@@ -96,84 +100,314 @@ func lookupGeminiAPIKey() string {
 // --- Internal JSON types for the Gemini REST API ---
 
 type geminiRequest struct {
-	Contents          []geminiContent  `json:"contents"`
-	GenerationConfig  *geminiGenConfig `json:"generationConfig,omitempty"`
-	SystemInstruction *geminiContent   `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent       `json:"contents"`
+	CachedContent     string                `json:"cachedContent,omitempty"`
+	GenerationConfig  *geminiGenConfig      `json:"generationConfig,omitempty"`
+	SystemInstruction *geminiContent        `json:"systemInstruction,omitempty"`
+	SafetySettings    []geminiSafetySetting `json:"safetySettings,omitempty"`
+	Tools             []geminiTool          `json:"tools,omitempty"`
+}
+
+type geminiSafetySetting struct {
+	Category  string `json:"category"`
+	Threshold string `json:"threshold"`
+}
+
+type geminiTool struct {
+	GoogleSearch *struct{} `json:"google_search,omitempty"`
 }
 
 type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
 	Parts []geminiPart `json:"parts"`
 }
 
 type geminiPart struct {
-	Text string `json:"text"`
+	Text     string          `json:"text,omitempty"`
+	Thought  bool            `json:"thought,omitempty"`
+	FileData *geminiFileData `json:"fileData,omitempty"`
+}
+
+type geminiFileData struct {
+	MIMEType string `json:"mimeType,omitempty"`
+	FileURI  string `json:"fileUri"`
 }
 
 type geminiGenConfig struct {
-	Temperature     *float32 `json:"temperature,omitempty"`
-	MaxOutputTokens int      `json:"maxOutputTokens,omitempty"`
-	TopP            *float32 `json:"topP,omitempty"`
-	TopK            *float32 `json:"topK,omitempty"`
-	StopSequences   []string `json:"stopSequences,omitempty"`
+	Temperature      *float32 `json:"temperature,omitempty"`
+	MaxOutputTokens  *int     `json:"maxOutputTokens,omitempty"`
+	TopP             *float32 `json:"topP,omitempty"`
+	TopK             *float32 `json:"topK,omitempty"`
+	StopSequences    []string `json:"stopSequences,omitempty"`
+	Seed             *int     `json:"seed,omitempty"`
+	PresencePenalty  *float32 `json:"presencePenalty,omitempty"`
+	FrequencyPenalty *float32 `json:"frequencyPenalty,omitempty"`
+	CandidateCount   *int     `json:"candidateCount,omitempty"`
+	ResponseLogprobs bool     `json:"responseLogprobs,omitempty"`
+	Logprobs         *int     `json:"logprobs,omitempty"`
+	ResponseMIMEType string   `json:"responseMimeType,omitempty"`
+
+	ThinkingConfig *geminiThinkingConfig `json:"thinkingConfig,omitempty"`
+}
+
+type geminiThinkingConfig struct {
+	ThinkingBudget  *int   `json:"thinkingBudget,omitempty"`
+	ThinkingLevel   string `json:"thinkingLevel,omitempty"`
+	IncludeThoughts bool   `json:"includeThoughts,omitempty"`
 }
 
 type geminiResponse struct {
-	Candidates []struct {
-		Content      geminiContent `json:"content"`
-		FinishReason string        `json:"finishReason"`
-	} `json:"candidates"`
-	UsageMetadata *struct {
-		PromptTokenCount     int `json:"promptTokenCount"`
-		CandidatesTokenCount int `json:"candidatesTokenCount"`
-		TotalTokenCount      int `json:"totalTokenCount"`
-	} `json:"usageMetadata"`
+	Candidates     []geminiCandidate     `json:"candidates"`
+	UsageMetadata  *geminiUsageMetadata  `json:"usageMetadata"`
+	PromptFeedback *geminiPromptFeedback `json:"promptFeedback,omitempty"`
+}
+
+type geminiCandidate struct {
+	Content           geminiContent            `json:"content"`
+	FinishReason      string                   `json:"finishReason"`
+	LogprobsResult    *geminiLogprobsResult    `json:"logprobsResult,omitempty"`
+	SafetyRatings     []geminiSafetyRating     `json:"safetyRatings,omitempty"`
+	CitationMetadata  *geminiCitationMetadata  `json:"citationMetadata,omitempty"`
+	GroundingMetadata *geminiGroundingMetadata `json:"groundingMetadata,omitempty"`
+}
+
+type geminiPromptFeedback struct {
+	BlockReason        string               `json:"blockReason,omitempty"`
+	BlockReasonMessage string               `json:"blockReasonMessage,omitempty"`
+	SafetyRatings      []geminiSafetyRating `json:"safetyRatings,omitempty"`
+}
+
+type geminiSafetyRating struct {
+	Category    string `json:"category"`
+	Probability string `json:"probability"`
+	Blocked     bool   `json:"blocked,omitempty"`
+}
+
+type geminiCitationMetadata struct {
+	CitationSources []struct {
+		StartIndex int    `json:"startIndex"`
+		EndIndex   int    `json:"endIndex"`
+		URI        string `json:"uri"`
+		Title      string `json:"title"`
+		License    string `json:"license"`
+	} `json:"citationSources"`
+}
+
+type geminiGroundingMetadata struct {
+	GroundingChunks []struct {
+		Web *struct {
+			URI   string `json:"uri"`
+			Title string `json:"title"`
+		} `json:"web,omitempty"`
+	} `json:"groundingChunks"`
+	GroundingSupports []struct {
+		Segment struct {
+			StartIndex int    `json:"startIndex"`
+			EndIndex   int    `json:"endIndex"`
+			Text       string `json:"text"`
+		} `json:"segment"`
+		GroundingChunkIndices []int     `json:"groundingChunkIndices"`
+		ConfidenceScores      []float64 `json:"confidenceScores"`
+	} `json:"groundingSupports"`
+	WebSearchQueries []string `json:"webSearchQueries"`
+}
+
+// geminiEmbedRequest is the body of a batchEmbedContents request.
+type geminiEmbedRequest struct {
+	Requests []geminiEmbedContentRequest `json:"requests"`
+}
+
+type geminiEmbedContentRequest struct {
+	Model   string        `json:"model"`
+	Content geminiContent `json:"content"`
+}
+
+type geminiEmbedResponse struct {
+	Embeddings []struct {
+		Values []float32 `json:"values"`
+	} `json:"embeddings"`
+}
+
+type geminiUsageMetadata struct {
+	PromptTokenCount        int `json:"promptTokenCount"`
+	CandidatesTokenCount    int `json:"candidatesTokenCount"`
+	ThoughtsTokenCount      int `json:"thoughtsTokenCount,omitempty"`
+	CachedContentTokenCount int `json:"cachedContentTokenCount,omitempty"`
+	TotalTokenCount         int `json:"totalTokenCount"`
+}
+
+type geminiLogprobsResult struct {
+	TopCandidates []struct {
+		Candidates []geminiLogprobCandidate `json:"candidates"`
+	} `json:"topCandidates"`
+	ChosenCandidates []geminiLogprobCandidate `json:"chosenCandidates"`
+}
+
+type geminiLogprobCandidate struct {
+	Token          string  `json:"token"`
+	LogProbability float64 `json:"logProbability"`
 }
 
 // --- GeminiGenerator ---
 
 // GeminiGenerator implements the Generator interface for Google Gemini
-// using the REST API directly via net/http.
+// using the REST API directly via net/http. The same client serves the
+// Gemini API, authenticated with an API key, and Vertex AI, authenticated
+// with OAuth access tokens from tokens.
 type GeminiGenerator struct {
 	httpClient *http.Client
 	apiKey     string
 	model      string
 	baseURL    string
+	tokens     *googleTokenSource
+}
+
+// name returns the provider name reported in hooks and errors.
+func (g *GeminiGenerator) name() string {
+	if g.tokens != nil {
+		return "vertex"
+	}
+	return "gemini"
+}
+
+// authorize sets the credentials of req: the API key, or an access token
+// on Vertex AI.
+func (g *GeminiGenerator) authorize(req *http.Request) error {
+	if g.tokens == nil {
+		req.Header.Set("x-goog-api-key", g.apiKey)
+		return nil
+	}
+	token, err := g.tokens.token(req.Context())
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// apiRoot returns the base URL up to the API version, such as
+// https://generativelanguage.googleapis.com/v1beta, and the resource name
+// prefix of models, such as "models" or, on Vertex AI,
+// "projects/{project}/locations/{location}/publishers/google/models".
+func (g *GeminiGenerator) apiRoot() (root, models string) {
+	u, err := url.Parse(g.baseURL)
+	if err != nil {
+		return g.baseURL, "models"
+	}
+	version, models, _ := strings.Cut(strings.TrimPrefix(u.Path, "/"), "/")
+	if models == "" {
+		models = "models"
+	}
+	u.Path, u.RawPath = "/"+version, ""
+	return u.String(), models
+}
+
+// resourceParent returns the prefix of resource names that do not belong
+// to a model, such as cached contents: empty for the Gemini API and
+// "projects/{project}/locations/{location}/" on Vertex AI.
+func (g *GeminiGenerator) resourceParent() string {
+	_, models := g.apiRoot()
+	return strings.TrimSuffix(strings.TrimSuffix(models, "models"), "publishers/google/")
+}
+
+// modelName returns the resource name of model, unless it already is one.
+func (g *GeminiGenerator) modelName(model string) string {
+	if strings.Contains(model, "/") {
+		return model
+	}
+	_, models := g.apiRoot()
+	return models + "/" + model
+}
+
+// doJSON sends a request with in, if not nil, as its JSON body, and decodes
+// the response into out, if not nil.
+func (g *GeminiGenerator) doJSON(ctx context.Context, method, endpoint string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("generators: %s marshal request: %w", g.name(), err)
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return fmt.Errorf("generators: %s create request: %w", g.name(), err)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if err := g.authorize(req); err != nil {
+		return err
+	}
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("generators: %s request failed: %w", g.name(), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return &APIError{Provider: g.name(), StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("generators: %s decode response: %w", g.name(), err)
+		}
+	}
+	return nil
 }
 
 // Generate produces a text completion for the given prompt using the Gemini REST API.
-func (g *GeminiGenerator) Generate(ctx context.Context, prompt string, opts ...Option) (*Response, error) {
+func (g *GeminiGenerator) Generate(ctx context.Context, prompt string, opts ...Option) (out *Response, err error) {
 	cfg := newConfig(opts)
+	model := g.resolveModel(cfg)
+	trace := startTrace(ctx, cfg, RequestInfo{Provider: g.name(), Model: model, Operation: OperationGenerate})
+	ctx = trace.ctx
+	defer func() { trace.end(out, err) }()
+	ctx, wd := startWatchdog(ctx, cfg, g.name())
+	defer wd.stop()
+
+	if err := cfg.checkStrict(g.capabilitiesFor(model), model); err != nil {
+		return nil, err
+	}
 	reqBody := g.buildRequestBody(cfg, prompt)
 
 	body, err := json.Marshal(reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("generators: gemini marshal request: %w", err)
+		return nil, fmt.Errorf("generators: %s marshal request: %w", g.name(), err)
 	}
 
-	model := g.resolveModel(cfg)
 	endpoint := fmt.Sprintf("%s/%s:generateContent", g.baseURL, model)
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
 	if err != nil {
-		return nil, fmt.Errorf("generators: gemini create request: %w", err)
+		return nil, fmt.Errorf("generators: %s create request: %w", g.name(), err)
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-goog-api-key", g.apiKey)
+	if err := g.authorize(req); err != nil {
+		return nil, timeoutCause(ctx, err)
+	}
 
 	resp, err := g.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("generators: gemini request failed: %w", err)
+		return nil, timeoutCause(ctx, fmt.Errorf("generators: %s request failed: %w", g.name(), err))
 	}
 	defer resp.Body.Close()
+	wd.first()
 
 	if resp.StatusCode != http.StatusOK {
 		respBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("generators: gemini API error (status %d): %s", resp.StatusCode, string(respBody))
+		return nil, &APIError{Provider: g.name(), StatusCode: resp.StatusCode, Body: string(respBody)}
 	}
 
 	var gemResp geminiResponse
-	if err := json.NewDecoder(resp.Body).Decode(&gemResp); err != nil {
-		return nil, fmt.Errorf("generators: gemini decode response: %w", err)
+	if err := json.NewDecoder(wd.reader(resp.Body)).Decode(&gemResp); err != nil {
+		return nil, timeoutCause(ctx, fmt.Errorf("generators: %s decode response: %w", g.name(), err))
+	}
+	if err := gemResp.PromptFeedback.blocked(g.name()); err != nil {
+		return nil, err
 	}
 
 	return g.parseResponse(&gemResp, model), nil
@@ -181,34 +415,49 @@ func (g *GeminiGenerator) Generate(ctx context.Context, prompt string, opts ...O
 
 // Stream produces a streaming text completion for the given prompt using the Gemini REST API.
 // Returns a read-only channel that yields response chunks as they arrive via SSE.
-func (g *GeminiGenerator) Stream(ctx context.Context, prompt string, opts ...Option) (<-chan StreamChunk, error) {
+func (g *GeminiGenerator) Stream(ctx context.Context, prompt string, opts ...Option) (_ <-chan StreamChunk, err error) {
 	cfg := newConfig(opts)
+	model := g.resolveModel(cfg)
+	trace := startTrace(ctx, cfg, RequestInfo{Provider: g.name(), Model: model, Operation: OperationStream})
+	ctx = trace.ctx
+	ctx, wd := startWatchdog(ctx, cfg, g.name())
+	defer func() {
+		if err != nil {
+			wd.stop()
+			trace.end(nil, err)
+		}
+	}()
+
+	if err := cfg.checkStrict(g.capabilitiesFor(model), model); err != nil {
+		return nil, err
+	}
 	reqBody := g.buildRequestBody(cfg, prompt)
 
 	body, err := json.Marshal(reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("generators: gemini marshal request: %w", err)
+		return nil, fmt.Errorf("generators: %s marshal request: %w", g.name(), err)
 	}
 
-	model := g.resolveModel(cfg)
 	endpoint := fmt.Sprintf("%s/%s:streamGenerateContent?alt=sse", g.baseURL, model)
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
 	if err != nil {
-		return nil, fmt.Errorf("generators: gemini create request: %w", err)
+		return nil, fmt.Errorf("generators: %s create request: %w", g.name(), err)
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-goog-api-key", g.apiKey)
+	if err := g.authorize(req); err != nil {
+		return nil, timeoutCause(ctx, err)
+	}
 
 	resp, err := g.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("generators: gemini stream request failed: %w", err)
+		return nil, timeoutCause(ctx, fmt.Errorf("generators: %s stream request failed: %w", g.name(), err))
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		respBody, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
-		return nil, fmt.Errorf("generators: gemini API error (status %d): %s", resp.StatusCode, string(respBody))
+		return nil, &APIError{Provider: g.name(), StatusCode: resp.StatusCode, Body: string(respBody)}
 	}
 
 	ch := make(chan StreamChunk)
@@ -216,17 +465,132 @@ func (g *GeminiGenerator) Stream(ctx context.Context, prompt string, opts ...Opt
 	go func() {
 		defer close(ch)
 		defer resp.Body.Close()
-		g.consumeSSE(ctx, resp.Body, ch)
+		defer wd.stop()
+		g.consumeSSE(ctx, wd.reader(resp.Body), wd.sender(trace.sender(ch)))
+		trace.end(nil, nil)
 	}()
 
 	return ch, nil
 }
 
+// Embed computes embeddings with the batchEmbedContents endpoint, or the
+// predict endpoint on Vertex AI. The model is taken from WithModel, or from
+// the URL if it names an embedding model, and defaults to
+// gemini-embedding-001 (text-embedding-005 on Vertex AI).
+func (g *GeminiGenerator) Embed(ctx context.Context, texts []string, opts ...Option) (_ [][]float32, err error) {
+	cfg := newConfig(opts)
+	model := cfg.Model
+	if model == "" {
+		model = defaultGeminiEmbeddingModel
+		if g.tokens != nil {
+			model = defaultVertexEmbeddingModel
+		}
+		if strings.Contains(g.model, "embedding") {
+			model = g.model
+		}
+	}
+	trace := startTrace(ctx, cfg, RequestInfo{Provider: g.name(), Model: model, Operation: OperationEmbed})
+	ctx = trace.ctx
+	defer func() { trace.end(nil, err) }()
+
+	if g.tokens != nil {
+		return g.embedPredict(ctx, model, texts)
+	}
+
+	reqBody := geminiEmbedRequest{Requests: make([]geminiEmbedContentRequest, len(texts))}
+	for i, text := range texts {
+		reqBody.Requests[i] = geminiEmbedContentRequest{
+			Model:   "models/" + model,
+			Content: geminiContent{Parts: []geminiPart{{Text: text}}},
+		}
+	}
+	body, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("generators: %s marshal embed request: %w", g.name(), err)
+	}
+
+	endpoint := fmt.Sprintf("%s/%s:batchEmbedContents", g.baseURL, model)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("generators: %s create request: %w", g.name(), err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := g.authorize(req); err != nil {
+		return nil, err
+	}
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("generators: %s request failed: %w", g.name(), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{Provider: g.name(), StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+
+	var embResp geminiEmbedResponse
+	if err := json.NewDecoder(resp.Body).Decode(&embResp); err != nil {
+		return nil, fmt.Errorf("generators: %s decode embed response: %w", g.name(), err)
+	}
+	if len(embResp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("generators: %s returned %d embeddings for %d texts", g.name(), len(embResp.Embeddings), len(texts))
+	}
+	out := make([][]float32, len(texts))
+	for i, e := range embResp.Embeddings {
+		out[i] = e.Values
+	}
+	return out, nil
+}
+
+// Model returns the model used when a request does not set one.
+func (g *GeminiGenerator) Model() string {
+	return g.model
+}
+
 // Close releases the resources held by the Gemini generator.
 func (g *GeminiGenerator) Close() error {
 	return nil
 }
 
+// Capabilities returns the features available for the generator's model.
+func (g *GeminiGenerator) Capabilities() Capabilities {
+	return g.capabilitiesFor(g.model)
+}
+
+// capabilitiesFor returns the features available for the given Gemini model.
+func (g *GeminiGenerator) capabilitiesFor(model string) Capabilities {
+	return Capabilities{
+		Streaming:        true,
+		Embeddings:       true,
+		MaxContextTokens: geminiContextWindow(model),
+		SupportedOptions: []string{
+			OptionTemperature, OptionMaxOutputTokens, OptionTopP, OptionTopK,
+			OptionSystemInstruction, OptionStopSequences, OptionSeed,
+			OptionPresencePenalty, OptionFrequencyPenalty, OptionCandidateCount,
+			OptionResponseLogprobs, OptionThinkingBudget, OptionThinkingLevel,
+			OptionIncludeThoughts, OptionSafetySettings, OptionGoogleSearch,
+			OptionCachedContent, OptionFileData, OptionJSONOutput,
+		},
+	}
+}
+
+// geminiContextWindow returns the input token limit of well-known Gemini
+// model families, or zero if the model is not recognized.
+func geminiContextWindow(model string) int {
+	switch {
+	case strings.HasPrefix(model, "gemini-1.5-pro"):
+		return 2097152
+	case strings.HasPrefix(model, "gemini-1.5-flash"),
+		strings.HasPrefix(model, "gemini-2.0-flash"),
+		strings.HasPrefix(model, "gemini-2.5-"):
+		return 1048576
+	}
+	return 0
+}
+
 // resolveModel returns the model from the config if set, otherwise the default.
 func (g *GeminiGenerator) resolveModel(cfg *Config) string {
 	if cfg.Model != "" {
@@ -237,44 +601,94 @@ func (g *GeminiGenerator) resolveModel(cfg *Config) string {
 
 // buildRequestBody converts a Config and prompt into a Gemini API request.
 func (g *GeminiGenerator) buildRequestBody(cfg *Config, prompt string) geminiRequest {
+	var parts []geminiPart
+	for _, f := range cfg.Files {
+		parts = append(parts, geminiPart{FileData: &geminiFileData{MIMEType: f.MIMEType, FileURI: f.URI}})
+	}
 	req := geminiRequest{
 		Contents: []geminiContent{
-			{Parts: []geminiPart{{Text: prompt}}},
+			{Parts: append(parts, geminiPart{Text: prompt})},
 		},
 	}
 
 	genCfg := &geminiGenConfig{}
 	hasConfig := false
 
-	if cfg.Temperature != 0 {
-		genCfg.Temperature = ptrFloat32(cfg.Temperature)
+	if cfg.Temperature != nil {
+		genCfg.Temperature = cfg.Temperature
 		hasConfig = true
 	}
-	if cfg.MaxOutputTokens != 0 {
+	if cfg.MaxOutputTokens != nil {
 		genCfg.MaxOutputTokens = cfg.MaxOutputTokens
 		hasConfig = true
 	}
-	if cfg.TopP != 0 {
-		genCfg.TopP = ptrFloat32(cfg.TopP)
+	if cfg.TopP != nil {
+		genCfg.TopP = cfg.TopP
 		hasConfig = true
 	}
-	if cfg.TopK != 0 {
-		genCfg.TopK = ptrFloat32(cfg.TopK)
+	if cfg.TopK != nil {
+		genCfg.TopK = cfg.TopK
 		hasConfig = true
 	}
 	if len(cfg.StopSequences) > 0 {
 		genCfg.StopSequences = cfg.StopSequences
 		hasConfig = true
 	}
+	if cfg.Seed != nil {
+		genCfg.Seed = cfg.Seed
+		hasConfig = true
+	}
+	if cfg.PresencePenalty != nil {
+		genCfg.PresencePenalty = cfg.PresencePenalty
+		hasConfig = true
+	}
+	if cfg.FrequencyPenalty != nil {
+		genCfg.FrequencyPenalty = cfg.FrequencyPenalty
+		hasConfig = true
+	}
+	if cfg.CandidateCount != nil {
+		genCfg.CandidateCount = cfg.CandidateCount
+		hasConfig = true
+	}
+	if cfg.ResponseLogprobs {
+		genCfg.ResponseLogprobs = true
+		genCfg.Logprobs = cfg.TopLogprobs
+		hasConfig = true
+	}
+	if cfg.JSONOutput {
+		genCfg.ResponseMIMEType = "application/json"
+		hasConfig = true
+	}
+	if cfg.ThinkingBudget != nil || cfg.ThinkingLevel != "" || cfg.IncludeThoughts {
+		genCfg.ThinkingConfig = &geminiThinkingConfig{
+			ThinkingBudget:  cfg.ThinkingBudget,
+			ThinkingLevel:   cfg.ThinkingLevel,
+			IncludeThoughts: cfg.IncludeThoughts,
+		}
+		hasConfig = true
+	}
 	if hasConfig {
 		req.GenerationConfig = genCfg
 	}
 
+	for _, ss := range cfg.SafetySettings {
+		req.SafetySettings = append(req.SafetySettings, geminiSafetySetting{
+			Category:  ss.Category,
+			Threshold: ss.Threshold,
+		})
+	}
+	if cfg.GoogleSearch {
+		req.Tools = append(req.Tools, geminiTool{GoogleSearch: &struct{}{}})
+	}
+
 	if cfg.SystemInstruction != "" {
 		req.SystemInstruction = &geminiContent{
 			Parts: []geminiPart{{Text: cfg.SystemInstruction}},
 		}
 	}
+	if cfg.CachedContent != "" {
+		req.CachedContent = g.cachedContentName(cfg.CachedContent)
+	}
 
 	return req
 }
@@ -289,36 +703,159 @@ func (g *GeminiGenerator) parseResponse(resp *geminiResponse, model string) *Res
 		return out
 	}
 
-	if len(resp.Candidates) > 0 {
-		c := resp.Candidates[0]
-		var texts []string
+	for _, c := range resp.Candidates {
+		var texts, thoughts []string
 		for _, p := range c.Content.Parts {
-			if p.Text != "" {
+			switch {
+			case p.Text == "":
+			case p.Thought:
+				thoughts = append(thoughts, p.Text)
+			default:
 				texts = append(texts, p.Text)
 			}
 		}
-		out.Text = strings.Join(texts, "")
-		out.FinishReason = c.FinishReason
+		out.Candidates = append(out.Candidates, Candidate{
+			Text:          strings.Join(texts, ""),
+			Thoughts:      strings.Join(thoughts, ""),
+			FinishReason:  c.FinishReason,
+			Logprobs:      c.LogprobsResult.tokenLogprobs(),
+			SafetyRatings: mapGeminiSafetyRatings(c.SafetyRatings),
+			Grounding:     c.GroundingMetadata.grounding(),
+			Citations:     c.CitationMetadata.citations(),
+		})
+	}
+	if len(out.Candidates) > 0 {
+		out.Text = out.Candidates[0].Text
+		out.Thoughts = out.Candidates[0].Thoughts
+		out.FinishReason = out.Candidates[0].FinishReason
 	}
 
 	if resp.UsageMetadata != nil {
-		out.Usage = Usage{
-			PromptTokens:     resp.UsageMetadata.PromptTokenCount,
-			CompletionTokens: resp.UsageMetadata.CandidatesTokenCount,
-			TotalTokens:      resp.UsageMetadata.TotalTokenCount,
+		out.Usage = resp.UsageMetadata.usage()
+	}
+
+	return out
+}
+
+// usage converts Gemini usage metadata into a generators.Usage.
+func (m *geminiUsageMetadata) usage() Usage {
+	return Usage{
+		PromptTokens:     m.PromptTokenCount,
+		CompletionTokens: m.CandidatesTokenCount,
+		ThoughtTokens:    m.ThoughtsTokenCount,
+		CachedTokens:     m.CachedContentTokenCount,
+		TotalTokens:      m.TotalTokenCount,
+	}
+}
+
+// blocked returns a BlockedError for provider if the prompt feedback
+// reports that the prompt was blocked, or nil otherwise.
+func (f *geminiPromptFeedback) blocked(provider string) error {
+	if f == nil || f.BlockReason == "" {
+		return nil
+	}
+	return &BlockedError{
+		Provider:      provider,
+		Reason:        f.BlockReason,
+		Message:       f.BlockReasonMessage,
+		SafetyRatings: mapGeminiSafetyRatings(f.SafetyRatings),
+	}
+}
+
+// mapGeminiSafetyRatings converts Gemini safety ratings into SafetyRatings.
+func mapGeminiSafetyRatings(ratings []geminiSafetyRating) []SafetyRating {
+	if len(ratings) == 0 {
+		return nil
+	}
+	out := make([]SafetyRating, len(ratings))
+	for i, r := range ratings {
+		out[i] = SafetyRating{Category: r.Category, Probability: r.Probability, Blocked: r.Blocked}
+	}
+	return out
+}
+
+// citations converts Gemini citation metadata into Citations.
+func (m *geminiCitationMetadata) citations() []Citation {
+	if m == nil || len(m.CitationSources) == 0 {
+		return nil
+	}
+	out := make([]Citation, len(m.CitationSources))
+	for i, c := range m.CitationSources {
+		out[i] = Citation{StartIndex: c.StartIndex, EndIndex: c.EndIndex, URI: c.URI, Title: c.Title, License: c.License}
+	}
+	return out
+}
+
+// grounding converts Gemini grounding metadata into a Grounding.
+func (m *geminiGroundingMetadata) grounding() *Grounding {
+	if m == nil {
+		return nil
+	}
+	out := &Grounding{SearchQueries: m.WebSearchQueries}
+	for _, c := range m.GroundingChunks {
+		var src GroundingSource
+		if c.Web != nil {
+			src = GroundingSource{URI: c.Web.URI, Title: c.Web.Title}
 		}
+		out.Sources = append(out.Sources, src)
+	}
+	for _, s := range m.GroundingSupports {
+		out.Supports = append(out.Supports, GroundingSupport{
+			StartIndex:    s.Segment.StartIndex,
+			EndIndex:      s.Segment.EndIndex,
+			Text:          s.Segment.Text,
+			SourceIndices: s.GroundingChunkIndices,
+			Confidence:    s.ConfidenceScores,
+		})
 	}
+	return out
+}
 
+// tokenLogprobs converts Gemini logprobs into per-token entries, pairing each
+// chosen token with the top alternatives reported at the same position.
+func (r *geminiLogprobsResult) tokenLogprobs() []TokenLogprob {
+	if r == nil || len(r.ChosenCandidates) == 0 {
+		return nil
+	}
+	out := make([]TokenLogprob, len(r.ChosenCandidates))
+	for i, c := range r.ChosenCandidates {
+		out[i] = TokenLogprob{Token: c.Token, Logprob: c.LogProbability}
+		if i < len(r.TopCandidates) {
+			for _, alt := range r.TopCandidates[i].Candidates {
+				out[i].TopLogprobs = append(out[i].TopLogprobs, TokenLogprob{
+					Token:   alt.Token,
+					Logprob: alt.LogProbability,
+				})
+			}
+		}
+	}
 	return out
 }
 
+// geminiBlockingFinishReasons are the candidate finish reasons that mean
+// the response was stopped by a safety or policy filter.
+var geminiBlockingFinishReasons = map[string]bool{
+	"SAFETY":             true,
+	"RECITATION":         true,
+	"BLOCKLIST":          true,
+	"PROHIBITED_CONTENT": true,
+	"SPII":               true,
+	"IMAGE_SAFETY":       true,
+}
+
 // consumeSSE reads Server-Sent Events from the response body and sends
-// parsed chunks on the channel.
-func (g *GeminiGenerator) consumeSSE(ctx context.Context, body io.Reader, ch chan<- StreamChunk) {
+// parsed chunks on the channel. Gemini reports cumulative usage on each
+// event, so the last reported usage is sent in a trailing chunk along with
+// the finish reason, safety ratings, grounding and citations of the first
+// candidate. A candidate stopped by a safety or policy filter ends the
+// stream with a BlockedError.
+func (g *GeminiGenerator) consumeSSE(ctx context.Context, body io.Reader, send func(StreamChunk)) {
+	var usage *Usage
+	var trailer StreamChunk
 	scanner := bufio.NewScanner(body)
 	for scanner.Scan() {
 		if ctx.Err() != nil {
-			ch <- StreamChunk{Error: ctx.Err()}
+			send(StreamChunk{Error: timeoutCause(ctx, ctx.Err())})
 			return
 		}
 
@@ -331,30 +868,62 @@ func (g *GeminiGenerator) consumeSSE(ctx context.Context, body io.Reader, ch cha
 
 		data := strings.TrimPrefix(line, "data: ")
 		if data == "[DONE]" {
-			return
+			break
 		}
 
 		var gemResp geminiResponse
 		if err := json.Unmarshal([]byte(data), &gemResp); err != nil {
-			ch <- StreamChunk{Error: fmt.Errorf("generators: gemini SSE unmarshal: %w", err)}
+			send(StreamChunk{Error: fmt.Errorf("generators: %s SSE unmarshal: %w", g.name(), err)})
+			return
+		}
+		if err := gemResp.PromptFeedback.blocked(g.name()); err != nil {
+			send(StreamChunk{Error: err})
 			return
 		}
 
 		if len(gemResp.Candidates) > 0 {
-			for _, p := range gemResp.Candidates[0].Content.Parts {
-				if p.Text != "" {
-					ch <- StreamChunk{Text: p.Text}
+			c := &gemResp.Candidates[0]
+			for _, p := range c.Content.Parts {
+				switch {
+				case p.Text == "":
+				case p.Thought:
+					send(StreamChunk{Thought: p.Text})
+				default:
+					send(StreamChunk{Text: p.Text})
 				}
 			}
+			if ratings := mapGeminiSafetyRatings(c.SafetyRatings); ratings != nil {
+				trailer.SafetyRatings = ratings
+			}
+			if grounding := c.GroundingMetadata.grounding(); grounding != nil {
+				trailer.Grounding = grounding
+			}
+			trailer.Citations = append(trailer.Citations, c.CitationMetadata.citations()...)
+			if c.FinishReason != "" {
+				trailer.FinishReason = c.FinishReason
+			}
+			if geminiBlockingFinishReasons[c.FinishReason] {
+				send(StreamChunk{Error: &BlockedError{
+					Provider:      g.name(),
+					Reason:        c.FinishReason,
+					Message:       "response stopped midway",
+					SafetyRatings: trailer.SafetyRatings,
+				}})
+				return
+			}
+		}
+		if gemResp.UsageMetadata != nil {
+			u := gemResp.UsageMetadata.usage()
+			usage = &u
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
-		ch <- StreamChunk{Error: fmt.Errorf("generators: gemini SSE read: %w", err)}
+		send(StreamChunk{Error: timeoutCause(ctx, fmt.Errorf("generators: %s SSE read: %w", g.name(), err))})
+		return
+	}
+	trailer.Usage = usage
+	if usage != nil || trailer.FinishReason != "" || trailer.SafetyRatings != nil || trailer.Grounding != nil || trailer.Citations != nil {
+		send(trailer)
 	}
-}
-
-// ptrFloat32 returns a pointer to the given float32 value.
-func ptrFloat32(v float32) *float32 {
-	return &v
 }
@@ -0,0 +1,348 @@
+package generators
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// geminiBatchInlineLimit is the largest size of the requests sent inline
+// when submitting a batch job; larger batches are uploaded as a JSONL file
+// with the Files API.
+const geminiBatchInlineLimit = 20 << 20
+
+// errVertexBatch is returned by the batch job methods on Vertex AI, whose
+// batch prediction jobs read from and write to Cloud Storage.
+var errVertexBatch = errors.New("generators: Gemini batch jobs are not available on Vertex AI")
+
+type geminiBatch struct {
+	Name        string             `json:"name,omitempty"`
+	DisplayName string             `json:"displayName,omitempty"`
+	Model       string             `json:"model,omitempty"`
+	InputConfig *geminiBatchInput  `json:"inputConfig,omitempty"`
+	Output      *geminiBatchOutput `json:"output,omitempty"`
+	State       string             `json:"state,omitempty"`
+	BatchStats  *geminiBatchStats  `json:"batchStats,omitempty"`
+	CreateTime  time.Time          `json:"createTime,omitzero"`
+	UpdateTime  time.Time          `json:"updateTime,omitzero"`
+	EndTime     time.Time          `json:"endTime,omitzero"`
+}
+
+type geminiBatchInput struct {
+	FileName string `json:"fileName,omitempty"`
+	Requests *struct {
+		Requests []geminiBatchRequest `json:"requests"`
+	} `json:"requests,omitempty"`
+}
+
+type geminiBatchRequest struct {
+	Request  geminiRequest        `json:"request"`
+	Metadata *geminiBatchMetadata `json:"metadata,omitempty"`
+	Key      string               `json:"key,omitempty"`
+}
+
+type geminiBatchMetadata struct {
+	Key string `json:"key"`
+}
+
+type geminiBatchStats struct {
+	RequestCount           int `json:"requestCount,omitempty,string"`
+	SuccessfulRequestCount int `json:"successfulRequestCount,omitempty,string"`
+	FailedRequestCount     int `json:"failedRequestCount,omitempty,string"`
+}
+
+type geminiBatchOutput struct {
+	ResponsesFile    string `json:"responsesFile,omitempty"`
+	InlinedResponses *struct {
+		InlinedResponses []geminiBatchResponse `json:"inlinedResponses"`
+	} `json:"inlinedResponses,omitempty"`
+}
+
+// geminiBatchResponse is the result of one request, inlined in the job or
+// as a line of its responses file, where the key is not nested in metadata.
+type geminiBatchResponse struct {
+	Response *geminiResponse      `json:"response,omitempty"`
+	Error    *geminiStatus        `json:"error,omitempty"`
+	Metadata *geminiBatchMetadata `json:"metadata,omitempty"`
+	Key      string               `json:"key,omitempty"`
+}
+
+// geminiStatus is a google.rpc.Status error.
+type geminiStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// geminiOperation is the long-running operation returned for batch jobs.
+type geminiOperation struct {
+	Name     string             `json:"name"`
+	Metadata geminiBatch        `json:"metadata"`
+	Done     bool               `json:"done"`
+	Response *geminiBatchOutput `json:"response,omitempty"`
+	Error    *geminiStatus      `json:"error,omitempty"`
+}
+
+// grpcHTTPStatus maps the google.rpc codes of per-request batch errors to
+// the HTTP status the synchronous API would have answered with, so that
+// they are reported as APIError.
+var grpcHTTPStatus = map[int]int{
+	1:  499, // CANCELLED
+	3:  http.StatusBadRequest,
+	4:  http.StatusGatewayTimeout,
+	5:  http.StatusNotFound,
+	7:  http.StatusForbidden,
+	8:  http.StatusTooManyRequests,
+	9:  http.StatusBadRequest,
+	13: http.StatusInternalServerError,
+	14: http.StatusServiceUnavailable,
+	16: http.StatusUnauthorized,
+}
+
+// err converts s to an APIError.
+func (s *geminiStatus) err(provider string) error {
+	status, ok := grpcHTTPStatus[s.Code]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	return &APIError{Provider: provider, StatusCode: status, Body: s.Message}
+}
+
+// batchJob converts the operation to a BatchJob.
+func (op *geminiOperation) batchJob() *BatchJob {
+	b := &op.Metadata
+	job := &BatchJob{
+		Name:        op.Name,
+		DisplayName: b.DisplayName,
+		Model:       strings.TrimPrefix(b.Model, "models/"),
+		State:       strings.TrimPrefix(strings.TrimPrefix(b.State, "BATCH_STATE_"), "JOB_STATE_"),
+		CreateTime:  b.CreateTime,
+		UpdateTime:  b.UpdateTime,
+		EndTime:     b.EndTime,
+	}
+	if job.Name == "" {
+		job.Name = b.Name
+	}
+	if s := b.BatchStats; s != nil {
+		job.Requests, job.Succeeded, job.Failed = s.RequestCount, s.SuccessfulRequestCount, s.FailedRequestCount
+	}
+	if op.Error != nil {
+		job.Error = op.Error.Message
+		if !job.Done() {
+			job.State = BatchJobFailed
+		}
+	}
+	return job
+}
+
+// batchName returns the resource name of a batch job given its name or ID.
+func batchName(name string) string {
+	if strings.HasPrefix(name, "batches/") {
+		return name
+	}
+	return "batches/" + name
+}
+
+// SubmitBatchJob submits a Gemini batch job running every prompt with the
+// given options. Batches larger than 20 MiB are uploaded with the Files
+// API first.
+func (g *GeminiGenerator) SubmitBatchJob(ctx context.Context, prompts []string, opts ...Option) (*BatchJob, error) {
+	if g.tokens != nil {
+		return nil, errVertexBatch
+	}
+	if len(prompts) == 0 {
+		return nil, errors.New("generators: batch job needs at least one prompt")
+	}
+	cfg := newConfig(opts)
+	model := g.resolveModel(cfg)
+	if err := cfg.checkStrict(g.capabilitiesFor(model), model); err != nil {
+		return nil, err
+	}
+
+	requests := make([]geminiBatchRequest, len(prompts))
+	size := 0
+	for i, prompt := range prompts {
+		requests[i] = geminiBatchRequest{
+			Request:  g.buildRequestBody(cfg, prompt),
+			Metadata: &geminiBatchMetadata{Key: strconv.Itoa(i)},
+		}
+		data, err := json.Marshal(requests[i])
+		if err != nil {
+			return nil, fmt.Errorf("generators: %s marshal request: %w", g.name(), err)
+		}
+		size += len(data)
+	}
+
+	input := &geminiBatchInput{}
+	if size <= geminiBatchInlineLimit {
+		input.Requests = &struct {
+			Requests []geminiBatchRequest `json:"requests"`
+		}{requests}
+	} else {
+		file, err := g.uploadBatchInput(ctx, requests)
+		if err != nil {
+			return nil, err
+		}
+		input.FileName = file.Name
+	}
+
+	body := struct {
+		Batch geminiBatch `json:"batch"`
+	}{geminiBatch{DisplayName: "batch-" + strconv.FormatInt(time.Now().Unix(), 10), InputConfig: input}}
+	var op geminiOperation
+	endpoint := fmt.Sprintf("%s/%s:batchGenerateContent", g.baseURL, model)
+	if err := g.doJSON(ctx, http.MethodPost, endpoint, body, &op); err != nil {
+		return nil, err
+	}
+	return op.batchJob(), nil
+}
+
+// uploadBatchInput uploads requests as a JSONL file, one request per line.
+func (g *GeminiGenerator) uploadBatchInput(ctx context.Context, requests []geminiBatchRequest) (*File, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, r := range requests {
+		r.Key, r.Metadata = r.Metadata.Key, nil
+		if err := enc.Encode(r); err != nil {
+			return nil, fmt.Errorf("generators: %s marshal request: %w", g.name(), err)
+		}
+	}
+	return g.UploadFile(ctx, &buf, FileUploadRequest{
+		DisplayName: "batch-input",
+		MIMEType:    "application/jsonl",
+		Size:        int64(buf.Len()),
+	})
+}
+
+// GetBatchJob returns the status of a Gemini batch job given its name or
+// ID.
+func (g *GeminiGenerator) GetBatchJob(ctx context.Context, name string) (*BatchJob, error) {
+	op, err := g.getBatch(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return op.batchJob(), nil
+}
+
+// getBatch fetches the operation of a batch job.
+func (g *GeminiGenerator) getBatch(ctx context.Context, name string) (*geminiOperation, error) {
+	if g.tokens != nil {
+		return nil, errVertexBatch
+	}
+	root, _ := g.apiRoot()
+	var op geminiOperation
+	if err := g.doJSON(ctx, http.MethodGet, root+"/"+batchName(name), nil, &op); err != nil {
+		return nil, err
+	}
+	return &op, nil
+}
+
+// BatchJobResults returns the results of a succeeded Gemini batch job,
+// reading them from the job or, for large jobs, from its responses file.
+func (g *GeminiGenerator) BatchJobResults(ctx context.Context, name string) ([]BatchResult, error) {
+	op, err := g.getBatch(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	job := op.batchJob()
+	if job.State != BatchJobSucceeded {
+		msg := fmt.Sprintf("generators: %s batch job %s is %s", g.name(), job.Name, strings.ToLower(job.State))
+		if job.Error != "" {
+			msg += ": " + job.Error
+		}
+		return nil, errors.New(msg)
+	}
+
+	output := op.Response
+	if output == nil {
+		output = op.Metadata.Output
+	}
+	var responses []geminiBatchResponse
+	switch {
+	case output == nil:
+		return nil, fmt.Errorf("generators: %s batch job %s has no output", g.name(), job.Name)
+	case output.InlinedResponses != nil:
+		responses = output.InlinedResponses.InlinedResponses
+	case output.ResponsesFile != "":
+		if responses, err = g.downloadBatchOutput(ctx, output.ResponsesFile); err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]BatchResult, max(job.Requests, len(responses)))
+	for i := range results {
+		results[i] = BatchResult{Index: i, Err: ErrBatchNoResult}
+	}
+	for i, r := range responses {
+		key := r.Key
+		if r.Metadata != nil {
+			key = r.Metadata.Key
+		}
+		if n, err := strconv.Atoi(key); err == nil && n >= 0 && n < len(results) {
+			i = n
+		}
+		switch {
+		case r.Error != nil:
+			results[i].Err = r.Error.err(g.name())
+		case r.Response == nil:
+			results[i].Err = fmt.Errorf("generators: %s batch response %s is empty", g.name(), key)
+		default:
+			if err := r.Response.PromptFeedback.blocked(g.name()); err != nil {
+				results[i].Err = err
+				continue
+			}
+			results[i].Response = g.parseResponse(r.Response, job.Model)
+			results[i].Err = nil
+		}
+	}
+	return results, nil
+}
+
+// downloadBatchOutput reads the results of a batch job from its JSONL
+// responses file.
+func (g *GeminiGenerator) downloadBatchOutput(ctx context.Context, file string) ([]geminiBatchResponse, error) {
+	root, _ := g.apiRoot()
+	u, err := url.Parse(root)
+	if err != nil {
+		return nil, fmt.Errorf("generators: %s invalid base URL: %w", g.name(), err)
+	}
+	u.Path = "/download" + u.Path + "/" + fileName(file) + ":download"
+	u.RawQuery = "alt=media"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("generators: %s create request: %w", g.name(), err)
+	}
+	resp, err := g.doRaw(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var responses []geminiBatchResponse
+	dec := json.NewDecoder(resp.Body)
+	for dec.More() {
+		var r geminiBatchResponse
+		if err := dec.Decode(&r); err != nil {
+			return nil, fmt.Errorf("generators: %s decode batch output: %w", g.name(), err)
+		}
+		responses = append(responses, r)
+	}
+	return responses, nil
+}
+
+// CancelBatchJob cancels a pending or running Gemini batch job given its
+// name or ID.
+func (g *GeminiGenerator) CancelBatchJob(ctx context.Context, name string) error {
+	if g.tokens != nil {
+		return errVertexBatch
+	}
+	root, _ := g.apiRoot()
+	return g.doJSON(ctx, http.MethodPost, root+"/"+batchName(name)+":cancel", nil, nil)
+}
@@ -0,0 +1,132 @@
+package generators
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGeminiSubmitBatchJob(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1beta/models/gemini-2.5-flash:batchGenerateContent" {
+			t.Errorf("request = %s %s", r.Method, r.URL.Path)
+		}
+		var body struct {
+			Batch geminiBatch `json:"batch"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		reqs := body.Batch.InputConfig.Requests.Requests
+		if body.Batch.DisplayName == "" || len(reqs) != 2 || reqs[1].Metadata.Key != "1" ||
+			reqs[1].Request.Contents[0].Parts[0].Text != "second" || *reqs[0].Request.GenerationConfig.Temperature != 0 {
+			t.Errorf("batch = %+v", body.Batch)
+		}
+		fmt.Fprint(w, `{"name":"batches/123","metadata":{"model":"models/gemini-2.5-flash","state":"BATCH_STATE_PENDING",`+
+			`"batchStats":{"requestCount":"2","pendingRequestCount":"2"}}}`)
+	}))
+	defer server.Close()
+
+	gen := &GeminiGenerator{httpClient: server.Client(), model: "gemini-2.0-flash", baseURL: server.URL + "/v1beta/models"}
+	var jobs BatchJobs = gen
+	job, err := jobs.SubmitBatchJob(context.Background(), []string{"first", "second"}, WithModel("gemini-2.5-flash"), WithTemperature(0))
+	if err != nil {
+		t.Fatalf("SubmitBatchJob() error = %v", err)
+	}
+	if job.Name != "batches/123" || job.State != BatchJobPending || job.Model != "gemini-2.5-flash" || job.Requests != 2 || job.Done() {
+		t.Errorf("job = %+v", job)
+	}
+	if _, err := gen.SubmitBatchJob(context.Background(), nil); err == nil {
+		t.Error("empty batch accepted")
+	}
+	vertex := &GeminiGenerator{tokens: &googleTokenSource{}}
+	if _, err := vertex.SubmitBatchJob(context.Background(), []string{"x"}); err != errVertexBatch {
+		t.Errorf("Vertex SubmitBatchJob() error = %v", err)
+	}
+}
+
+func TestGeminiBatchJobResults(t *testing.T) {
+	gets := 0
+	var cancelled bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1beta/batches/inline":
+			gets++
+			if gets < 3 {
+				fmt.Fprint(w, `{"name":"batches/inline","metadata":{"state":"BATCH_STATE_RUNNING"}}`)
+				return
+			}
+			fmt.Fprint(w, `{"name":"batches/inline","done":true,`+
+				`"metadata":{"model":"models/gemini-2.0-flash","state":"BATCH_STATE_SUCCEEDED","batchStats":{"requestCount":"4","successfulRequestCount":"2","failedRequestCount":"1"}},`+
+				`"response":{"inlinedResponses":{"inlinedResponses":[`+
+				`{"metadata":{"key":"1"},"response":{"candidates":[{"content":{"parts":[{"text":"two"}]}}],"usageMetadata":{"totalTokenCount":7}}},`+
+				`{"metadata":{"key":"0"},"response":{"candidates":[{"content":{"parts":[{"text":"one"}]}}]}},`+
+				`{"metadata":{"key":"2"},"error":{"code":8,"message":"quota"}}]}}}`)
+		case "/v1beta/batches/file":
+			fmt.Fprint(w, `{"name":"batches/file","metadata":{"state":"BATCH_STATE_SUCCEEDED","output":{"responsesFile":"files/out"}}}`)
+		case "/download/v1beta/files/out:download":
+			if r.URL.Query().Get("alt") != "media" {
+				t.Errorf("download query = %q", r.URL.RawQuery)
+			}
+			fmt.Fprintln(w, `{"key":"1","response":{"promptFeedback":{"blockReason":"SAFETY"}}}`)
+			fmt.Fprintln(w, `{"key":"0","response":{"candidates":[{"content":{"parts":[{"text":"ok"}]}}]}}`)
+		case "/v1beta/batches/failed":
+			fmt.Fprint(w, `{"name":"batches/failed","done":true,"metadata":{"state":"BATCH_STATE_RUNNING"},"error":{"code":3,"message":"bad input"}}`)
+		case "/v1beta/batches/inline:cancel":
+			cancelled = r.Method == http.MethodPost
+			fmt.Fprint(w, `{}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	gen := &GeminiGenerator{httpClient: server.Client(), model: "gemini-2.0-flash", baseURL: server.URL + "/v1beta/models"}
+	ctx := context.Background()
+
+	job, err := WaitBatchJob(ctx, gen, "inline", time.Millisecond)
+	if err != nil || job.State != BatchJobSucceeded || gets != 3 || job.Succeeded != 2 {
+		t.Fatalf("WaitBatchJob() = %+v, %v after %d polls", job, err, gets)
+	}
+	results, err := gen.BatchJobResults(ctx, "batches/inline")
+	if err != nil {
+		t.Fatalf("BatchJobResults() error = %v", err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("got %d results, want 4", len(results))
+	}
+	if results[0].Response.Text != "one" || results[1].Response.Text != "two" || results[1].Response.Usage.TotalTokens != 7 ||
+		results[1].Index != 1 || results[1].Response.Model != "gemini-2.0-flash" {
+		t.Errorf("results = %+v", results)
+	}
+	var apiErr *APIError
+	if !errors.As(results[2].Err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests || results[2].Response != nil {
+		t.Errorf("results[2].Err = %v", results[2].Err)
+	}
+	if !errors.Is(results[3].Err, ErrBatchNoResult) || results[3].Response != nil {
+		t.Errorf("results[3].Err = %v", results[3].Err)
+	}
+
+	results, err = gen.BatchJobResults(ctx, "file")
+	if err != nil || len(results) != 2 || results[0].Response.Text != "ok" {
+		t.Fatalf("BatchJobResults(file) = %+v, %v", results, err)
+	}
+	var blocked *BlockedError
+	if !errors.As(results[1].Err, &blocked) {
+		t.Errorf("results[1].Err = %v, want BlockedError", results[1].Err)
+	}
+
+	job, err = gen.GetBatchJob(ctx, "failed")
+	if err != nil || job.State != BatchJobFailed || job.Error != "bad input" {
+		t.Errorf("GetBatchJob(failed) = %+v, %v", job, err)
+	}
+	if _, err := gen.BatchJobResults(ctx, "failed"); err == nil {
+		t.Error("results of a failed job returned")
+	}
+	if err := gen.CancelBatchJob(ctx, "inline"); err != nil || !cancelled {
+		t.Errorf("CancelBatchJob() = %v, cancelled %v", err, cancelled)
+	}
+}
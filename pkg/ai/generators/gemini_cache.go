@@ -0,0 +1,179 @@
+package generators
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CachedContent describes content cached by Gemini for reuse across
+// requests. Name is the resource name to pass to WithCachedContent, such
+// as "cachedContents/abc123".
+type CachedContent struct {
+	Name        string
+	DisplayName string
+	Model       string
+	TotalTokens int
+	CreateTime  time.Time
+	UpdateTime  time.Time
+	ExpireTime  time.Time
+}
+
+// CachedContentRequest describes the content to cache. Model defaults to
+// the generator's model; the cache can only be used with the model it was
+// created for. TTL defaults to one hour on the server.
+type CachedContentRequest struct {
+	Model             string
+	DisplayName       string
+	SystemInstruction string
+	Contents          []string
+	TTL               time.Duration
+}
+
+type geminiCachedContent struct {
+	Name              string          `json:"name,omitempty"`
+	DisplayName       string          `json:"displayName,omitempty"`
+	Model             string          `json:"model,omitempty"`
+	SystemInstruction *geminiContent  `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent `json:"contents,omitempty"`
+	TTL               string          `json:"ttl,omitempty"`
+	CreateTime        time.Time       `json:"createTime,omitzero"`
+	UpdateTime        time.Time       `json:"updateTime,omitzero"`
+	ExpireTime        time.Time       `json:"expireTime,omitzero"`
+	UsageMetadata     *struct {
+		TotalTokenCount int `json:"totalTokenCount"`
+	} `json:"usageMetadata,omitempty"`
+}
+
+// cachedContent converts the wire form to a CachedContent.
+func (c *geminiCachedContent) cachedContent() CachedContent {
+	out := CachedContent{
+		Name:        c.Name,
+		DisplayName: c.DisplayName,
+		Model:       c.Model,
+		CreateTime:  c.CreateTime,
+		UpdateTime:  c.UpdateTime,
+		ExpireTime:  c.ExpireTime,
+	}
+	if c.UsageMetadata != nil {
+		out.TotalTokens = c.UsageMetadata.TotalTokenCount
+	}
+	return out
+}
+
+// cachedContentName returns the resource name of a cached content given
+// its name or ID.
+func (g *GeminiGenerator) cachedContentName(name string) string {
+	if strings.Contains(name, "/") {
+		return name
+	}
+	return g.resourceParent() + "cachedContents/" + name
+}
+
+// formatTTL formats d as a protobuf duration, such as "3600s".
+func formatTTL(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "s"
+}
+
+// CreateCachedContent caches a system instruction and contents so that
+// later requests can reference them with WithCachedContent. Gemini
+// requires a minimum number of tokens to cache, which depends on the
+// model.
+func (g *GeminiGenerator) CreateCachedContent(ctx context.Context, req CachedContentRequest) (*CachedContent, error) {
+	if req.SystemInstruction == "" && len(req.Contents) == 0 {
+		return nil, errors.New("generators: cached content needs a system instruction or contents")
+	}
+	model := req.Model
+	if model == "" {
+		model = g.model
+	}
+	body := geminiCachedContent{
+		DisplayName: req.DisplayName,
+		Model:       g.modelName(model),
+	}
+	if req.SystemInstruction != "" {
+		body.SystemInstruction = &geminiContent{Parts: []geminiPart{{Text: req.SystemInstruction}}}
+	}
+	for _, text := range req.Contents {
+		body.Contents = append(body.Contents, geminiContent{Role: "user", Parts: []geminiPart{{Text: text}}})
+	}
+	if req.TTL > 0 {
+		body.TTL = formatTTL(req.TTL)
+	}
+
+	root, _ := g.apiRoot()
+	var out geminiCachedContent
+	if err := g.doJSON(ctx, http.MethodPost, root+"/"+g.resourceParent()+"cachedContents", body, &out); err != nil {
+		return nil, err
+	}
+	cc := out.cachedContent()
+	return &cc, nil
+}
+
+// GetCachedContent returns the metadata of a cached content given its
+// name or ID.
+func (g *GeminiGenerator) GetCachedContent(ctx context.Context, name string) (*CachedContent, error) {
+	root, _ := g.apiRoot()
+	var out geminiCachedContent
+	if err := g.doJSON(ctx, http.MethodGet, root+"/"+g.cachedContentName(name), nil, &out); err != nil {
+		return nil, err
+	}
+	cc := out.cachedContent()
+	return &cc, nil
+}
+
+// ListCachedContents returns the metadata of all the cached contents of
+// the project or API key.
+func (g *GeminiGenerator) ListCachedContents(ctx context.Context) ([]CachedContent, error) {
+	root, _ := g.apiRoot()
+	endpoint := root + "/" + g.resourceParent() + "cachedContents"
+	var all []CachedContent
+	pageToken := ""
+	for {
+		q := url.Values{"pageSize": {"100"}}
+		if pageToken != "" {
+			q.Set("pageToken", pageToken)
+		}
+		var page struct {
+			CachedContents []geminiCachedContent `json:"cachedContents"`
+			NextPageToken  string                `json:"nextPageToken"`
+		}
+		if err := g.doJSON(ctx, http.MethodGet, endpoint+"?"+q.Encode(), nil, &page); err != nil {
+			return nil, err
+		}
+		for _, c := range page.CachedContents {
+			all = append(all, c.cachedContent())
+		}
+		if page.NextPageToken == "" {
+			return all, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// RefreshCachedContent extends the life of a cached content so that it
+// expires ttl from now.
+func (g *GeminiGenerator) RefreshCachedContent(ctx context.Context, name string, ttl time.Duration) (*CachedContent, error) {
+	if ttl <= 0 {
+		return nil, fmt.Errorf("generators: invalid cache TTL %s", ttl)
+	}
+	root, _ := g.apiRoot()
+	endpoint := root + "/" + g.cachedContentName(name) + "?updateMask=ttl"
+	var out geminiCachedContent
+	if err := g.doJSON(ctx, http.MethodPatch, endpoint, geminiCachedContent{TTL: formatTTL(ttl)}, &out); err != nil {
+		return nil, err
+	}
+	cc := out.cachedContent()
+	return &cc, nil
+}
+
+// DeleteCachedContent deletes a cached content given its name or ID.
+func (g *GeminiGenerator) DeleteCachedContent(ctx context.Context, name string) error {
+	root, _ := g.apiRoot()
+	return g.doJSON(ctx, http.MethodDelete, root+"/"+g.cachedContentName(name), nil, nil)
+}
@@ -0,0 +1,133 @@
+package generators
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGeminiCachedContents(t *testing.T) {
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.RequestURI())
+		switch {
+		case r.Method == http.MethodPost:
+			var body geminiCachedContent
+			json.NewDecoder(r.Body).Decode(&body)
+			if body.Model != "models/gemini-2.0-flash-001" || body.TTL != "1800s" || body.SystemInstruction.Parts[0].Text != "policies" ||
+				len(body.Contents) != 1 || body.Contents[0].Role != "user" {
+				t.Errorf("create body = %+v", body)
+			}
+			fmt.Fprint(w, `{"name":"cachedContents/abc","model":"models/gemini-2.0-flash-001","displayName":"policy",`+
+				`"createTime":"2025-01-02T03:04:05.123456Z","expireTime":"2025-01-02T03:34:05Z","usageMetadata":{"totalTokenCount":4096}}`)
+		case r.Method == http.MethodGet && r.URL.Query().Get("pageToken") == "":
+			fmt.Fprint(w, `{"cachedContents":[{"name":"cachedContents/abc"}],"nextPageToken":"p2"}`)
+		case r.Method == http.MethodGet:
+			fmt.Fprint(w, `{"cachedContents":[{"name":"cachedContents/def"}]}`)
+		case r.Method == http.MethodPatch:
+			var body map[string]any
+			json.NewDecoder(r.Body).Decode(&body)
+			if len(body) != 1 || body["ttl"] != "7200s" {
+				t.Errorf("refresh body = %v", body)
+			}
+			fmt.Fprint(w, `{"name":"cachedContents/abc","expireTime":"2025-01-02T05:04:05Z"}`)
+		case r.Method == http.MethodDelete:
+			fmt.Fprint(w, `{}`)
+		}
+	}))
+	defer server.Close()
+
+	gen := &GeminiGenerator{httpClient: server.Client(), model: "gemini-2.0-flash-001", baseURL: server.URL + "/v1beta/models"}
+	ctx := context.Background()
+
+	cc, err := gen.CreateCachedContent(ctx, CachedContentRequest{
+		DisplayName:       "policy",
+		SystemInstruction: "policies",
+		Contents:          []string{"document"},
+		TTL:               30 * time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("CreateCachedContent() error = %v", err)
+	}
+	if cc.Name != "cachedContents/abc" || cc.TotalTokens != 4096 || cc.ExpireTime.Sub(cc.CreateTime) < 29*time.Minute {
+		t.Errorf("created = %+v", cc)
+	}
+
+	list, err := gen.ListCachedContents(ctx)
+	if err != nil || len(list) != 2 || list[1].Name != "cachedContents/def" {
+		t.Errorf("ListCachedContents() = %+v, %v", list, err)
+	}
+	if _, err := gen.RefreshCachedContent(ctx, "abc", 2*time.Hour); err != nil {
+		t.Errorf("RefreshCachedContent() error = %v", err)
+	}
+	if err := gen.DeleteCachedContent(ctx, "cachedContents/abc"); err != nil {
+		t.Errorf("DeleteCachedContent() error = %v", err)
+	}
+
+	want := []string{
+		"POST /v1beta/cachedContents",
+		"GET /v1beta/cachedContents?pageSize=100",
+		"GET /v1beta/cachedContents?pageSize=100&pageToken=p2",
+		"PATCH /v1beta/cachedContents/abc?updateMask=ttl",
+		"DELETE /v1beta/cachedContents/abc",
+	}
+	if fmt.Sprint(calls) != fmt.Sprint(want) {
+		t.Errorf("calls =\n%q\nwant\n%q", calls, want)
+	}
+
+	if _, err := gen.CreateCachedContent(ctx, CachedContentRequest{}); err == nil {
+		t.Error("empty cache accepted")
+	}
+	if _, err := gen.RefreshCachedContent(ctx, "abc", 0); err == nil {
+		t.Error("zero TTL accepted")
+	}
+}
+
+func TestGeminiCachedContentNames(t *testing.T) {
+	gem := &GeminiGenerator{baseURL: "https://generativelanguage.googleapis.com/v1beta/models"}
+	vertex := &GeminiGenerator{baseURL: "https://us-central1-aiplatform.googleapis.com/v1/projects/p/locations/us-central1/publishers/google/models"}
+
+	if root, models := vertex.apiRoot(); root != "https://us-central1-aiplatform.googleapis.com/v1" || models != "projects/p/locations/us-central1/publishers/google/models" {
+		t.Errorf("vertex apiRoot = %q, %q", root, models)
+	}
+	tests := []struct {
+		got, want string
+	}{
+		{gem.cachedContentName("abc"), "cachedContents/abc"},
+		{gem.modelName("gemini-2.0-flash"), "models/gemini-2.0-flash"},
+		{vertex.cachedContentName("abc"), "projects/p/locations/us-central1/cachedContents/abc"},
+		{vertex.cachedContentName("projects/x/locations/y/cachedContents/z"), "projects/x/locations/y/cachedContents/z"},
+		{vertex.modelName("gemini-2.0-flash"), "projects/p/locations/us-central1/publishers/google/models/gemini-2.0-flash"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("got %q, want %q", tt.got, tt.want)
+		}
+	}
+}
+
+func TestGeminiGenerate_CachedContent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req geminiRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.CachedContent != "cachedContents/abc" {
+			t.Errorf("cachedContent = %q", req.CachedContent)
+		}
+		fmt.Fprint(w, `{"candidates":[{"content":{"parts":[{"text":"ok"}]}}],`+
+			`"usageMetadata":{"promptTokenCount":5010,"cachedContentTokenCount":5000,"candidatesTokenCount":1,"totalTokenCount":5011}}`)
+	}))
+	defer server.Close()
+
+	gen := &GeminiGenerator{httpClient: server.Client(), model: "gemini-2.0-flash-001", baseURL: server.URL + "/v1beta/models"}
+	resp, err := gen.Generate(context.Background(), "question", WithCachedContent("abc"), WithStrict())
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if resp.Usage.CachedTokens != 5000 || resp.Usage.PromptTokens != 5010 {
+		t.Errorf("usage = %+v", resp.Usage)
+	}
+}
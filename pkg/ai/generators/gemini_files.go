@@ -0,0 +1,358 @@
+package generators
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// fileUploadChunkSize is the size of the chunks sent by UploadFile. The
+	// resumable upload protocol requires a multiple of 256 KiB.
+	fileUploadChunkSize = 8 << 20
+
+	// fileUploadRetries is the number of times a chunk is resumed after a
+	// network or server error before UploadFile gives up.
+	fileUploadRetries = 3
+
+	// defaultFilePollInterval is the interval used by WaitForFile when none
+	// is given.
+	defaultFilePollInterval = 2 * time.Second
+)
+
+// States of a file uploaded to the Gemini Files API.
+const (
+	FileStateProcessing = "PROCESSING"
+	FileStateActive     = "ACTIVE"
+	FileStateFailed     = "FAILED"
+)
+
+// errVertexFiles is returned by the Files API methods on Vertex AI, which
+// reads media from Cloud Storage instead.
+var errVertexFiles = errors.New("generators: the Files API is not available on Vertex AI, reference gs:// URIs with WithFileData instead")
+
+// File describes a file uploaded to the Gemini Files API. Name is the
+// resource name, such as "files/abc123", and URI the value to reference
+// the file with WithFileData once State is FileStateActive.
+type File struct {
+	Name        string
+	DisplayName string
+	MIMEType    string
+	SizeBytes   int64
+	URI         string
+	State       string
+	Error       string
+	CreateTime  time.Time
+	ExpireTime  time.Time
+}
+
+// FileData returns the reference to f to pass to WithFileData.
+func (f *File) FileData() FileData {
+	return FileData{URI: f.URI, MIMEType: f.MIMEType}
+}
+
+// FileUploadRequest describes a file to upload. Size is the total number
+// of bytes, or zero when it is not known in advance.
+type FileUploadRequest struct {
+	DisplayName string
+	MIMEType    string
+	Size        int64
+}
+
+type geminiFile struct {
+	Name        string    `json:"name,omitempty"`
+	DisplayName string    `json:"displayName,omitempty"`
+	MIMEType    string    `json:"mimeType,omitempty"`
+	SizeBytes   int64     `json:"sizeBytes,omitempty,string"`
+	URI         string    `json:"uri,omitempty"`
+	State       string    `json:"state,omitempty"`
+	CreateTime  time.Time `json:"createTime,omitzero"`
+	ExpireTime  time.Time `json:"expireTime,omitzero"`
+	Error       *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// file converts the wire form to a File.
+func (f *geminiFile) file() File {
+	out := File{
+		Name:        f.Name,
+		DisplayName: f.DisplayName,
+		MIMEType:    f.MIMEType,
+		SizeBytes:   f.SizeBytes,
+		URI:         f.URI,
+		State:       f.State,
+		CreateTime:  f.CreateTime,
+		ExpireTime:  f.ExpireTime,
+	}
+	if f.Error != nil {
+		out.Error = f.Error.Message
+	}
+	return out
+}
+
+// fileName returns the resource name of a file given its name or ID.
+func fileName(name string) string {
+	if strings.HasPrefix(name, "files/") {
+		return name
+	}
+	return "files/" + name
+}
+
+// UploadFile uploads the contents of r to the Gemini Files API with the
+// resumable upload protocol, sending it in chunks and resuming a chunk
+// from the last byte the server acknowledged after a network or server
+// error. Uploaded files are processed asynchronously; use WaitForFile
+// before referencing video or audio files in a request.
+func (g *GeminiGenerator) UploadFile(ctx context.Context, r io.Reader, req FileUploadRequest) (_ *File, err error) {
+	trace := startTrace(ctx, &Config{}, RequestInfo{Provider: g.name(), Operation: OperationUpload})
+	ctx = trace.ctx
+	defer func() { trace.end(nil, err) }()
+
+	if g.tokens != nil {
+		return nil, errVertexFiles
+	}
+	if req.MIMEType == "" {
+		return nil, errors.New("generators: file upload needs a MIME type")
+	}
+	session, err := g.startUpload(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, fileUploadChunkSize)
+	var offset int64
+	for {
+		n, err := io.ReadFull(r, buf)
+		final := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !final {
+			return nil, fmt.Errorf("generators: %s read file: %w", g.name(), err)
+		}
+		out, err := g.uploadChunk(ctx, trace, session, buf[:n], offset, final)
+		if err != nil {
+			return nil, err
+		}
+		offset += int64(n)
+		if final {
+			f := out.file()
+			return &f, nil
+		}
+	}
+}
+
+// startUpload starts a resumable upload session and returns its URL.
+func (g *GeminiGenerator) startUpload(ctx context.Context, req FileUploadRequest) (string, error) {
+	root, _ := g.apiRoot()
+	u, err := url.Parse(root)
+	if err != nil {
+		return "", fmt.Errorf("generators: %s invalid base URL: %w", g.name(), err)
+	}
+	u.Path = "/upload" + u.Path + "/files"
+
+	var meta struct {
+		File geminiFile `json:"file"`
+	}
+	meta.File.DisplayName = req.DisplayName
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return "", fmt.Errorf("generators: %s marshal request: %w", g.name(), err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("generators: %s create request: %w", g.name(), err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("X-Goog-Upload-Protocol", "resumable")
+	httpReq.Header.Set("X-Goog-Upload-Command", "start")
+	httpReq.Header.Set("X-Goog-Upload-Header-Content-Type", req.MIMEType)
+	if req.Size > 0 {
+		httpReq.Header.Set("X-Goog-Upload-Header-Content-Length", strconv.FormatInt(req.Size, 10))
+	}
+
+	resp, err := g.doRaw(httpReq)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	session := resp.Header.Get("X-Goog-Upload-URL")
+	if session == "" {
+		return "", fmt.Errorf("generators: %s upload session URL missing", g.name())
+	}
+	return session, nil
+}
+
+// uploadChunk sends chunk, which starts at offset in the file, to the
+// upload session. On a network or server error it asks the server how many
+// bytes it received and resends the rest of the chunk. The uploaded file is
+// returned when final is set. Each resumption is reported to trace as a
+// retry.
+func (g *GeminiGenerator) uploadChunk(ctx context.Context, trace *requestTrace, session string, chunk []byte, offset int64, final bool) (*geminiFile, error) {
+	command := "upload"
+	if final {
+		command = "upload, finalize"
+	}
+	sent := 0
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, session, bytes.NewReader(chunk[sent:]))
+		if err != nil {
+			return nil, fmt.Errorf("generators: %s create request: %w", g.name(), err)
+		}
+		req.Header.Set("X-Goog-Upload-Command", command)
+		req.Header.Set("X-Goog-Upload-Offset", strconv.FormatInt(offset+int64(sent), 10))
+
+		resp, err := g.doRaw(req)
+		if err == nil {
+			defer resp.Body.Close()
+			if !final {
+				return nil, nil
+			}
+			var out struct {
+				File geminiFile `json:"file"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+				return nil, fmt.Errorf("generators: %s decode response: %w", g.name(), err)
+			}
+			return &out.File, nil
+		}
+
+		var apiErr *APIError
+		if ctx.Err() != nil || attempt == fileUploadRetries ||
+			errors.As(err, &apiErr) && apiErr.StatusCode < http.StatusInternalServerError {
+			return nil, err
+		}
+		received, qerr := g.queryUpload(ctx, session)
+		if qerr != nil {
+			return nil, err
+		}
+		if received < offset || received > offset+int64(len(chunk)) {
+			return nil, fmt.Errorf("generators: %s upload resumed at unexpected offset %d", g.name(), received)
+		}
+		sent = int(received - offset)
+		trace.retry(attempt+1, err)
+	}
+}
+
+// queryUpload returns the number of bytes received by the upload session.
+func (g *GeminiGenerator) queryUpload(ctx context.Context, session string) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, session, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("X-Goog-Upload-Command", "query")
+	resp, err := g.doRaw(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return strconv.ParseInt(resp.Header.Get("X-Goog-Upload-Size-Received"), 10, 64)
+}
+
+// doRaw authorizes and sends a request whose body is not JSON, turning
+// a non-200 status into an APIError.
+func (g *GeminiGenerator) doRaw(req *http.Request) (*http.Response, error) {
+	if err := g.authorize(req); err != nil {
+		return nil, err
+	}
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("generators: %s request failed: %w", g.name(), err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{Provider: g.name(), StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	return resp, nil
+}
+
+// GetFile returns the metadata of an uploaded file given its name or ID.
+func (g *GeminiGenerator) GetFile(ctx context.Context, name string) (*File, error) {
+	if g.tokens != nil {
+		return nil, errVertexFiles
+	}
+	root, _ := g.apiRoot()
+	var out geminiFile
+	if err := g.doJSON(ctx, http.MethodGet, root+"/"+fileName(name), nil, &out); err != nil {
+		return nil, err
+	}
+	f := out.file()
+	return &f, nil
+}
+
+// WaitForFile polls an uploaded file every interval, or every two seconds
+// when interval is not positive, until it leaves the processing state. It
+// returns the file once it is active and an error if its processing
+// failed or ctx is done first.
+func (g *GeminiGenerator) WaitForFile(ctx context.Context, name string, interval time.Duration) (*File, error) {
+	if interval <= 0 {
+		interval = defaultFilePollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		f, err := g.GetFile(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		switch f.State {
+		case FileStateActive:
+			return f, nil
+		case FileStateFailed:
+			return f, fmt.Errorf("generators: %s processing of %s failed: %s", g.name(), f.Name, f.Error)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// ListFiles returns the metadata of all the files uploaded with the API
+// key.
+func (g *GeminiGenerator) ListFiles(ctx context.Context) ([]File, error) {
+	if g.tokens != nil {
+		return nil, errVertexFiles
+	}
+	root, _ := g.apiRoot()
+	var all []File
+	pageToken := ""
+	for {
+		q := url.Values{"pageSize": {"100"}}
+		if pageToken != "" {
+			q.Set("pageToken", pageToken)
+		}
+		var page struct {
+			Files         []geminiFile `json:"files"`
+			NextPageToken string       `json:"nextPageToken"`
+		}
+		if err := g.doJSON(ctx, http.MethodGet, root+"/files?"+q.Encode(), nil, &page); err != nil {
+			return nil, err
+		}
+		for _, f := range page.Files {
+			all = append(all, f.file())
+		}
+		if page.NextPageToken == "" {
+			return all, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// DeleteFile deletes an uploaded file given its name or ID. Files are
+// otherwise deleted automatically after 48 hours.
+func (g *GeminiGenerator) DeleteFile(ctx context.Context, name string) error {
+	if g.tokens != nil {
+		return errVertexFiles
+	}
+	root, _ := g.apiRoot()
+	return g.doJSON(ctx, http.MethodDelete, root+"/"+fileName(name), nil, nil)
+}
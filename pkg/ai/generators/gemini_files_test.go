@@ -0,0 +1,207 @@
+package generators
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeUploadServer implements the resumable upload protocol. When failAt is
+// positive, the first upload request that would carry the file past that
+// offset stores the bytes up to it and fails with a server error.
+type fakeUploadServer struct {
+	mu       sync.Mutex
+	received bytes.Buffer
+	failAt   int
+	failed   bool
+	commands []string
+}
+
+func (s *fakeUploadServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	command := r.Header.Get("X-Goog-Upload-Command")
+	s.commands = append(s.commands, command)
+	switch command {
+	case "start":
+		if r.URL.Path != "/upload/v1beta/files" || r.Header.Get("X-Goog-Upload-Header-Content-Type") != "video/mp4" ||
+			r.Header.Get("X-Goog-Api-Key") != "key" {
+			http.Error(w, "bad start", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("X-Goog-Upload-URL", "http://"+r.Host+"/session/1")
+	case "query":
+		w.Header().Set("X-Goog-Upload-Size-Received", strconv.Itoa(s.received.Len()))
+	case "upload", "upload, finalize":
+		offset, _ := strconv.Atoi(r.Header.Get("X-Goog-Upload-Offset"))
+		if offset != s.received.Len() {
+			http.Error(w, "bad offset", http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		if s.failAt > 0 && !s.failed && offset+len(data) > s.failAt {
+			s.failed = true
+			s.received.Write(data[:s.failAt-offset])
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		s.received.Write(data)
+		if command == "upload, finalize" {
+			fmt.Fprintf(w, `{"file":{"name":"files/abc","mimeType":"video/mp4","sizeBytes":"%d","uri":"https://example.com/files/abc","state":"PROCESSING"}}`,
+				s.received.Len())
+		}
+	default:
+		http.Error(w, "bad command", http.StatusBadRequest)
+	}
+}
+
+func TestGeminiUploadFile(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789abcdef"), (fileUploadChunkSize+1000)/16)
+
+	hook := &recordingHook{}
+	RegisterHook(hook)
+	defer ResetHooks()
+
+	for _, failAt := range []int{0, fileUploadChunkSize + 500} {
+		fake := &fakeUploadServer{failAt: failAt}
+		server := httptest.NewServer(fake)
+		gen := &GeminiGenerator{httpClient: server.Client(), apiKey: "key", model: "gemini-2.0-flash", baseURL: server.URL + "/v1beta/models"}
+
+		f, err := gen.UploadFile(context.Background(), bytes.NewReader(data), FileUploadRequest{MIMEType: "video/mp4", Size: int64(len(data))})
+		server.Close()
+		if err != nil {
+			t.Fatalf("failAt %d: UploadFile() error = %v", failAt, err)
+		}
+		if !bytes.Equal(fake.received.Bytes(), data) {
+			t.Errorf("failAt %d: received %d bytes, want %d", failAt, fake.received.Len(), len(data))
+		}
+		if f.Name != "files/abc" || f.SizeBytes != int64(len(data)) || f.State != FileStateProcessing {
+			t.Errorf("failAt %d: file = %+v", failAt, f)
+		}
+		if got := f.FileData(); got.URI != "https://example.com/files/abc" || got.MIMEType != "video/mp4" {
+			t.Errorf("failAt %d: FileData() = %+v", failAt, got)
+		}
+		want := "[start upload upload, finalize]"
+		if failAt > 0 {
+			want = "[start upload upload, finalize query upload, finalize]"
+		}
+		if got := fmt.Sprint(fake.commands); got != want {
+			t.Errorf("failAt %d: commands = %s, want %s", failAt, got, want)
+		}
+	}
+
+	// The resumed chunk is reported to hooks as a retry.
+	if len(hook.spans) != 2 || hook.infos[1].Operation != OperationUpload {
+		t.Fatalf("infos = %+v, want two uploads", hook.infos)
+	}
+	if got := fmt.Sprint(hook.spans[0].retries, hook.spans[1].retries); got != "[] [1]" {
+		t.Errorf("retries = %s, want [] [1]", got)
+	}
+	if len(hook.spans[1].ends) != 1 || hook.spans[1].ends[0].Err != nil {
+		t.Errorf("ends = %+v", hook.spans[1].ends)
+	}
+}
+
+func TestGeminiUploadFile_ClientError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "quota", http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	gen := &GeminiGenerator{httpClient: server.Client(), baseURL: server.URL + "/v1beta/models"}
+	_, err := gen.UploadFile(context.Background(), strings.NewReader("x"), FileUploadRequest{MIMEType: "text/plain"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("UploadFile() error = %v, want APIError 429", err)
+	}
+	if _, err := gen.UploadFile(context.Background(), strings.NewReader("x"), FileUploadRequest{}); err == nil {
+		t.Error("upload without MIME type accepted")
+	}
+	vertex := &GeminiGenerator{tokens: &googleTokenSource{}}
+	if _, err := vertex.ListFiles(context.Background()); err != errVertexFiles {
+		t.Errorf("Vertex ListFiles() error = %v", err)
+	}
+}
+
+func TestGeminiFiles(t *testing.T) {
+	var calls []string
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.RequestURI())
+		switch {
+		case r.URL.Path == "/v1beta/files/ok":
+			polls++
+			state := "PROCESSING"
+			if polls > 2 {
+				state = "ACTIVE"
+			}
+			fmt.Fprintf(w, `{"name":"files/ok","state":%q,"createTime":"2025-01-02T03:04:05.123Z"}`, state)
+		case r.URL.Path == "/v1beta/files/bad":
+			fmt.Fprint(w, `{"name":"files/bad","state":"FAILED","error":{"code":3,"message":"unsupported codec"}}`)
+		case r.Method == http.MethodDelete:
+			fmt.Fprint(w, `{}`)
+		case r.URL.Query().Get("pageToken") == "":
+			fmt.Fprint(w, `{"files":[{"name":"files/a","sizeBytes":"12"}],"nextPageToken":"p2"}`)
+		default:
+			fmt.Fprint(w, `{"files":[{"name":"files/b"}]}`)
+		}
+	}))
+	defer server.Close()
+
+	gen := &GeminiGenerator{httpClient: server.Client(), baseURL: server.URL + "/v1beta/models"}
+	ctx := context.Background()
+
+	f, err := gen.WaitForFile(ctx, "ok", time.Millisecond)
+	if err != nil || f.State != FileStateActive || polls != 3 || f.CreateTime.IsZero() {
+		t.Errorf("WaitForFile() = %+v, %v after %d polls", f, err, polls)
+	}
+	if _, err := gen.WaitForFile(ctx, "files/bad", time.Millisecond); err == nil || !strings.Contains(err.Error(), "unsupported codec") {
+		t.Errorf("WaitForFile(bad) error = %v", err)
+	}
+	files, err := gen.ListFiles(ctx)
+	if err != nil || len(files) != 2 || files[0].SizeBytes != 12 || files[1].Name != "files/b" {
+		t.Errorf("ListFiles() = %+v, %v", files, err)
+	}
+	if err := gen.DeleteFile(ctx, "a"); err != nil {
+		t.Errorf("DeleteFile() error = %v", err)
+	}
+	if got := calls[len(calls)-1]; got != "DELETE /v1beta/files/a" {
+		t.Errorf("last call = %q", got)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := gen.WaitForFile(canceled, "ok", time.Hour); err == nil {
+		t.Error("WaitForFile() with canceled context succeeded")
+	}
+}
+
+func TestGeminiGenerate_FileData(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		json.NewDecoder(r.Body).Decode(&req)
+		parts, _ := json.Marshal(req["contents"].([]any)[0].(map[string]any)["parts"])
+		want := `[{"fileData":{"fileUri":"https://example.com/files/abc","mimeType":"application/pdf"}},{"text":"summarize"}]`
+		if string(parts) != want {
+			t.Errorf("parts = %s, want %s", parts, want)
+		}
+		fmt.Fprint(w, `{"candidates":[{"content":{"parts":[{"text":"ok"}]}}]}`)
+	}))
+	defer server.Close()
+
+	gen := &GeminiGenerator{httpClient: server.Client(), model: "gemini-2.0-flash", baseURL: server.URL + "/v1beta/models"}
+	file := File{URI: "https://example.com/files/abc", MIMEType: "application/pdf"}
+	if _, err := gen.Generate(context.Background(), "summarize", WithFileData(file.FileData()), WithStrict()); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+}
@@ -3,6 +3,7 @@
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -10,6 +11,7 @@
 	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 // --- Opener tests ---
@@ -178,14 +180,14 @@ func TestGeminiBuildRequestBody(t *testing.T) {
 	})
 
 	t.Run("full config sets all fields", func(t *testing.T) {
-		cfg := &Config{
-			Temperature:       0.7,
-			MaxOutputTokens:   256,
-			TopP:              0.9,
-			TopK:              40,
-			SystemInstruction: "You are a helpful assistant.",
-			StopSequences:     []string{"END", "STOP"},
-		}
+		cfg := newConfig([]Option{
+			WithTemperature(0.7),
+			WithMaxOutputTokens(256),
+			WithTopP(0.9),
+			WithTopK(40),
+			WithSystemInstruction("You are a helpful assistant."),
+			WithStopSequences("END", "STOP"),
+		})
 		req := g.buildRequestBody(cfg, "test prompt")
 
 		if req.GenerationConfig == nil {
@@ -194,8 +196,8 @@ func TestGeminiBuildRequestBody(t *testing.T) {
 		if *req.GenerationConfig.Temperature != 0.7 {
 			t.Errorf("Temperature = %v, want 0.7", *req.GenerationConfig.Temperature)
 		}
-		if req.GenerationConfig.MaxOutputTokens != 256 {
-			t.Errorf("MaxOutputTokens = %d, want 256", req.GenerationConfig.MaxOutputTokens)
+		if *req.GenerationConfig.MaxOutputTokens != 256 {
+			t.Errorf("MaxOutputTokens = %d, want 256", *req.GenerationConfig.MaxOutputTokens)
 		}
 		if req.SystemInstruction == nil || req.SystemInstruction.Parts[0].Text != "You are a helpful assistant." {
 			t.Error("SystemInstruction not set correctly")
@@ -203,7 +205,7 @@ func TestGeminiBuildRequestBody(t *testing.T) {
 	})
 
 	t.Run("config serializes to valid JSON", func(t *testing.T) {
-		cfg := &Config{Temperature: 0.5, MaxOutputTokens: 100}
+		cfg := newConfig([]Option{WithTemperature(0.5), WithMaxOutputTokens(100)})
 		req := g.buildRequestBody(cfg, "test")
 		data, err := json.Marshal(req)
 		if err != nil {
@@ -213,6 +215,66 @@ func TestGeminiBuildRequestBody(t *testing.T) {
 			t.Error("JSON should contain temperature")
 		}
 	})
+
+	t.Run("wire JSON", func(t *testing.T) {
+		testCases := []struct {
+			name string
+			opts []Option
+			want string
+		}{
+			{
+				name: "no options omits generationConfig",
+				want: `{"contents":[{"parts":[{"text":"test"}]}]}`,
+			},
+			{
+				name: "explicit zero values are sent",
+				opts: []Option{WithTemperature(0), WithTopP(0), WithTopK(0), WithMaxOutputTokens(0)},
+				want: `{"contents":[{"parts":[{"text":"test"}]}],"generationConfig":{"temperature":0,"maxOutputTokens":0,"topP":0,"topK":0}}`,
+			},
+			{
+				name: "only set fields are sent",
+				opts: []Option{WithTemperature(0), WithStopSequences("END")},
+				want: `{"contents":[{"parts":[{"text":"test"}]}],"generationConfig":{"temperature":0,"stopSequences":["END"]}}`,
+			},
+			{
+				name: "extended sampling options",
+				opts: []Option{WithSeed(42), WithPresencePenalty(0.5), WithFrequencyPenalty(0), WithCandidateCount(2), WithResponseLogprobs(3)},
+				want: `{"contents":[{"parts":[{"text":"test"}]}],"generationConfig":{"seed":42,"presencePenalty":0.5,"frequencyPenalty":0,"candidateCount":2,"responseLogprobs":true,"logprobs":3}}`,
+			},
+			{
+				name: "JSON output",
+				opts: []Option{WithJSONOutput()},
+				want: `{"contents":[{"parts":[{"text":"test"}]}],"generationConfig":{"responseMimeType":"application/json"}}`,
+			},
+			{
+				name: "thinking budget with thoughts",
+				opts: []Option{WithThinkingBudget(0), WithIncludeThoughts()},
+				want: `{"contents":[{"parts":[{"text":"test"}]}],"generationConfig":{"thinkingConfig":{"thinkingBudget":0,"includeThoughts":true}}}`,
+			},
+			{
+				name: "thinking level",
+				opts: []Option{WithThinkingLevel("low")},
+				want: `{"contents":[{"parts":[{"text":"test"}]}],"generationConfig":{"thinkingConfig":{"thinkingLevel":"low"}}}`,
+			},
+			{
+				name: "safety settings and google search",
+				opts: []Option{WithSafetySettings(SafetySetting{HarmCategoryHarassment, BlockOnlyHigh}), WithGoogleSearch()},
+				want: `{"contents":[{"parts":[{"text":"test"}]}],"safetySettings":[{"category":"HARM_CATEGORY_HARASSMENT","threshold":"BLOCK_ONLY_HIGH"}],"tools":[{"google_search":{}}]}`,
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				data, err := json.Marshal(g.buildRequestBody(newConfig(tc.opts), "test"))
+				if err != nil {
+					t.Fatalf("marshal: %v", err)
+				}
+				if string(data) != tc.want {
+					t.Errorf("JSON = %s, want %s", data, tc.want)
+				}
+			})
+		}
+	})
 }
 
 func TestGeminiParseResponse(t *testing.T) {
@@ -227,20 +289,13 @@ func TestGeminiParseResponse(t *testing.T) {
 
 	t.Run("full response maps correctly", func(t *testing.T) {
 		gemResp := &geminiResponse{
-			Candidates: []struct {
-				Content      geminiContent `json:"content"`
-				FinishReason string        `json:"finishReason"`
-			}{
+			Candidates: []geminiCandidate{
 				{
 					Content:      geminiContent{Parts: []geminiPart{{Text: "Hello world"}}},
 					FinishReason: "STOP",
 				},
 			},
-			UsageMetadata: &struct {
-				PromptTokenCount     int `json:"promptTokenCount"`
-				CandidatesTokenCount int `json:"candidatesTokenCount"`
-				TotalTokenCount      int `json:"totalTokenCount"`
-			}{PromptTokenCount: 10, CandidatesTokenCount: 5, TotalTokenCount: 15},
+			UsageMetadata: &geminiUsageMetadata{PromptTokenCount: 10, CandidatesTokenCount: 5, TotalTokenCount: 15},
 		}
 
 		resp := g.parseResponse(gemResp, "gemini-2.0-flash")
@@ -254,10 +309,7 @@ func TestGeminiParseResponse(t *testing.T) {
 
 	t.Run("multi-part content is concatenated", func(t *testing.T) {
 		gemResp := &geminiResponse{
-			Candidates: []struct {
-				Content      geminiContent `json:"content"`
-				FinishReason string        `json:"finishReason"`
-			}{
+			Candidates: []geminiCandidate{
 				{Content: geminiContent{Parts: []geminiPart{{Text: "Hello "}, {Text: "world"}}}},
 			},
 		}
@@ -266,6 +318,100 @@ func TestGeminiParseResponse(t *testing.T) {
 			t.Errorf("Text = %q, want %q", resp.Text, "Hello world")
 		}
 	})
+
+	t.Run("thought parts are separated from the answer", func(t *testing.T) {
+		gemResp := &geminiResponse{
+			Candidates: []geminiCandidate{
+				{Content: geminiContent{Parts: []geminiPart{
+					{Text: "Let me think. ", Thought: true},
+					{Text: "Done.", Thought: true},
+					{Text: "42"},
+				}}},
+			},
+			UsageMetadata: &geminiUsageMetadata{PromptTokenCount: 3, CandidatesTokenCount: 1, ThoughtsTokenCount: 20, TotalTokenCount: 24},
+		}
+		resp := g.parseResponse(gemResp, "model")
+		if resp.Text != "42" {
+			t.Errorf("Text = %q, want %q", resp.Text, "42")
+		}
+		if resp.Thoughts != "Let me think. Done." {
+			t.Errorf("Thoughts = %q, want %q", resp.Thoughts, "Let me think. Done.")
+		}
+		if resp.Usage.ThoughtTokens != 20 || resp.Usage.CompletionTokens != 1 {
+			t.Errorf("Usage = %+v, want 20 thought tokens and 1 completion token", resp.Usage)
+		}
+	})
+
+	t.Run("multiple candidates with logprobs", func(t *testing.T) {
+		var gemResp geminiResponse
+		data := `{"candidates":[
+			{"content":{"parts":[{"text":"Yes"}]},"finishReason":"STOP",
+			 "logprobsResult":{
+			   "topCandidates":[{"candidates":[{"token":"Yes","logProbability":-0.1},{"token":"No","logProbability":-2.3}]}],
+			   "chosenCandidates":[{"token":"Yes","logProbability":-0.1}]}},
+			{"content":{"parts":[{"text":"No"}]},"finishReason":"MAX_TOKENS"}]}`
+		if err := json.Unmarshal([]byte(data), &gemResp); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+
+		resp := g.parseResponse(&gemResp, "model")
+		if resp.Text != "Yes" || resp.FinishReason != "STOP" {
+			t.Errorf("Text/FinishReason = %q/%q, want first candidate", resp.Text, resp.FinishReason)
+		}
+		if len(resp.Candidates) != 2 {
+			t.Fatalf("len(Candidates) = %d, want 2", len(resp.Candidates))
+		}
+		if resp.Candidates[1].Text != "No" || resp.Candidates[1].FinishReason != "MAX_TOKENS" {
+			t.Errorf("Candidates[1] = %+v, want No/MAX_TOKENS", resp.Candidates[1])
+		}
+		lps := resp.Candidates[0].Logprobs
+		if len(lps) != 1 || lps[0].Token != "Yes" || lps[0].Logprob != -0.1 {
+			t.Fatalf("Logprobs = %+v, want single Yes token", lps)
+		}
+		if len(lps[0].TopLogprobs) != 2 || lps[0].TopLogprobs[1].Token != "No" {
+			t.Errorf("TopLogprobs = %+v, want Yes and No alternatives", lps[0].TopLogprobs)
+		}
+		if resp.Candidates[1].Logprobs != nil {
+			t.Errorf("Candidates[1].Logprobs = %+v, want nil", resp.Candidates[1].Logprobs)
+		}
+	})
+}
+
+func TestGeminiParseResponse_Metadata(t *testing.T) {
+	g := &GeminiGenerator{}
+
+	var gemResp geminiResponse
+	data := `{"candidates":[{"content":{"parts":[{"text":"Spain won Euro 2024."}]},"finishReason":"STOP",
+		"safetyRatings":[{"category":"HARM_CATEGORY_HARASSMENT","probability":"NEGLIGIBLE"}],
+		"citationMetadata":{"citationSources":[{"startIndex":0,"endIndex":5,"uri":"https://example.com/a","license":"mit"}]},
+		"groundingMetadata":{
+			"groundingChunks":[{"web":{"uri":"https://example.com/euro","title":"example.com"}}],
+			"groundingSupports":[{"segment":{"startIndex":0,"endIndex":20,"text":"Spain won Euro 2024."},"groundingChunkIndices":[0],"confidenceScores":[0.9]}],
+			"webSearchQueries":["euro 2024 winner"]}}]}`
+	if err := json.Unmarshal([]byte(data), &gemResp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	c := g.parseResponse(&gemResp, "model").Candidates[0]
+	if len(c.SafetyRatings) != 1 || c.SafetyRatings[0].Probability != "NEGLIGIBLE" || c.SafetyRatings[0].Blocked {
+		t.Errorf("SafetyRatings = %+v, want one unblocked NEGLIGIBLE rating", c.SafetyRatings)
+	}
+	if len(c.Citations) != 1 || c.Citations[0].URI != "https://example.com/a" || c.Citations[0].EndIndex != 5 || c.Citations[0].License != "mit" {
+		t.Errorf("Citations = %+v", c.Citations)
+	}
+	gr := c.Grounding
+	if gr == nil {
+		t.Fatal("Grounding is nil")
+	}
+	if len(gr.Sources) != 1 || gr.Sources[0].URI != "https://example.com/euro" {
+		t.Errorf("Sources = %+v", gr.Sources)
+	}
+	if len(gr.Supports) != 1 || gr.Supports[0].EndIndex != 20 || gr.Supports[0].SourceIndices[0] != 0 || gr.Supports[0].Confidence[0] != 0.9 {
+		t.Errorf("Supports = %+v", gr.Supports)
+	}
+	if len(gr.SearchQueries) != 1 || gr.SearchQueries[0] != "euro 2024 winner" {
+		t.Errorf("SearchQueries = %v", gr.SearchQueries)
+	}
 }
 
 func TestGeminiResolveModel(t *testing.T) {
@@ -288,17 +434,10 @@ func TestGeminiGenerate_HTTPTestServer(t *testing.T) {
 			return
 		}
 		resp := geminiResponse{
-			Candidates: []struct {
-				Content      geminiContent `json:"content"`
-				FinishReason string        `json:"finishReason"`
-			}{
+			Candidates: []geminiCandidate{
 				{Content: geminiContent{Parts: []geminiPart{{Text: "test response"}}}, FinishReason: "STOP"},
 			},
-			UsageMetadata: &struct {
-				PromptTokenCount     int `json:"promptTokenCount"`
-				CandidatesTokenCount int `json:"candidatesTokenCount"`
-				TotalTokenCount      int `json:"totalTokenCount"`
-			}{PromptTokenCount: 5, CandidatesTokenCount: 3, TotalTokenCount: 8},
+			UsageMetadata: &geminiUsageMetadata{PromptTokenCount: 5, CandidatesTokenCount: 3, TotalTokenCount: 8},
 		}
 		json.NewEncoder(w).Encode(resp)
 	}))
@@ -327,14 +466,12 @@ func TestGeminiStream_HTTPTestServer(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/event-stream")
 		chunks := []string{"Hello ", "world", "!"}
-		for _, chunk := range chunks {
+		for i, chunk := range chunks {
 			gemResp := geminiResponse{
-				Candidates: []struct {
-					Content      geminiContent `json:"content"`
-					FinishReason string        `json:"finishReason"`
-				}{
+				Candidates: []geminiCandidate{
 					{Content: geminiContent{Parts: []geminiPart{{Text: chunk}}}},
 				},
+				UsageMetadata: &geminiUsageMetadata{PromptTokenCount: 2, CandidatesTokenCount: i + 1, TotalTokenCount: i + 3},
 			}
 			data, _ := json.Marshal(gemResp)
 			fmt.Fprintf(w, "data: %s\n\n", data)
@@ -355,15 +492,52 @@ func TestGeminiStream_HTTPTestServer(t *testing.T) {
 	}
 
 	var collected string
+	var usage *Usage
 	for chunk := range ch {
 		if chunk.Error != nil {
 			t.Fatalf("Stream chunk error: %v", chunk.Error)
 		}
 		collected += chunk.Text
+		if chunk.Usage != nil {
+			if usage != nil {
+				t.Error("usage should be reported once")
+			}
+			usage = chunk.Usage
+		}
 	}
 	if collected != "Hello world!" {
 		t.Errorf("collected = %q, want %q", collected, "Hello world!")
 	}
+	if usage == nil || usage.CompletionTokens != 3 || usage.TotalTokens != 5 {
+		t.Errorf("usage = %+v, want last cumulative usage", usage)
+	}
+}
+
+func TestGeminiStream_ThoughtChunks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"hmm\",\"thought\":true}]}}]}\n\n")
+		fmt.Fprint(w, "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"answer\"}]}}]}\n\n")
+	}))
+	defer server.Close()
+
+	gen := &GeminiGenerator{httpClient: server.Client(), model: "gemini-2.5-flash", baseURL: server.URL}
+
+	ch, err := gen.Stream(context.Background(), "hello", WithIncludeThoughts())
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+
+	var text, thought string
+	for chunk := range ch {
+		if chunk.Error != nil {
+			t.Fatalf("Stream chunk error: %v", chunk.Error)
+		}
+		text += chunk.Text
+		thought += chunk.Thought
+	}
+	if text != "answer" || thought != "hmm" {
+		t.Errorf("text/thought = %q/%q, want answer/hmm", text, thought)
+	}
 }
 
 func TestGeminiGenerate_APIError(t *testing.T) {
@@ -388,6 +562,154 @@ func TestGeminiGenerate_APIError(t *testing.T) {
 	}
 }
 
+func TestGeminiEmbed_HTTPTestServer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/gemini-embedding-001:batchEmbedContents" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		var req geminiEmbedRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Requests[0].Model != "models/gemini-embedding-001" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"embeddings":[{"values":[0.1,0.2]},{"values":[0.3,0.4]}]}`))
+	}))
+	defer server.Close()
+
+	gen := &GeminiGenerator{
+		httpClient: server.Client(),
+		apiKey:     "test-key",
+		model:      "gemini-2.0-flash",
+		baseURL:    server.URL,
+	}
+
+	vectors, err := gen.Embed(context.Background(), []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+	if len(vectors) != 2 || vectors[1][1] != 0.4 {
+		t.Errorf("vectors = %v, want [[0.1 0.2] [0.3 0.4]]", vectors)
+	}
+}
+
+func TestGeminiGenerate_Blocked(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"promptFeedback":{"blockReason":"SAFETY","safetyRatings":[{"category":"HARM_CATEGORY_DANGEROUS_CONTENT","probability":"HIGH","blocked":true}]}}`))
+	}))
+	defer server.Close()
+
+	gen := &GeminiGenerator{
+		httpClient: server.Client(),
+		apiKey:     "test-key",
+		model:      "gemini-2.0-flash",
+		baseURL:    server.URL,
+	}
+
+	_, err := gen.Generate(context.Background(), "hello")
+	var blocked *BlockedError
+	if !errors.As(err, &blocked) {
+		t.Fatalf("error = %v, want *BlockedError", err)
+	}
+	if blocked.Provider != "gemini" || blocked.Reason != "SAFETY" || len(blocked.SafetyRatings) != 1 || !blocked.SafetyRatings[0].Blocked {
+		t.Errorf("BlockedError = %+v", blocked)
+	}
+
+	gen.tokens = &googleTokenSource{cached: "token", expires: time.Now().Add(time.Hour), now: time.Now}
+	_, err = gen.Generate(context.Background(), "hello")
+	if !errors.As(err, &blocked) || blocked.Provider != "vertex" {
+		t.Errorf("Vertex error = %v, want *BlockedError from vertex", err)
+	}
+}
+
+func TestGeminiStream_Blocked(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"promptFeedback\":{\"blockReason\":\"PROHIBITED_CONTENT\"}}\n\n"))
+	}))
+	defer server.Close()
+
+	gen := &GeminiGenerator{
+		httpClient: server.Client(),
+		apiKey:     "test-key",
+		model:      "gemini-2.0-flash",
+		baseURL:    server.URL,
+	}
+
+	ch, err := gen.Stream(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	var blocked *BlockedError
+	for chunk := range ch {
+		if chunk.Error != nil && !errors.As(chunk.Error, &blocked) {
+			t.Fatalf("chunk error = %v, want *BlockedError", chunk.Error)
+		}
+	}
+	if blocked == nil || blocked.Reason != "PROHIBITED_CONTENT" {
+		t.Errorf("blocked = %+v, want PROHIBITED_CONTENT", blocked)
+	}
+}
+
+func TestGeminiStream_BlockedFinish(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"Once \"}]}}]}\n\n")
+		fmt.Fprint(w, "data: {\"candidates\":[{\"content\":{\"parts\":[]},\"finishReason\":\"SAFETY\","+
+			"\"safetyRatings\":[{\"category\":\"HARM_CATEGORY_HARASSMENT\",\"probability\":\"HIGH\",\"blocked\":true}]}]}\n\n")
+	}))
+	defer server.Close()
+
+	gen := &GeminiGenerator{httpClient: server.Client(), model: "gemini-2.0-flash", baseURL: server.URL}
+	ch, err := gen.Stream(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	var text strings.Builder
+	var last StreamChunk
+	for chunk := range ch {
+		text.WriteString(chunk.Text)
+		last = chunk
+	}
+	var blocked *BlockedError
+	if !errors.As(last.Error, &blocked) {
+		t.Fatalf("last chunk error = %v, want *BlockedError", last.Error)
+	}
+	if text.String() != "Once " || blocked.Reason != "SAFETY" || len(blocked.SafetyRatings) != 1 || blocked.Provider != "gemini" {
+		t.Errorf("text = %q, blocked = %+v", text.String(), blocked)
+	}
+}
+
+func TestGeminiStream_Metadata(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"Madrid\"}]},"+
+			"\"citationMetadata\":{\"citationSources\":[{\"startIndex\":0,\"endIndex\":6,\"uri\":\"https://a.example\"}]}}]}\n\n")
+		fmt.Fprint(w, "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\".\"}]},\"finishReason\":\"STOP\","+
+			"\"safetyRatings\":[{\"category\":\"HARM_CATEGORY_HARASSMENT\",\"probability\":\"NEGLIGIBLE\"}],"+
+			"\"groundingMetadata\":{\"webSearchQueries\":[\"capital of spain\"]}}],"+
+			"\"usageMetadata\":{\"promptTokenCount\":3,\"candidatesTokenCount\":2,\"totalTokenCount\":5}}\n\n")
+	}))
+	defer server.Close()
+
+	gen := &GeminiGenerator{httpClient: server.Client(), model: "gemini-2.0-flash", baseURL: server.URL}
+	ch, err := gen.Stream(context.Background(), "hello", WithGoogleSearch())
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	var last StreamChunk
+	for chunk := range ch {
+		if chunk.Error != nil {
+			t.Fatalf("chunk error = %v", chunk.Error)
+		}
+		last = chunk
+	}
+	if last.FinishReason != "STOP" || last.Usage == nil || last.Usage.TotalTokens != 5 || len(last.SafetyRatings) != 1 || len(last.Citations) != 1 ||
+		last.Citations[0].URI != "https://a.example" || last.Grounding == nil || last.Grounding.SearchQueries[0] != "capital of spain" {
+		t.Errorf("trailing chunk = %+v", last)
+	}
+}
+
 // --- Live integration tests ---
 
 func TestGeminiOpener_Open_Integration(t *testing.T) {
@@ -20,22 +20,109 @@ type Generator interface {
 }
 
 // Response represents the result of a text generation call.
+// Text, Thoughts and FinishReason mirror the first candidate; all
+// candidates, including the first, are listed in Candidates.
+// Thoughts holds the model's reasoning summary when it was requested.
 type Response struct {
 	Text         string
+	Thoughts     string
 	Model        string
 	FinishReason string
 	Usage        Usage
+	Candidates   []Candidate
+}
+
+// Candidate represents one alternative response produced by the model.
+// SafetyRatings, Grounding and Citations are only set by providers that
+// report them.
+type Candidate struct {
+	Text          string
+	Thoughts      string
+	FinishReason  string
+	Logprobs      []TokenLogprob
+	SafetyRatings []SafetyRating
+	Grounding     *Grounding
+	Citations     []Citation
+}
+
+// SafetyRating reports the probability that content belongs to a harm
+// category, and whether it was blocked because of it.
+type SafetyRating struct {
+	Category    string
+	Probability string
+	Blocked     bool
+}
+
+// Grounding describes the sources an answer was grounded on.
+// Supports link segments of the answer text to entries in Sources.
+type Grounding struct {
+	Sources       []GroundingSource
+	Supports      []GroundingSupport
+	SearchQueries []string
+}
+
+// GroundingSource is a document used to ground an answer.
+type GroundingSource struct {
+	URI   string
+	Title string
+}
+
+// GroundingSupport links a segment of the answer text to the sources that
+// support it. StartIndex and EndIndex are byte offsets into the candidate
+// text; SourceIndices index into Grounding.Sources and Confidence holds the
+// matching confidence scores, when reported.
+type GroundingSupport struct {
+	StartIndex    int
+	EndIndex      int
+	Text          string
+	SourceIndices []int
+	Confidence    []float64
+}
+
+// Citation attributes a segment of the answer text to a source.
+// StartIndex and EndIndex are byte offsets into the candidate text.
+type Citation struct {
+	StartIndex int
+	EndIndex   int
+	URI        string
+	Title      string
+	License    string
+}
+
+// TokenLogprob holds the log probability of a single output token and,
+// when requested, the most likely alternatives at that position.
+type TokenLogprob struct {
+	Token       string
+	Logprob     float64
+	TopLogprobs []TokenLogprob
 }
 
 // Usage represents token usage statistics for a generation call.
+// ThoughtTokens counts reasoning tokens, which are not included in
+// CompletionTokens but are included in TotalTokens. CachedTokens counts
+// prompt tokens read from a context cache; they are included in
+// PromptTokens.
 type Usage struct {
 	PromptTokens     int
 	CompletionTokens int
+	ThoughtTokens    int
+	CachedTokens     int
 	TotalTokens      int
 }
 
 // StreamChunk represents a single chunk of a streamed response.
+// Reasoning summaries are delivered in Thought, never in Text.
+// Providers that report token usage send it in a trailing chunk with
+// empty Text once the stream completes successfully. The same chunk
+// carries the finish reason, safety ratings, grounding and citations of
+// the response, for providers that report them.
 type StreamChunk struct {
-	Text  string
-	Error error
+	Text          string
+	Thought       string
+	Error         error
+	Usage         *Usage
+	FinishReason  string
+	SafetyRatings []SafetyRating
+	Grounding     *Grounding
+	Citations     []Citation
 }
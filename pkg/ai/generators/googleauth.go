@@ -0,0 +1,186 @@
+package generators
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultGoogleTokenURL is the OAuth 2.0 token endpoint used when the
+	// credentials do not name one.
+	defaultGoogleTokenURL = "https://oauth2.googleapis.com/token"
+
+	// googleCloudPlatformScope is the OAuth scope requested for Vertex AI.
+	googleCloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+	// googleTokenLifetime is the lifetime requested for signed assertions.
+	googleTokenLifetime = time.Hour
+
+	// googleTokenRefreshMargin is how long before its expiry a cached access
+	// token is replaced.
+	googleTokenRefreshMargin = time.Minute
+)
+
+// googleServiceAccount holds the fields of a service-account credentials
+// JSON file used to sign token requests.
+type googleServiceAccount struct {
+	Type         string `json:"type"`
+	ProjectID    string `json:"project_id"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	ClientEmail  string `json:"client_email"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// loadGoogleServiceAccount reads a service-account credentials file and
+// parses its private key.
+func loadGoogleServiceAccount(path string) (*googleServiceAccount, *rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generators: read credentials: %w", err)
+	}
+	var sa googleServiceAccount
+	if err := json.Unmarshal(data, &sa); err != nil {
+		return nil, nil, fmt.Errorf("generators: parse credentials %s: %w", path, err)
+	}
+	if sa.Type != "service_account" {
+		return nil, nil, fmt.Errorf("generators: credentials %s: type %q is not service_account", path, sa.Type)
+	}
+	if sa.ClientEmail == "" || sa.PrivateKey == "" {
+		return nil, nil, fmt.Errorf("generators: credentials %s: missing client_email or private_key", path)
+	}
+	key, err := parseRSAPrivateKey([]byte(sa.PrivateKey))
+	if err != nil {
+		return nil, nil, fmt.Errorf("generators: credentials %s: %w", path, err)
+	}
+	return &sa, key, nil
+}
+
+// parseRSAPrivateKey parses a PEM-encoded PKCS #8 or PKCS #1 RSA key.
+func parseRSAPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("private key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an RSA key")
+	}
+	return key, nil
+}
+
+// googleTokenSource obtains OAuth access tokens for a service account by
+// exchanging signed JWT assertions, and caches them until shortly before
+// they expire. It is safe for concurrent use.
+type googleTokenSource struct {
+	httpClient *http.Client
+	tokenURL   string
+	email      string
+	keyID      string
+	key        *rsa.PrivateKey
+	now        func() time.Time
+
+	mu      sync.Mutex
+	cached  string
+	expires time.Time
+}
+
+// token returns a valid access token, fetching a new one if the cached
+// token is missing or about to expire.
+func (s *googleTokenSource) token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if s.cached != "" && now.Before(s.expires.Add(-googleTokenRefreshMargin)) {
+		return s.cached, nil
+	}
+
+	assertion, err := s.assertion(now)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("generators: create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("generators: token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return "", &APIError{Provider: "google oauth", StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return "", fmt.Errorf("generators: decode token response: %w", err)
+	}
+	if tok.AccessToken == "" {
+		return "", errors.New("generators: token response has no access_token")
+	}
+	s.cached = tok.AccessToken
+	s.expires = now.Add(time.Duration(tok.ExpiresIn) * time.Second)
+	return s.cached, nil
+}
+
+// assertion returns a JWT signed with the service account's key, as
+// expected by the token endpoint.
+func (s *googleTokenSource) assertion(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": s.keyID})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]any{
+		"iss":   s.email,
+		"scope": googleCloudPlatformScope,
+		"aud":   s.tokenURL,
+		"iat":   now.Unix(),
+		"exp":   now.Add(googleTokenLifetime).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	signed := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", fmt.Errorf("generators: sign token assertion: %w", err)
+	}
+	return signed + "." + enc.EncodeToString(sig), nil
+}
@@ -0,0 +1,183 @@
+package generators
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Operation names reported in RequestInfo.Operation.
+const (
+	OperationGenerate = "generate"
+	OperationStream   = "stream"
+	OperationEmbed    = "embed"
+	OperationUpload   = "upload"
+)
+
+// RequestInfo describes a generator request reported to a Hook. Model is
+// empty for requests that do not use a model, such as file uploads.
+type RequestInfo struct {
+	Provider  string
+	Model     string
+	Operation string
+}
+
+// RequestResult summarizes a finished generator request.
+// Chunks counts the text and thought chunks delivered by a stream and is zero for
+// Generate. Usage is nil if the provider did not report token usage.
+type RequestResult struct {
+	Duration     time.Duration
+	Chunks       int
+	FinishReason string
+	Usage        *Usage
+	Err          error
+}
+
+// Hook observes generator requests, for example to export them to a
+// tracing backend. Hooks are invoked by every provider's Generate and Stream.
+type Hook interface {
+
+	// Start is called before a request is sent. The returned context is used
+	// for the request, so hooks can propagate trace context to the transport.
+	// The returned Span receives the rest of the request's events.
+	Start(ctx context.Context, info RequestInfo) (context.Context, Span)
+}
+
+// Span receives the events of a single generator request.
+// A Span's methods are never called concurrently.
+type Span interface {
+
+	// FirstToken is called once when a stream delivers its first text or
+	// thought chunk, with the latency measured from the start of the request.
+	FirstToken(latency time.Duration)
+
+	// Retry is called before each new attempt of a request that failed and
+	// is retried. attempt counts from 1 for the first retry; err caused it.
+	// Generate, Stream and Embed requests are not retried, so it is only
+	// called for file uploads resumed after a network or server error.
+	Retry(attempt int, err error)
+
+	// End is called exactly once when the request finishes.
+	End(result RequestResult)
+}
+
+var (
+	hooks   []Hook
+	hooksMu sync.RWMutex
+)
+
+// RegisterHook registers a hook invoked for every request made by any
+// generator. It panics if the hook is nil.
+//
+// Hooks that only apply to some requests can be passed with WithHooks.
+func RegisterHook(hook Hook) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+
+	if hook == nil {
+		panic("generators: RegisterHook hook is nil")
+	}
+	hooks = append(hooks, hook)
+}
+
+// ResetHooks removes all registered hooks.
+// This function is primarily useful for testing.
+func ResetHooks() {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+
+	hooks = nil
+}
+
+// WithHooks adds hooks invoked for this request only, after the hooks
+// registered with RegisterHook.
+func WithHooks(h ...Hook) Option {
+	return func(c *Config) { c.Hooks = append(c.Hooks, h...) }
+}
+
+// requestTrace reports the events of one request to the active hooks.
+// A requestTrace without spans does nothing.
+type requestTrace struct {
+	ctx        context.Context
+	spans      []Span
+	start      time.Time
+	firstToken bool
+	result     RequestResult
+}
+
+// startTrace starts a span on every registered hook and on the hooks set in
+// cfg, and returns the trace together with the context to use for the request.
+func startTrace(ctx context.Context, cfg *Config, info RequestInfo) *requestTrace {
+	hooksMu.RLock()
+	active := append(append([]Hook(nil), hooks...), cfg.Hooks...)
+	hooksMu.RUnlock()
+
+	t := &requestTrace{ctx: ctx, start: time.Now()}
+	for _, h := range active {
+		var span Span
+		t.ctx, span = h.Start(t.ctx, info)
+		if span != nil {
+			t.spans = append(t.spans, span)
+		}
+	}
+	return t
+}
+
+// observe records a stream chunk before it is delivered to the caller.
+func (t *requestTrace) observe(chunk StreamChunk) {
+	if chunk.Text != "" || chunk.Thought != "" {
+		t.result.Chunks++
+		if !t.firstToken {
+			t.firstToken = true
+			latency := time.Since(t.start)
+			for _, s := range t.spans {
+				s.FirstToken(latency)
+			}
+		}
+	}
+	if chunk.Usage != nil {
+		t.result.Usage = chunk.Usage
+	}
+	if chunk.FinishReason != "" {
+		t.result.FinishReason = chunk.FinishReason
+	}
+	if chunk.Error != nil {
+		t.result.Err = chunk.Error
+	}
+}
+
+// retry reports that the request is retried after err.
+func (t *requestTrace) retry(attempt int, err error) {
+	for _, s := range t.spans {
+		s.Retry(attempt, err)
+	}
+}
+
+// sender returns a function that records each chunk and sends it on ch.
+func (t *requestTrace) sender(ch chan<- StreamChunk) func(StreamChunk) {
+	return func(chunk StreamChunk) {
+		t.observe(chunk)
+		ch <- chunk
+	}
+}
+
+// end reports the end of the request. For Generate, resp and err are the
+// call's results; for Stream, the result accumulated by observe is used
+// unless err is non-nil.
+func (t *requestTrace) end(resp *Response, err error) {
+	if len(t.spans) == 0 {
+		return
+	}
+	t.result.Duration = time.Since(t.start)
+	if resp != nil {
+		u := resp.Usage
+		t.result.Usage = &u
+		t.result.FinishReason = resp.FinishReason
+	}
+	if err != nil {
+		t.result.Err = err
+	}
+	for _, s := range t.spans {
+		s.End(t.result)
+	}
+}
@@ -0,0 +1,107 @@
+package generators
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type recordingHook struct {
+	infos []RequestInfo
+	spans []*recordingSpan
+}
+
+func (h *recordingHook) Start(ctx context.Context, info RequestInfo) (context.Context, Span) {
+	h.infos = append(h.infos, info)
+	s := &recordingSpan{}
+	h.spans = append(h.spans, s)
+	return ctx, s
+}
+
+type recordingSpan struct {
+	firstTokens int
+	retries     []int
+	ends        []RequestResult
+}
+
+func (s *recordingSpan) FirstToken(time.Duration)   { s.firstTokens++ }
+func (s *recordingSpan) Retry(attempt int, _ error) { s.retries = append(s.retries, attempt) }
+func (s *recordingSpan) End(result RequestResult)   { s.ends = append(s.ends, result) }
+
+func TestHooks_GeminiStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, text := range []string{"a", "b", "c"} {
+			fmt.Fprintf(w, "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":%q}]}}],\"usageMetadata\":{\"totalTokenCount\":9}}\n\n", text)
+		}
+	}))
+	defer server.Close()
+
+	hook := &recordingHook{}
+	gen := &GeminiGenerator{httpClient: server.Client(), model: "gemini-2.0-flash", baseURL: server.URL}
+
+	ch, err := gen.Stream(context.Background(), "hi", WithHooks(hook))
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	for range ch {
+	}
+
+	if len(hook.infos) != 1 || hook.infos[0] != (RequestInfo{Provider: "gemini", Model: "gemini-2.0-flash", Operation: OperationStream}) {
+		t.Fatalf("infos = %+v, want one gemini stream request", hook.infos)
+	}
+	span := hook.spans[0]
+	if span.firstTokens != 1 {
+		t.Errorf("FirstToken called %d times, want 1", span.firstTokens)
+	}
+	if len(span.ends) != 1 {
+		t.Fatalf("End called %d times, want 1", len(span.ends))
+	}
+	res := span.ends[0]
+	if res.Chunks != 3 || res.Err != nil || res.Usage == nil || res.Usage.TotalTokens != 9 {
+		t.Errorf("result = %+v, want 3 chunks, usage 9 and no error", res)
+	}
+}
+
+func TestHooks_RegisteredHookSeesErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	hook := &recordingHook{}
+	RegisterHook(hook)
+	defer ResetHooks()
+
+	gen := &OllamaGenerator{httpClient: server.Client(), baseURL: server.URL, model: "llama3.2"}
+
+	if _, err := gen.Generate(context.Background(), "hi"); err == nil {
+		t.Fatal("expected Generate error")
+	}
+	if _, err := gen.Stream(context.Background(), "hi"); err == nil {
+		t.Fatal("expected Stream error")
+	}
+
+	if len(hook.spans) != 2 {
+		t.Fatalf("spans = %d, want 2", len(hook.spans))
+	}
+	for i, span := range hook.spans {
+		if len(span.ends) != 1 || span.ends[0].Err == nil {
+			t.Errorf("span %d ends = %+v, want one failed result", i, span.ends)
+		}
+	}
+	if hook.infos[0].Operation != OperationGenerate || hook.infos[1].Operation != OperationStream {
+		t.Errorf("operations = %q, %q", hook.infos[0].Operation, hook.infos[1].Operation)
+	}
+}
+
+func TestRegisterHook_NilPanics(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("expected panic for nil hook, got none")
+		}
+	}()
+	RegisterHook(nil)
+}
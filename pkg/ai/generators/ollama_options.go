@@ -0,0 +1,227 @@
+package generators
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Option names reported for the Ollama-specific settings.
+const (
+	OptionOllamaKeepAlive     = "ollama.keep_alive"
+	OptionOllamaNumCtx        = "ollama.num_ctx"
+	OptionOllamaNumGPU        = "ollama.num_gpu"
+	OptionOllamaRaw           = "ollama.raw"
+	OptionOllamaMirostat      = "ollama.mirostat"
+	OptionOllamaMirostatEta   = "ollama.mirostat_eta"
+	OptionOllamaMirostatTau   = "ollama.mirostat_tau"
+	OptionOllamaRepeatLastN   = "ollama.repeat_last_n"
+	OptionOllamaRepeatPenalty = "ollama.repeat_penalty"
+	OptionOllamaTemplate      = "ollama.template"
+)
+
+// OllamaConfig holds Ollama-specific runtime settings. Nil pointers and an
+// empty Template mean "use the server's default".
+//
+// The settings can be given per request with the WithOllama* options or as
+// defaults for every request in the query string of an ollama:// URL:
+//
+//	ollama://localhost:11434/llama3.2?num_ctx=8192&keep_alive=30m
+//
+// Per-request settings take precedence over URL defaults.
+type OllamaConfig struct {
+	KeepAlive     *time.Duration
+	NumCtx        *int
+	NumGPU        *int
+	Raw           *bool
+	Mirostat      *int
+	MirostatEta   *float32
+	MirostatTau   *float32
+	RepeatLastN   *int
+	RepeatPenalty *float32
+	Template      string
+}
+
+// WithOllamaKeepAlive sets how long the model stays loaded after the
+// request. A negative duration keeps it loaded indefinitely and zero
+// unloads it immediately.
+func WithOllamaKeepAlive(d time.Duration) Option {
+	return func(c *Config) { c.Ollama.KeepAlive = &d }
+}
+
+// WithOllamaNumCtx sets the size of the context window in tokens.
+func WithOllamaNumCtx(n int) Option {
+	return func(c *Config) { c.Ollama.NumCtx = &n }
+}
+
+// WithOllamaNumGPU sets the number of model layers offloaded to the GPU.
+func WithOllamaNumGPU(n int) Option {
+	return func(c *Config) { c.Ollama.NumGPU = &n }
+}
+
+// WithOllamaRaw disables prompt templating so the prompt is sent to the
+// model verbatim.
+func WithOllamaRaw(raw bool) Option {
+	return func(c *Config) { c.Ollama.Raw = &raw }
+}
+
+// WithOllamaMirostat enables Mirostat sampling: 0 disables it, 1 selects
+// Mirostat and 2 selects Mirostat 2.0.
+func WithOllamaMirostat(mode int) Option {
+	return func(c *Config) { c.Ollama.Mirostat = &mode }
+}
+
+// WithOllamaMirostatEta sets the Mirostat learning rate.
+func WithOllamaMirostatEta(eta float32) Option {
+	return func(c *Config) { c.Ollama.MirostatEta = &eta }
+}
+
+// WithOllamaMirostatTau sets the Mirostat target entropy.
+func WithOllamaMirostatTau(tau float32) Option {
+	return func(c *Config) { c.Ollama.MirostatTau = &tau }
+}
+
+// WithOllamaRepeatLastN sets how far back the model looks to penalize
+// repetition (0 disables, -1 uses the whole context).
+func WithOllamaRepeatLastN(n int) Option {
+	return func(c *Config) { c.Ollama.RepeatLastN = &n }
+}
+
+// WithOllamaRepeatPenalty sets the strength of the repetition penalty.
+func WithOllamaRepeatPenalty(p float32) Option {
+	return func(c *Config) { c.Ollama.RepeatPenalty = &p }
+}
+
+// WithOllamaTemplate overrides the prompt template defined in the Modelfile.
+func WithOllamaTemplate(tmpl string) Option {
+	return func(c *Config) { c.Ollama.Template = tmpl }
+}
+
+// merge returns a copy of c with the settings of override applied on top.
+func (c OllamaConfig) merge(override OllamaConfig) OllamaConfig {
+	if override.KeepAlive != nil {
+		c.KeepAlive = override.KeepAlive
+	}
+	if override.NumCtx != nil {
+		c.NumCtx = override.NumCtx
+	}
+	if override.NumGPU != nil {
+		c.NumGPU = override.NumGPU
+	}
+	if override.Raw != nil {
+		c.Raw = override.Raw
+	}
+	if override.Mirostat != nil {
+		c.Mirostat = override.Mirostat
+	}
+	if override.MirostatEta != nil {
+		c.MirostatEta = override.MirostatEta
+	}
+	if override.MirostatTau != nil {
+		c.MirostatTau = override.MirostatTau
+	}
+	if override.RepeatLastN != nil {
+		c.RepeatLastN = override.RepeatLastN
+	}
+	if override.RepeatPenalty != nil {
+		c.RepeatPenalty = override.RepeatPenalty
+	}
+	if override.Template != "" {
+		c.Template = override.Template
+	}
+	return c
+}
+
+// setOptions returns the names of the Ollama settings that are set.
+func (c OllamaConfig) setOptions() []string {
+	var names []string
+	add := func(set bool, name string) {
+		if set {
+			names = append(names, name)
+		}
+	}
+	add(c.KeepAlive != nil, OptionOllamaKeepAlive)
+	add(c.NumCtx != nil, OptionOllamaNumCtx)
+	add(c.NumGPU != nil, OptionOllamaNumGPU)
+	add(c.Raw != nil, OptionOllamaRaw)
+	add(c.Mirostat != nil, OptionOllamaMirostat)
+	add(c.MirostatEta != nil, OptionOllamaMirostatEta)
+	add(c.MirostatTau != nil, OptionOllamaMirostatTau)
+	add(c.RepeatLastN != nil, OptionOllamaRepeatLastN)
+	add(c.RepeatPenalty != nil, OptionOllamaRepeatPenalty)
+	add(c.Template != "", OptionOllamaTemplate)
+	return names
+}
+
+// parseOllamaQuery parses Ollama settings from the query string of an
+// ollama:// URL. Unknown parameters are rejected to surface typos early.
+func parseOllamaQuery(q url.Values) (OllamaConfig, error) {
+	var c OllamaConfig
+	for key, values := range q {
+		if len(values) == 0 {
+			continue
+		}
+		v := values[len(values)-1]
+		var err error
+		switch key {
+		case "keep_alive":
+			var d time.Duration
+			d, err = parseKeepAlive(v)
+			c.KeepAlive = &d
+		case "num_ctx":
+			c.NumCtx, err = parseIntParam(v)
+		case "num_gpu":
+			c.NumGPU, err = parseIntParam(v)
+		case "raw":
+			var b bool
+			b, err = strconv.ParseBool(v)
+			c.Raw = &b
+		case "mirostat":
+			c.Mirostat, err = parseIntParam(v)
+		case "mirostat_eta":
+			c.MirostatEta, err = parseFloat32Param(v)
+		case "mirostat_tau":
+			c.MirostatTau, err = parseFloat32Param(v)
+		case "repeat_last_n":
+			c.RepeatLastN, err = parseIntParam(v)
+		case "repeat_penalty":
+			c.RepeatPenalty, err = parseFloat32Param(v)
+		case "template":
+			c.Template = v
+		default:
+			return OllamaConfig{}, fmt.Errorf("generators: unknown ollama URL parameter %q", key)
+		}
+		if err != nil {
+			return OllamaConfig{}, fmt.Errorf("generators: invalid ollama URL parameter %s=%q: %w", key, v, err)
+		}
+	}
+	return c, nil
+}
+
+// parseKeepAlive parses a keep_alive value given either as a Go duration
+// ("10m", "-1s") or, as Ollama also accepts, a number of seconds ("600", "-1").
+func parseKeepAlive(v string) (time.Duration, error) {
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second, nil
+	}
+	return time.ParseDuration(v)
+}
+
+// parseIntParam parses an integer URL parameter.
+func parseIntParam(v string) (*int, error) {
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// parseFloat32Param parses a float32 URL parameter.
+func parseFloat32Param(v string) (*float32, error) {
+	f, err := strconv.ParseFloat(v, 32)
+	if err != nil {
+		return nil, err
+	}
+	return ptrFloat32(float32(f)), nil
+}
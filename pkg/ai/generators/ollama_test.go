@@ -9,6 +9,7 @@
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 )
 
 // --- Opener tests ---
@@ -119,6 +120,64 @@ func TestOllamaOpener_Open_WithUserinfo(t *testing.T) {
 	}
 }
 
+func TestOllamaOpener_Open_QueryParameters(t *testing.T) {
+	op := &OllamaOpener{}
+
+	testCases := []struct {
+		name    string
+		rawurl  string
+		wantErr bool
+		check   func(t *testing.T, c OllamaConfig)
+	}{
+		{
+			name:   "runtime settings",
+			rawurl: "ollama://localhost:11434/llama3.2?num_ctx=8192&keep_alive=30m&raw=true&mirostat=2&mirostat_tau=5.5&repeat_penalty=1.1",
+			check: func(t *testing.T, c OllamaConfig) {
+				if c.NumCtx == nil || *c.NumCtx != 8192 {
+					t.Errorf("NumCtx = %v, want 8192", c.NumCtx)
+				}
+				if c.KeepAlive == nil || *c.KeepAlive != 30*time.Minute {
+					t.Errorf("KeepAlive = %v, want 30m", c.KeepAlive)
+				}
+				if c.Raw == nil || !*c.Raw {
+					t.Errorf("Raw = %v, want true", c.Raw)
+				}
+				if c.Mirostat == nil || *c.Mirostat != 2 || c.MirostatTau == nil || *c.MirostatTau != 5.5 {
+					t.Errorf("Mirostat = %v/%v, want 2/5.5", c.Mirostat, c.MirostatTau)
+				}
+			},
+		},
+		{
+			name:   "keep_alive in seconds",
+			rawurl: "ollama:///llama3.2?keep_alive=-1",
+			check: func(t *testing.T, c OllamaConfig) {
+				if c.KeepAlive == nil || *c.KeepAlive != -time.Second {
+					t.Errorf("KeepAlive = %v, want -1s", c.KeepAlive)
+				}
+			},
+		},
+		{name: "invalid value", rawurl: "ollama:///llama3.2?num_ctx=big", wantErr: true},
+		{name: "unknown parameter", rawurl: "ollama:///llama3.2?num_cxt=8192", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			u, _ := url.Parse(tc.rawurl)
+			gen, err := op.Open(context.Background(), u)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tc.check(t, gen.(*OllamaGenerator).defaults)
+		})
+	}
+}
+
 // --- Request/response mapping tests ---
 
 func TestOllamaBuildRequest(t *testing.T) {
@@ -136,14 +195,14 @@ func TestOllamaBuildRequest(t *testing.T) {
 	})
 
 	t.Run("full config sets all fields", func(t *testing.T) {
-		cfg := &Config{
-			Temperature:       0.8,
-			MaxOutputTokens:   256,
-			TopP:              0.9,
-			TopK:              40,
-			SystemInstruction: "You are a helpful assistant.",
-			StopSequences:     []string{"END", "STOP"},
-		}
+		cfg := newConfig([]Option{
+			WithTemperature(0.8),
+			WithMaxOutputTokens(256),
+			WithTopP(0.9),
+			WithTopK(40),
+			WithSystemInstruction("You are a helpful assistant."),
+			WithStopSequences("END", "STOP"),
+		})
 		req := g.buildRequest(cfg, "test prompt", true)
 
 		if req.Stream != true {
@@ -152,8 +211,8 @@ func TestOllamaBuildRequest(t *testing.T) {
 		if *req.Options.Temperature != 0.8 {
 			t.Errorf("Temperature = %v, want 0.8", *req.Options.Temperature)
 		}
-		if req.Options.NumPredict != 256 {
-			t.Errorf("NumPredict = %d, want 256", req.Options.NumPredict)
+		if *req.Options.NumPredict != 256 {
+			t.Errorf("NumPredict = %d, want 256", *req.Options.NumPredict)
 		}
 		if req.System != "You are a helpful assistant." {
 			t.Errorf("System = %q, want %q", req.System, "You are a helpful assistant.")
@@ -161,7 +220,7 @@ func TestOllamaBuildRequest(t *testing.T) {
 	})
 
 	t.Run("config serializes to valid JSON", func(t *testing.T) {
-		cfg := &Config{Temperature: 0.5, MaxOutputTokens: 100}
+		cfg := newConfig([]Option{WithTemperature(0.5), WithMaxOutputTokens(100)})
 		req := g.buildRequest(cfg, "test", false)
 		data, err := json.Marshal(req)
 		if err != nil {
@@ -174,6 +233,87 @@ func TestOllamaBuildRequest(t *testing.T) {
 			t.Error("JSON should contain num_predict")
 		}
 	})
+
+	t.Run("wire JSON", func(t *testing.T) {
+		testCases := []struct {
+			name string
+			opts []Option
+			want string
+		}{
+			{
+				name: "no options omits options object",
+				want: `{"model":"llama3.2","prompt":"test","stream":false}`,
+			},
+			{
+				name: "explicit zero values are sent",
+				opts: []Option{WithTemperature(0), WithTopP(0), WithTopK(0), WithMaxOutputTokens(0)},
+				want: `{"model":"llama3.2","prompt":"test","stream":false,"options":{"temperature":0,"num_predict":0,"top_p":0,"top_k":0}}`,
+			},
+			{
+				name: "only set fields are sent",
+				opts: []Option{WithTemperature(0), WithStopSequences("END")},
+				want: `{"model":"llama3.2","prompt":"test","stream":false,"options":{"temperature":0,"stop":["END"]}}`,
+			},
+			{
+				name: "extended sampling options",
+				opts: []Option{WithSeed(42), WithPresencePenalty(0.5), WithFrequencyPenalty(0), WithResponseLogprobs(3)},
+				want: `{"model":"llama3.2","prompt":"test","stream":false,"options":{"seed":42,"presence_penalty":0.5,"frequency_penalty":0},"logprobs":true,"top_logprobs":3}`,
+			},
+			{
+				name: "JSON output",
+				opts: []Option{WithJSONOutput()},
+				want: `{"model":"llama3.2","prompt":"test","stream":false,"format":"json"}`,
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				data, err := json.Marshal(g.buildRequest(newConfig(tc.opts), "test", false))
+				if err != nil {
+					t.Fatalf("marshal: %v", err)
+				}
+				if string(data) != tc.want {
+					t.Errorf("JSON = %s, want %s", data, tc.want)
+				}
+			})
+		}
+	})
+}
+
+func TestOllamaBuildRequest_RuntimeSettings(t *testing.T) {
+	g := &OllamaGenerator{model: "llama3.2", defaults: OllamaConfig{
+		NumCtx:    ptrInt(4096),
+		KeepAlive: func() *time.Duration { d := 10 * time.Minute; return &d }(),
+		Template:  "{{ .Prompt }}",
+	}}
+
+	cfg := newConfig([]Option{
+		WithOllamaNumCtx(8192),
+		WithOllamaNumGPU(0),
+		WithOllamaRaw(false),
+		WithOllamaMirostat(1),
+		WithOllamaMirostatEta(0.1),
+		WithOllamaRepeatLastN(64),
+		WithOllamaRepeatPenalty(1.2),
+	})
+	data, err := json.Marshal(g.buildRequest(cfg, "test", false))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	want := `{"model":"llama3.2","prompt":"test","stream":false,` +
+		`"options":{"num_ctx":8192,"num_gpu":0,"mirostat":1,"mirostat_eta":0.1,"repeat_last_n":64,"repeat_penalty":1.2},` +
+		`"keep_alive":"10m0s","raw":false,"template":"{{ .Prompt }}"}`
+	if string(data) != want {
+		t.Errorf("JSON = %s, want %s", data, want)
+	}
+
+	if caps := g.Capabilities(); caps.MaxContextTokens != 4096 {
+		t.Errorf("MaxContextTokens = %d, want num_ctx from URL", caps.MaxContextTokens)
+	}
+	if err := newConfig([]Option{WithStrict(), WithOllamaNumCtx(1)}).checkStrict((&GeminiGenerator{}).Capabilities(), "gemini"); err == nil {
+		t.Error("Ollama settings should be rejected by Gemini in strict mode")
+	}
 }
 
 func TestOllamaMapResponse(t *testing.T) {
@@ -193,6 +333,24 @@ func TestOllamaMapResponse(t *testing.T) {
 		}
 	})
 
+	t.Run("logprobs map to the single candidate", func(t *testing.T) {
+		ollResp := &ollamaResponse{
+			Response: "Hi", Done: true, DoneReason: "stop",
+			Logprobs: []ollamaLogprob{{
+				Token: "Hi", Logprob: -0.2,
+				TopLogprobs: []ollamaLogprob{{Token: "Hi", Logprob: -0.2}, {Token: "Hey", Logprob: -1.9}},
+			}},
+		}
+		resp := g.mapResponse(ollResp, "llama3.2")
+		if len(resp.Candidates) != 1 || resp.Candidates[0].Text != "Hi" {
+			t.Fatalf("Candidates = %+v, want one candidate with text Hi", resp.Candidates)
+		}
+		lps := resp.Candidates[0].Logprobs
+		if len(lps) != 1 || lps[0].Logprob != -0.2 || len(lps[0].TopLogprobs) != 2 {
+			t.Errorf("Logprobs = %+v, want one token with two alternatives", lps)
+		}
+	})
+
 	t.Run("empty response", func(t *testing.T) {
 		ollResp := &ollamaResponse{Done: true}
 		resp := g.mapResponse(ollResp, "model")
@@ -258,6 +416,7 @@ func TestOllamaStream_HTTPTestServer(t *testing.T) {
 			resp := ollamaResponse{Model: "llama3.2", Response: chunk, Done: i == len(chunks)-1}
 			if resp.Done {
 				resp.DoneReason = "stop"
+				resp.PromptEvalCount, resp.EvalCount = 4, 3
 			}
 			data, _ := json.Marshal(resp)
 			fmt.Fprintf(w, "%s\n", data)
@@ -274,16 +433,29 @@ func TestOllamaStream_HTTPTestServer(t *testing.T) {
 		t.Fatalf("Stream() error = %v", err)
 	}
 
-	var collected string
+	var collected, reason string
+	var usage *Usage
 	for chunk := range ch {
 		if chunk.Error != nil {
 			t.Fatalf("Stream chunk error: %v", chunk.Error)
 		}
 		collected += chunk.Text
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		if chunk.FinishReason != "" {
+			reason = chunk.FinishReason
+		}
 	}
 	if collected != "Hello world!" {
 		t.Errorf("collected = %q, want %q", collected, "Hello world!")
 	}
+	if usage == nil || usage.TotalTokens != 7 {
+		t.Errorf("usage = %+v, want TotalTokens 7", usage)
+	}
+	if reason != "stop" {
+		t.Errorf("finish reason = %q, want stop", reason)
+	}
 }
 
 func TestOllamaGenerate_APIError(t *testing.T) {
@@ -305,6 +477,38 @@ func TestOllamaGenerate_APIError(t *testing.T) {
 	}
 }
 
+func TestOllamaEmbed_HTTPTestServer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embed" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		var req ollamaEmbedRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Model != "nomic-embed-text" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		resp := ollamaEmbedResponse{}
+		for i := range req.Input {
+			resp.Embeddings = append(resp.Embeddings, []float32{float32(i), 1})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	gen := &OllamaGenerator{
+		httpClient: server.Client(), baseURL: server.URL, model: "nomic-embed-text",
+	}
+
+	vectors, err := gen.Embed(context.Background(), []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+	if len(vectors) != 2 || vectors[1][0] != 1 {
+		t.Errorf("vectors = %v, want two vectors in input order", vectors)
+	}
+}
+
 // --- Live integration test ---
 
 func TestOllamaGenerate_Integration(t *testing.T) {
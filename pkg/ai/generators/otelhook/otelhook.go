@@ -0,0 +1,98 @@
+// Package otelhook adapts generator hooks to OpenTelemetry tracing.
+// It is a separate module so that neither the generators package nor the
+// root module depends on OpenTelemetry.
+//
+// Attribute names follow the OpenTelemetry semantic conventions for
+// generative AI systems.
+package otelhook
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/tnotstar/go-minolas/pkg/ai/generators"
+)
+
+// instrumentationName identifies this package as the tracer's instrumentation scope.
+const instrumentationName = "github.com/tnotstar/go-minolas/pkg/ai/generators"
+
+// Hook implements generators.Hook by recording each request as an
+// OpenTelemetry span.
+type Hook struct {
+	tracer trace.Tracer
+}
+
+// New creates a Hook that creates spans using the given tracer provider.
+//
+// Example:
+//
+//	generators.RegisterHook(otelhook.New(otel.GetTracerProvider()))
+func New(tp trace.TracerProvider) *Hook {
+	return &Hook{tracer: tp.Tracer(instrumentationName)}
+}
+
+// Start starts a client span for the request and returns a context carrying it.
+func (h *Hook) Start(ctx context.Context, info generators.RequestInfo) (context.Context, generators.Span) {
+	ctx, span := h.tracer.Start(ctx, strings.TrimSpace(info.Operation+" "+info.Model),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("gen_ai.system", info.Provider),
+			attribute.String("gen_ai.operation.name", info.Operation),
+			attribute.String("gen_ai.request.model", info.Model),
+		),
+	)
+	return ctx, &otelSpan{span: span}
+}
+
+// otelSpan implements generators.Span on top of an OpenTelemetry span.
+type otelSpan struct {
+	span trace.Span
+}
+
+// FirstToken records the time to first token as a span event.
+func (s *otelSpan) FirstToken(latency time.Duration) {
+	s.span.AddEvent("gen_ai.first_token", trace.WithAttributes(
+		attribute.Float64("gen_ai.first_token.latency_ms", float64(latency)/float64(time.Millisecond)),
+	))
+}
+
+// Retry records a retried attempt as a span event.
+func (s *otelSpan) Retry(attempt int, err error) {
+	attrs := []attribute.KeyValue{attribute.Int("gen_ai.retry.attempt", attempt)}
+	if err != nil {
+		attrs = append(attrs, attribute.String("error.message", err.Error()))
+	}
+	s.span.AddEvent("gen_ai.retry", trace.WithAttributes(attrs...))
+}
+
+// End records the result attributes and ends the span.
+func (s *otelSpan) End(result generators.RequestResult) {
+	if result.Chunks > 0 {
+		s.span.SetAttributes(attribute.Int("gen_ai.stream.chunks", result.Chunks))
+	}
+	if result.FinishReason != "" {
+		s.span.SetAttributes(attribute.StringSlice("gen_ai.response.finish_reasons", []string{result.FinishReason}))
+	}
+	if result.Usage != nil {
+		s.span.SetAttributes(
+			attribute.Int("gen_ai.usage.input_tokens", result.Usage.PromptTokens),
+			attribute.Int("gen_ai.usage.output_tokens", result.Usage.CompletionTokens),
+		)
+		if result.Usage.ThoughtTokens > 0 {
+			s.span.SetAttributes(attribute.Int("gen_ai.usage.reasoning_tokens", result.Usage.ThoughtTokens))
+		}
+		if result.Usage.CachedTokens > 0 {
+			s.span.SetAttributes(attribute.Int("gen_ai.usage.cached_tokens", result.Usage.CachedTokens))
+		}
+	}
+	if result.Err != nil {
+		s.span.RecordError(result.Err)
+		s.span.SetStatus(codes.Error, result.Err.Error())
+	}
+	s.span.End()
+}
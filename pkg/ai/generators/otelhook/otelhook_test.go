@@ -0,0 +1,89 @@
+package otelhook
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/tnotstar/go-minolas/pkg/ai/generators"
+)
+
+func newRecorder() (*tracetest.SpanRecorder, *Hook) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	return rec, New(tp)
+}
+
+func attrMap(attrs []attribute.KeyValue) map[attribute.Key]attribute.Value {
+	m := make(map[attribute.Key]attribute.Value, len(attrs))
+	for _, kv := range attrs {
+		m[kv.Key] = kv.Value
+	}
+	return m
+}
+
+func TestHook_SuccessfulStream(t *testing.T) {
+	rec, hook := newRecorder()
+
+	ctx, span := hook.Start(context.Background(), generators.RequestInfo{
+		Provider: "gemini", Model: "gemini-2.0-flash", Operation: generators.OperationStream,
+	})
+	if ctx == context.Background() {
+		t.Error("Start should return a context carrying the span")
+	}
+	span.FirstToken(150 * time.Millisecond)
+	span.End(generators.RequestResult{
+		Chunks: 3,
+		Usage:  &generators.Usage{PromptTokens: 4, CompletionTokens: 6, TotalTokens: 10},
+	})
+
+	spans := rec.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("ended spans = %d, want 1", len(spans))
+	}
+	s := spans[0]
+	if s.Name() != "stream gemini-2.0-flash" {
+		t.Errorf("Name = %q", s.Name())
+	}
+	attrs := attrMap(s.Attributes())
+	if attrs["gen_ai.system"].AsString() != "gemini" {
+		t.Errorf("gen_ai.system = %v", attrs["gen_ai.system"])
+	}
+	if attrs["gen_ai.usage.output_tokens"].AsInt64() != 6 || attrs["gen_ai.stream.chunks"].AsInt64() != 3 {
+		t.Errorf("attributes = %v", attrs)
+	}
+	if len(s.Events()) != 1 || s.Events()[0].Name != "gen_ai.first_token" {
+		t.Errorf("events = %v, want first token event", s.Events())
+	}
+	if s.Status().Code != codes.Unset {
+		t.Errorf("Status = %v, want unset", s.Status())
+	}
+}
+
+func TestHook_FailedRequest(t *testing.T) {
+	rec, hook := newRecorder()
+
+	_, span := hook.Start(context.Background(), generators.RequestInfo{
+		Provider: "ollama", Model: "llama3.2", Operation: generators.OperationGenerate,
+	})
+	span.Retry(1, errors.New("timeout"))
+	span.End(generators.RequestResult{Err: errors.New("boom")})
+
+	s := rec.Ended()[0]
+	if s.Status().Code != codes.Error || s.Status().Description != "boom" {
+		t.Errorf("Status = %v, want error boom", s.Status())
+	}
+	var names []string
+	for _, e := range s.Events() {
+		names = append(names, e.Name)
+	}
+	if len(names) != 2 || names[0] != "gen_ai.retry" || names[1] != "exception" {
+		t.Errorf("events = %v, want retry and exception", names)
+	}
+}
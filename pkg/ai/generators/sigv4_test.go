@@ -0,0 +1,108 @@
+package generators
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignAWSRequest_TestSuite(t *testing.T) {
+	// The get-vanilla case of the AWS Signature Version 4 test suite.
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	creds := awsCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	if err := signAWSRequest(req, nil, creds, "us-east-1", "service", now); err != nil {
+		t.Fatal(err)
+	}
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization =\n%s\nwant\n%s", got, want)
+	}
+	if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+		t.Errorf("X-Amz-Date = %q", got)
+	}
+}
+
+func TestSignAWSRequest_SessionToken(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "https://bedrock-runtime.us-east-1.amazonaws.com/model/a%3A0/converse", nil)
+	req.Header.Set("Content-Type", "application/json")
+	creds := awsCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "session"}
+	if err := signAWSRequest(req, []byte("{}"), creds, "us-east-1", "bedrock", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if req.Header.Get("X-Amz-Security-Token") != "session" {
+		t.Error("session token not sent")
+	}
+	if auth := req.Header.Get("Authorization"); !strings.Contains(auth, "SignedHeaders=content-type;host;x-amz-date;x-amz-security-token,") {
+		t.Errorf("Authorization = %q", auth)
+	}
+	if err := signAWSRequest(req, nil, awsCredentials{}, "us-east-1", "bedrock", time.Now()); err == nil {
+		t.Error("signing without credentials succeeded")
+	}
+}
+
+func TestAWSCanonicalPath(t *testing.T) {
+	for escaped, want := range map[string]string{
+		"":               "/",
+		"/":              "/",
+		"/a%20b/~x_y.z-": "/a%2520b/~x_y.z-",
+		"/model/anthropic.claude-v2%3A1/converse": "/model/anthropic.claude-v2%253A1/converse",
+	} {
+		if got := awsCanonicalPath(escaped); got != want {
+			t.Errorf("awsCanonicalPath(%q) = %q, want %q", escaped, got, want)
+		}
+	}
+}
+
+func TestLoadAWSCredentials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials")
+	os.WriteFile(path, []byte(`
+# comment
+[default]
+aws_access_key_id = AKIDDEFAULT
+aws_secret_access_key = default-secret
+
+[prod]
+aws_access_key_id=AKIDPROD
+aws_secret_access_key=prod-secret
+aws_session_token=prod-token
+
+[empty]
+region = eu-west-1
+`), 0o600)
+	t.Setenv(envAWSSharedCredentialsFile, path)
+	t.Setenv(envAWSAccessKeyID, "AKIDENV")
+	t.Setenv(envAWSSecretAccessKey, "env-secret")
+	t.Setenv(envAWSSessionToken, "")
+	t.Setenv(envAWSProfile, "")
+
+	tests := []struct {
+		profile string
+		want    string
+		wantErr bool
+	}{
+		{"", "AKIDENV", false},
+		{"prod", "AKIDPROD", false},
+		{"missing", "", true},
+		{"empty", "", true},
+	}
+	for _, tt := range tests {
+		creds, err := loadAWSCredentials(tt.profile)
+		if (err != nil) != tt.wantErr || creds.AccessKeyID != tt.want {
+			t.Errorf("loadAWSCredentials(%q) = %+v, %v", tt.profile, creds, err)
+		}
+	}
+
+	t.Setenv(envAWSAccessKeyID, "")
+	if creds, err := loadAWSCredentials(""); err != nil || creds.AccessKeyID != "AKIDDEFAULT" {
+		t.Errorf("default profile = %+v, %v", creds, err)
+	}
+	t.Setenv(envAWSProfile, "prod")
+	if creds, err := loadAWSCredentials(""); err != nil || creds.SessionToken != "prod-token" {
+		t.Errorf("AWS_PROFILE = %+v, %v", creds, err)
+	}
+}
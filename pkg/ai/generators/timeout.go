@@ -0,0 +1,214 @@
+package generators
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrTimeout is matched by errors.Is for every TimeoutError.
+var ErrTimeout = errors.New("generators: timeout")
+
+// Timeout kinds reported in TimeoutError.Kind.
+const (
+	TimeoutRequest    = "request"
+	TimeoutFirstToken = "first_token"
+	TimeoutIdle       = "idle"
+)
+
+// TimeoutError is returned by Generate, or sent in StreamChunk.Error, when
+// one of the timeouts set with WithTimeout, WithFirstTokenTimeout or
+// WithIdleTimeout expires. Kind tells which one, and After is its duration.
+type TimeoutError struct {
+	Provider string
+	Kind     string
+	After    time.Duration
+}
+
+// Error implements the error interface.
+func (e *TimeoutError) Error() string {
+	var what string
+	switch e.Kind {
+	case TimeoutFirstToken:
+		what = "no first token"
+	case TimeoutIdle:
+		what = "stream idle"
+	default:
+		what = "request timed out"
+	}
+	return fmt.Sprintf("generators: %s %s after %s", e.Provider, what, e.After)
+}
+
+// Is reports whether target is ErrTimeout.
+func (e *TimeoutError) Is(target error) bool {
+	return target == ErrTimeout
+}
+
+// IsTimeout reports whether err was caused by an expired request,
+// first-token or idle timeout.
+func IsTimeout(err error) bool {
+	return errors.Is(err, ErrTimeout)
+}
+
+// WithTimeout limits the total duration of a request. For Stream it covers
+// the whole stream, up to the last chunk.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Config) { c.Timeout = d }
+}
+
+// WithFirstTokenTimeout limits the time until the first text or thought
+// chunk of a stream arrives. For Generate, which receives the answer in one
+// piece, it limits the time until the provider starts responding.
+func WithFirstTokenTimeout(d time.Duration) Option {
+	return func(c *Config) { c.FirstTokenTimeout = d }
+}
+
+// WithIdleTimeout limits the time the provider may stay silent while a
+// response is being read: between the chunks of a stream, and before the
+// first one when no first-token timeout is set. Time spent waiting for the
+// caller to receive a chunk does not count.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(c *Config) { c.IdleTimeout = d }
+}
+
+// watchdog enforces the timeouts of a request by cancelling its context
+// with a TimeoutError as the cause. Reads from the response body count as
+// activity for the idle timeout.
+type watchdog struct {
+	provider   string
+	firstToken time.Duration
+	idle       time.Duration
+	cancel     context.CancelCauseFunc
+	stopTotal  context.CancelFunc
+
+	mu       sync.Mutex
+	timer    *time.Timer
+	gotFirst bool
+	paused   bool
+}
+
+// startWatchdog returns a context bounded by the timeouts in cfg and the
+// watchdog that enforces them. The caller must call stop when the request
+// is over.
+func startWatchdog(ctx context.Context, cfg *Config, provider string) (context.Context, *watchdog) {
+	w := &watchdog{
+		provider:   provider,
+		firstToken: cfg.FirstTokenTimeout,
+		idle:       cfg.IdleTimeout,
+		stopTotal:  func() {},
+	}
+	if cfg.Timeout > 0 {
+		ctx, w.stopTotal = context.WithTimeoutCause(ctx, cfg.Timeout,
+			&TimeoutError{Provider: provider, Kind: TimeoutRequest, After: cfg.Timeout})
+	}
+	ctx, w.cancel = context.WithCancelCause(ctx)
+	w.mu.Lock()
+	w.arm()
+	w.mu.Unlock()
+	return ctx, w
+}
+
+// arm restarts the timer for the current phase. w.mu must be held.
+func (w *watchdog) arm() {
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	kind, d := TimeoutIdle, w.idle
+	if !w.gotFirst && w.firstToken > 0 {
+		kind, d = TimeoutFirstToken, w.firstToken
+	}
+	if d <= 0 || w.paused {
+		return
+	}
+	cause := &TimeoutError{Provider: w.provider, Kind: kind, After: d}
+	w.timer = time.AfterFunc(d, func() { w.cancel(cause) })
+}
+
+// activity records data received from the provider. It restarts the idle
+// timer, but not the first-token one.
+func (w *watchdog) activity() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.gotFirst || w.firstToken <= 0 {
+		w.arm()
+	}
+}
+
+// first records the arrival of the first token and switches to the idle
+// timeout.
+func (w *watchdog) first() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.gotFirst {
+		w.gotFirst = true
+		w.arm()
+	}
+}
+
+// setPaused stops the timers while a chunk waits to be received by the
+// caller, and restarts them afterwards.
+func (w *watchdog) setPaused(paused bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.paused = paused
+	w.arm()
+}
+
+// stop releases the watchdog and its context.
+func (w *watchdog) stop() {
+	w.mu.Lock()
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.paused = true
+	w.mu.Unlock()
+	w.cancel(nil)
+	w.stopTotal()
+}
+
+// reader returns r reporting every successful read as activity.
+func (w *watchdog) reader(r io.Reader) io.Reader {
+	return &watchedReader{r: r, w: w}
+}
+
+// sender wraps send so that the first text or thought chunk ends the
+// first-token phase and time spent blocked in send is not counted.
+func (w *watchdog) sender(send func(StreamChunk)) func(StreamChunk) {
+	return func(chunk StreamChunk) {
+		if chunk.Text != "" || chunk.Thought != "" {
+			w.first()
+		}
+		w.setPaused(true)
+		send(chunk)
+		w.setPaused(false)
+	}
+}
+
+// watchedReader is the reader returned by watchdog.reader.
+type watchedReader struct {
+	r io.Reader
+	w *watchdog
+}
+
+func (r *watchedReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.w.activity()
+	}
+	return n, err
+}
+
+// timeoutCause returns err, or the TimeoutError that cancelled ctx if one
+// did, so that callers see which timeout expired instead of a context or
+// read error.
+func timeoutCause(ctx context.Context, err error) error {
+	var te *TimeoutError
+	if ctx.Err() != nil && errors.As(context.Cause(ctx), &te) {
+		return te
+	}
+	return err
+}
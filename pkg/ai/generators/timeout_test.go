@@ -0,0 +1,161 @@
+package generators
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// stallingServer writes the given lines and then stays silent until the
+// test ends.
+func stallingServer(t *testing.T, lines ...string) *httptest.Server {
+	t.Helper()
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		for _, line := range lines {
+			fmt.Fprintln(w, line)
+		}
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+	return server
+}
+
+// lastError drains ch and returns the text received and the error of the
+// last chunk.
+func lastError(t *testing.T, ch <-chan StreamChunk) (string, error) {
+	t.Helper()
+	var text string
+	var err error
+	done := time.After(5 * time.Second)
+	for {
+		select {
+		case chunk, ok := <-ch:
+			if !ok {
+				return text, err
+			}
+			text += chunk.Text
+			if chunk.Error != nil {
+				err = chunk.Error
+			}
+		case <-done:
+			t.Fatal("stream did not end")
+		}
+	}
+}
+
+func TestGeminiStream_IdleTimeout(t *testing.T) {
+	server := stallingServer(t, `data: {"candidates":[{"content":{"parts":[{"text":"Hello"}]}}]}`, "")
+	gen := &GeminiGenerator{httpClient: server.Client(), model: "gemini-2.0-flash", baseURL: server.URL}
+
+	ch, err := gen.Stream(context.Background(), "hello", WithIdleTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	text, err := lastError(t, ch)
+	if text != "Hello" {
+		t.Errorf("text = %q, want %q", text, "Hello")
+	}
+	var te *TimeoutError
+	if !errors.As(err, &te) || te.Kind != TimeoutIdle || te.Provider != "gemini" {
+		t.Fatalf("error = %v, want idle TimeoutError", err)
+	}
+	if !IsTimeout(err) || !errors.Is(err, ErrTimeout) {
+		t.Error("IsTimeout = false")
+	}
+}
+
+func TestGeminiStream_FirstTokenTimeout(t *testing.T) {
+	// Events without text keep the connection busy but do not count as the
+	// first token.
+	server := stallingServer(t, `data: {"usageMetadata":{"promptTokenCount":1}}`, "")
+	gen := &GeminiGenerator{httpClient: server.Client(), model: "gemini-2.0-flash", baseURL: server.URL}
+
+	ch, err := gen.Stream(context.Background(), "hello",
+		WithFirstTokenTimeout(50*time.Millisecond), WithIdleTimeout(time.Minute))
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	_, err = lastError(t, ch)
+	var te *TimeoutError
+	if !errors.As(err, &te) || te.Kind != TimeoutFirstToken {
+		t.Fatalf("error = %v, want first-token TimeoutError", err)
+	}
+}
+
+func TestGeminiStream_SlowConsumerIsNotIdle(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, s := range []string{"a", "b", "c"} {
+			fmt.Fprintf(w, "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":%q}]}}]}\n\n", s)
+		}
+	}))
+	defer server.Close()
+	gen := &GeminiGenerator{httpClient: server.Client(), model: "gemini-2.0-flash", baseURL: server.URL}
+
+	ch, err := gen.Stream(context.Background(), "hello", WithIdleTimeout(30*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	var text string
+	for chunk := range ch {
+		if chunk.Error != nil {
+			t.Fatalf("chunk error = %v", chunk.Error)
+		}
+		text += chunk.Text
+		time.Sleep(60 * time.Millisecond)
+	}
+	if text != "abc" {
+		t.Errorf("text = %q, want %q", text, "abc")
+	}
+}
+
+func TestOllamaGenerate_Timeout(t *testing.T) {
+	server := stallingServer(t)
+	gen := &OllamaGenerator{httpClient: server.Client(), model: "llama3.2", baseURL: server.URL}
+
+	start := time.Now()
+	_, err := gen.Generate(context.Background(), "hello", WithTimeout(50*time.Millisecond))
+	var te *TimeoutError
+	if !errors.As(err, &te) || te.Kind != TimeoutRequest || te.Provider != "ollama" {
+		t.Fatalf("error = %v, want request TimeoutError", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Generate took %v", elapsed)
+	}
+}
+
+func TestOllamaStream_IdleTimeout(t *testing.T) {
+	server := stallingServer(t, `{"response":"Hi"}`)
+	gen := &OllamaGenerator{httpClient: server.Client(), model: "llama3.2", baseURL: server.URL}
+
+	ch, err := gen.Stream(context.Background(), "hello", WithIdleTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	text, err := lastError(t, ch)
+	if text != "Hi" || !IsTimeout(err) {
+		t.Errorf("text = %q, error = %v", text, err)
+	}
+}
+
+func TestTimeoutNotTriggeredByCallerCancel(t *testing.T) {
+	server := stallingServer(t)
+	gen := &GeminiGenerator{httpClient: server.Client(), model: "gemini-2.0-flash", baseURL: server.URL}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := gen.Stream(ctx, "hello", WithIdleTimeout(time.Minute))
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	cancel()
+	if _, err := lastError(t, ch); err == nil || IsTimeout(err) {
+		t.Errorf("error = %v, want a non-timeout error", err)
+	}
+}
@@ -0,0 +1,222 @@
+package generators
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultVertexLocation is the Vertex AI region used when the URL does
+	// not name one.
+	defaultVertexLocation = "us-central1"
+
+	// defaultVertexEmbeddingModel is the model used by Embed on Vertex AI
+	// when neither the request nor the URL names an embedding model.
+	defaultVertexEmbeddingModel = "text-embedding-005"
+
+	// envGoogleCredentials is the environment variable naming the
+	// service-account credentials file.
+	envGoogleCredentials = "GOOGLE_APPLICATION_CREDENTIALS"
+)
+
+// VertexOpener implements the Opener interface for Gemini models served by
+// Google Cloud Vertex AI. It supports the "vertex" URL scheme.
+//
+// URL format: vertex://{project}/[{location}/]{model}[?params]
+//
+// Requests are authenticated with OAuth access tokens obtained for a
+// service account, whose credentials JSON file is named by the credentials
+// parameter or the GOOGLE_APPLICATION_CREDENTIALS environment variable.
+// The project defaults to the one in the credentials, the location to
+// us-central1 and the model to gemini-2.0-flash.
+//
+// Parameters:
+//   - credentials: path of the service-account credentials file
+//   - token_url:   OAuth token endpoint, overriding the one in the credentials
+//   - endpoint:    base URL of the Vertex AI API, such as http://localhost:8080,
+//     overriding https://{location}-aiplatform.googleapis.com
+//
+// Examples:
+//   - vertex://my-project/us-central1/gemini-2.5-flash
+//   - vertex://my-project/gemini-2.0-flash?credentials=/etc/keys/sa.json
+//   - vertex:///europe-west4/gemini-2.5-pro   (project from the credentials)
+type VertexOpener struct{}
+
+// Id returns the unique identifier for the Vertex AI opener.
+func (o *VertexOpener) Id() string {
+	return "vertex"
+}
+
+// CanOpen reports whether this opener can handle the given URL.
+// It returns true for the "vertex" scheme.
+func (o *VertexOpener) CanOpen(u *url.URL) bool {
+	return u.Scheme == "vertex"
+}
+
+// Open creates a Gemini generator client for Vertex AI using the provided
+// URL. The credentials file is read here; access tokens are fetched on the
+// first request and refreshed before they expire.
+func (o *VertexOpener) Open(_ context.Context, u *url.URL) (Generator, error) {
+	if u == nil {
+		return nil, errors.New("generators: URL cannot be nil for VertexOpener")
+	}
+	if !o.CanOpen(u) {
+		return nil, fmt.Errorf("generators: scheme %q not supported by VertexOpener (expected vertex)", u.Scheme)
+	}
+
+	var credentials, tokenURL, endpoint string
+	for key, values := range u.Query() {
+		if len(values) == 0 {
+			continue
+		}
+		v := values[len(values)-1]
+		switch key {
+		case "credentials":
+			credentials = v
+		case "token_url":
+			tokenURL = v
+		case "endpoint":
+			endpoint = strings.TrimSuffix(v, "/")
+		default:
+			return nil, fmt.Errorf("generators: unknown vertex URL parameter %q", key)
+		}
+	}
+	if credentials == "" {
+		credentials = os.Getenv(envGoogleCredentials)
+	}
+	if credentials == "" {
+		return nil, fmt.Errorf("generators: vertex needs a credentials parameter or %s", envGoogleCredentials)
+	}
+	sa, key, err := loadGoogleServiceAccount(credentials)
+	if err != nil {
+		return nil, err
+	}
+	if tokenURL == "" {
+		tokenURL = sa.TokenURI
+	}
+	if tokenURL == "" {
+		tokenURL = defaultGoogleTokenURL
+	}
+
+	project := u.Host
+	if project == "" {
+		project = sa.ProjectID
+	}
+	if project == "" {
+		return nil, errors.New("generators: vertex URL has no project and the credentials do not name one")
+	}
+	location, model := splitLastSegment(u.EscapedPath())
+	if location == "" {
+		location = defaultVertexLocation
+	}
+	if strings.Contains(location, "/") {
+		return nil, fmt.Errorf("generators: invalid vertex URL path %q (expected /{location}/{model})", u.Path)
+	}
+	if model == "" {
+		model = defaultGeminiModel
+	}
+	if endpoint == "" {
+		endpoint = "https://" + vertexHost(location)
+	}
+
+	httpClient := &http.Client{}
+	return &GeminiGenerator{
+		httpClient: httpClient,
+		model:      model,
+		baseURL:    fmt.Sprintf("%s/v1/projects/%s/locations/%s/publishers/google/models", endpoint, project, location),
+		tokens: &googleTokenSource{
+			httpClient: httpClient,
+			tokenURL:   tokenURL,
+			email:      sa.ClientEmail,
+			keyID:      sa.PrivateKeyID,
+			key:        key,
+			now:        time.Now,
+		},
+	}, nil
+}
+
+func init() {
+	RegisterOpener(&VertexOpener{})
+}
+
+// vertexHost returns the API host serving the given location.
+func vertexHost(location string) string {
+	if location == "global" {
+		return "aiplatform.googleapis.com"
+	}
+	return location + "-aiplatform.googleapis.com"
+}
+
+// vertexPredictRequest is the body of an embedding predict request.
+type vertexPredictRequest struct {
+	Instances []vertexEmbedInstance `json:"instances"`
+}
+
+type vertexEmbedInstance struct {
+	Content string `json:"content"`
+}
+
+type vertexPredictResponse struct {
+	Predictions []struct {
+		Embeddings struct {
+			Values []float32 `json:"values"`
+		} `json:"embeddings"`
+	} `json:"predictions"`
+}
+
+// embedPredict computes embeddings with the Vertex AI predict endpoint,
+// which replaces batchEmbedContents there.
+func (g *GeminiGenerator) embedPredict(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	reqBody := vertexPredictRequest{Instances: make([]vertexEmbedInstance, len(texts))}
+	for i, text := range texts {
+		reqBody.Instances[i] = vertexEmbedInstance{Content: text}
+	}
+	body, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("generators: vertex marshal embed request: %w", err)
+	}
+
+	endpoint := fmt.Sprintf("%s/%s:predict", g.baseURL, model)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("generators: vertex create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := g.authorize(req); err != nil {
+		return nil, err
+	}
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("generators: vertex request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{Provider: "vertex", StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+
+	var predResp vertexPredictResponse
+	if err := json.NewDecoder(resp.Body).Decode(&predResp); err != nil {
+		return nil, fmt.Errorf("generators: vertex decode embed response: %w", err)
+	}
+	if len(predResp.Predictions) != len(texts) {
+		return nil, fmt.Errorf("generators: vertex returned %d embeddings for %d texts", len(predResp.Predictions), len(texts))
+	}
+	out := make([][]float32, len(texts))
+	for i, p := range predResp.Predictions {
+		out[i] = p.Embeddings.Values
+	}
+	return out, nil
+}
@@ -0,0 +1,260 @@
+package generators
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// writeServiceAccount writes a credentials file for a fresh RSA key and
+// returns its path and the key.
+func writeServiceAccount(t *testing.T, project, tokenURI string) (string, *rsa.PrivateKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(googleServiceAccount{
+		Type:         "service_account",
+		ProjectID:    project,
+		PrivateKeyID: "key-1",
+		PrivateKey:   string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		ClientEmail:  "bot@" + project + ".iam.gserviceaccount.com",
+		TokenURI:     tokenURI,
+	})
+	path := filepath.Join(t.TempDir(), "sa.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path, key
+}
+
+// tokenServer is a stand-in OAuth endpoint that verifies the signed
+// assertion and issues numbered tokens.
+func tokenServer(t *testing.T, key *rsa.PublicKey, issued *atomic.Int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		if g := r.PostForm.Get("grant_type"); g != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+			t.Errorf("grant_type = %q", g)
+		}
+		parts := strings.Split(r.PostForm.Get("assertion"), ".")
+		if len(parts) != 3 {
+			http.Error(w, "bad assertion", http.StatusBadRequest)
+			return
+		}
+		sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, sum[:], sig); err != nil {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		var claims map[string]any
+		payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		json.Unmarshal(payload, &claims)
+		if claims["scope"] != googleCloudPlatformScope || !strings.HasPrefix(claims["iss"].(string), "bot@") {
+			t.Errorf("claims = %v", claims)
+		}
+		n := issued.Add(1)
+		fmt.Fprintf(w, `{"access_token":"tok-%d","expires_in":3600,"token_type":"Bearer"}`, n)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestVertexOpener_Open(t *testing.T) {
+	var issued atomic.Int32
+	creds, key := writeServiceAccount(t, "cred-project", "")
+	tokens := tokenServer(t, &key.PublicKey, &issued)
+
+	var auth []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+		want := "/v1/projects/my-project/locations/europe-west4/publishers/google/models/gemini-2.5-flash:generateContent"
+		if r.URL.Path != want {
+			t.Errorf("path = %q, want %q", r.URL.Path, want)
+		}
+		if r.Header.Get("x-goog-api-key") != "" {
+			t.Error("API key header sent to Vertex AI")
+		}
+		fmt.Fprint(w, `{"candidates":[{"content":{"parts":[{"text":"hola"}]},"finishReason":"STOP"}]}`)
+	}))
+	defer api.Close()
+
+	u, _ := url.Parse("vertex://my-project/europe-west4/gemini-2.5-flash?" + url.Values{
+		"credentials": {creds},
+		"token_url":   {tokens.URL},
+		"endpoint":    {api.URL},
+	}.Encode())
+	gen, err := (&VertexOpener{}).Open(context.Background(), u)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer gen.Close()
+
+	for range 2 {
+		resp, err := gen.Generate(context.Background(), "hello")
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		if resp.Text != "hola" {
+			t.Errorf("Text = %q", resp.Text)
+		}
+	}
+	if issued.Load() != 1 {
+		t.Errorf("tokens issued = %d, want 1 (cached)", issued.Load())
+	}
+	if len(auth) != 2 || auth[0] != "Bearer tok-1" || auth[1] != "Bearer tok-1" {
+		t.Errorf("Authorization headers = %q", auth)
+	}
+}
+
+func TestVertexOpener_OpenDefaults(t *testing.T) {
+	creds, _ := writeServiceAccount(t, "cred-project", "https://oauth.example/token")
+	t.Setenv(envGoogleCredentials, creds)
+
+	gen, err := (&VertexOpener{}).Open(context.Background(), &url.URL{Scheme: "vertex"})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	g := gen.(*GeminiGenerator)
+	wantBase := "https://us-central1-aiplatform.googleapis.com/v1/projects/cred-project/locations/us-central1/publishers/google/models"
+	if g.baseURL != wantBase || g.model != defaultGeminiModel {
+		t.Errorf("baseURL = %q, model = %q", g.baseURL, g.model)
+	}
+	if g.tokens.tokenURL != "https://oauth.example/token" || g.name() != "vertex" {
+		t.Errorf("tokenURL = %q, name = %q", g.tokens.tokenURL, g.name())
+	}
+
+	u, _ := url.Parse("vertex://p/global/gemini-2.5-pro")
+	gen, err = (&VertexOpener{}).Open(context.Background(), u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := gen.(*GeminiGenerator).baseURL; !strings.HasPrefix(got, "https://aiplatform.googleapis.com/v1/projects/p/locations/global/") {
+		t.Errorf("global baseURL = %q", got)
+	}
+}
+
+func TestVertexOpener_OpenErrors(t *testing.T) {
+	creds, _ := writeServiceAccount(t, "", "")
+	t.Setenv(envGoogleCredentials, "")
+	for _, rawurl := range []string{
+		"vertex://p/us-central1/m",                                    // no credentials
+		"vertex://p/m?credentials=" + url.QueryEscape(creds) + "&x=1", // unknown parameter
+		"vertex:///m?credentials=" + url.QueryEscape(creds),           // no project
+		"vertex://p/a/b/m?credentials=" + url.QueryEscape(creds),      // bad path
+		"vertex://p/m?credentials=/does/not/exist.json",
+	} {
+		u, _ := url.Parse(rawurl)
+		if _, err := (&VertexOpener{}).Open(context.Background(), u); err == nil {
+			t.Errorf("Open(%q) succeeded", rawurl)
+		}
+	}
+}
+
+func TestGoogleTokenSource_Refresh(t *testing.T) {
+	var issued atomic.Int32
+	_, key := writeServiceAccount(t, "p", "")
+	tokens := tokenServer(t, &key.PublicKey, &issued)
+
+	now := time.Unix(1_700_000_000, 0)
+	src := &googleTokenSource{
+		httpClient: tokens.Client(),
+		tokenURL:   tokens.URL,
+		email:      "bot@p.iam.gserviceaccount.com",
+		key:        key,
+		now:        func() time.Time { return now },
+	}
+	ctx := context.Background()
+	for _, step := range []struct {
+		advance time.Duration
+		want    string
+	}{
+		{0, "tok-1"},
+		{30 * time.Minute, "tok-1"},
+		{29 * time.Minute, "tok-2"}, // within the refresh margin
+		{time.Minute, "tok-2"},
+	} {
+		now = now.Add(step.advance)
+		got, err := src.token(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != step.want {
+			t.Errorf("after %v: token = %q, want %q", step.advance, got, step.want)
+		}
+	}
+}
+
+func TestVertexEmbed(t *testing.T) {
+	var issued atomic.Int32
+	_, key := writeServiceAccount(t, "p", "")
+	tokens := tokenServer(t, &key.PublicKey, &issued)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/models/text-embedding-005:predict") {
+			t.Errorf("path = %q", r.URL.Path)
+		}
+		var req vertexPredictRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if len(req.Instances) != 2 || req.Instances[1].Content != "b" {
+			t.Errorf("instances = %+v", req.Instances)
+		}
+		fmt.Fprint(w, `{"predictions":[{"embeddings":{"values":[1,0]}},{"embeddings":{"values":[0,1]}}]}`)
+	}))
+	defer api.Close()
+
+	gen := &GeminiGenerator{
+		httpClient: api.Client(),
+		model:      "gemini-2.0-flash",
+		baseURL:    api.URL + "/v1/projects/p/locations/us-central1/publishers/google/models",
+		tokens:     &googleTokenSource{httpClient: tokens.Client(), tokenURL: tokens.URL, email: "bot@p", key: key, now: time.Now},
+	}
+	vecs, err := gen.Embed(context.Background(), []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+	if len(vecs) != 2 || vecs[1][1] != 1 {
+		t.Errorf("vectors = %v", vecs)
+	}
+}
+
+func TestVertexGenerate_ErrorNamesProvider(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"candidates":`)
+	}))
+	defer api.Close()
+
+	gen := &GeminiGenerator{
+		httpClient: api.Client(),
+		model:      "gemini-2.0-flash",
+		baseURL:    api.URL + "/v1/projects/p/locations/us-central1/publishers/google/models",
+		tokens:     &googleTokenSource{cached: "token", expires: time.Now().Add(time.Hour), now: time.Now},
+	}
+	_, err := gen.Generate(context.Background(), "hello")
+	if err == nil || !strings.HasPrefix(err.Error(), "generators: vertex decode response") {
+		t.Errorf("Generate() error = %v, want vertex decode error", err)
+	}
+}
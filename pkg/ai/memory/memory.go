@@ -0,0 +1,69 @@
+// Package memory stores conversation history and trims it to fit a model's
+// context window.
+//
+// Turns are persisted per session in a SQL database (see Store). Before a
+// request, the history is passed through a Strategy, such as SlidingWindow,
+// TokenBudget or Summarize, and rendered into a single prompt for a
+// generators.Generator. Store.Run performs a whole turn: it loads and trims
+// the history, calls the generator and records the exchange.
+package memory
+
+import (
+	"strings"
+	"time"
+)
+
+// Roles of the participants in a conversation.
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Turn is a single message in a conversation.
+// Seq orders the turns of a session and is assigned by the Store.
+type Turn struct {
+	Seq       int64
+	Role      string
+	Content   string
+	CreatedAt time.Time
+}
+
+// Render formats turns as a prompt for a Generator.
+//
+// System turns are returned separately so they can be passed with
+// generators.WithSystemInstruction. The remaining turns are written as
+// "User:" and "Assistant:" lines, and the prompt ends with an "Assistant:"
+// cue for the model to continue.
+func Render(turns []Turn) (system, prompt string) {
+	var sys []string
+	var b strings.Builder
+	for _, t := range turns {
+		switch t.Role {
+		case RoleSystem:
+			sys = append(sys, t.Content)
+		case RoleAssistant:
+			b.WriteString("Assistant: ")
+			b.WriteString(t.Content)
+			b.WriteString("\n\n")
+		default:
+			b.WriteString("User: ")
+			b.WriteString(t.Content)
+			b.WriteString("\n\n")
+		}
+	}
+	b.WriteString("Assistant:")
+	return strings.Join(sys, "\n\n"), b.String()
+}
+
+// splitSystem separates the system turns from the rest of the conversation.
+func splitSystem(turns []Turn) (system, rest []Turn) {
+	for _, t := range turns {
+		if t.Role == RoleSystem {
+			system = append(system, t)
+		} else {
+			rest = append(rest, t)
+		}
+	}
+	return system, rest
+}
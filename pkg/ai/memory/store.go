@@ -0,0 +1,240 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/tnotstar/go-minolas/pkg/ai/generators"
+	"github.com/tnotstar/go-minolas/pkg/db/sqlt"
+)
+
+// schema creates the table used to persist conversation turns.
+const schema = `CREATE TABLE IF NOT EXISTS conversation_turns (
+	session    VARCHAR(255) NOT NULL,
+	seq        BIGINT       NOT NULL,
+	role       VARCHAR(16)  NOT NULL,
+	content    TEXT         NOT NULL,
+	created_at BIGINT       NOT NULL,
+	PRIMARY KEY (session, seq)
+)`
+
+// Store persists conversation turns in a SQL database.
+// The statements use "?" placeholders, as supported by SQLite.
+type Store struct {
+	db *sql.DB
+}
+
+// OpenStore opens the SQLite database at dburl through sqlt.Open and
+// prepares the conversation_turns table. URLs of other databases are
+// rejected, as the statements are written for SQLite.
+//
+// Example:
+//
+//	store, err := memory.OpenStore(ctx, "sqlite:chat.db")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer store.Close()
+func OpenStore(ctx context.Context, dburl string) (*Store, error) {
+	u, err := url.Parse(dburl)
+	if err != nil {
+		return nil, fmt.Errorf("memory: open store: %w", err)
+	}
+	if !(&sqlt.SqliteOpener{}).CanOpen(u) {
+		return nil, fmt.Errorf("memory: open store: scheme %q not supported (expected sqlite or sqlite3)", u.Scheme)
+	}
+	db, err := sqlt.Open(dburl)
+	if err != nil {
+		return nil, fmt.Errorf("memory: open store: %w", err)
+	}
+	s, err := NewStore(ctx, db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewStore prepares the conversation_turns table in an already open
+// database. Closing the returned Store closes db.
+func NewStore(ctx context.Context, db *sql.DB) (*Store, error) {
+	if db == nil {
+		return nil, errors.New("memory: database cannot be nil")
+	}
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		return nil, fmt.Errorf("memory: create schema: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Append adds turns to the end of a session and returns them with Seq and,
+// if unset, CreatedAt filled in.
+func (s *Store) Append(ctx context.Context, session string, turns ...Turn) ([]Turn, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("memory: begin append: %w", err)
+	}
+	defer tx.Rollback()
+
+	var last int64
+	err = tx.QueryRowContext(ctx,
+		"SELECT COALESCE(MAX(seq), 0) FROM conversation_turns WHERE session = ?", session).Scan(&last)
+	if err != nil {
+		return nil, fmt.Errorf("memory: query last turn: %w", err)
+	}
+
+	out := make([]Turn, len(turns))
+	for i, t := range turns {
+		last++
+		t.Seq = last
+		if t.CreatedAt.IsZero() {
+			t.CreatedAt = time.Now()
+		}
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO conversation_turns (session, seq, role, content, created_at) VALUES (?, ?, ?, ?, ?)`,
+			session, t.Seq, t.Role, t.Content, t.CreatedAt.UnixMilli())
+		if err != nil {
+			return nil, fmt.Errorf("memory: insert turn: %w", err)
+		}
+		out[i] = t
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("memory: commit append: %w", err)
+	}
+	return out, nil
+}
+
+// Turns returns all turns of a session, oldest first.
+// An unknown session has no turns.
+func (s *Store) Turns(ctx context.Context, session string) ([]Turn, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT seq, role, content, created_at FROM conversation_turns WHERE session = ? ORDER BY seq`, session)
+	if err != nil {
+		return nil, fmt.Errorf("memory: query turns: %w", err)
+	}
+	defer rows.Close()
+
+	var turns []Turn
+	for rows.Next() {
+		var (
+			t       Turn
+			created int64
+		)
+		if err := rows.Scan(&t.Seq, &t.Role, &t.Content, &created); err != nil {
+			return nil, fmt.Errorf("memory: scan turn: %w", err)
+		}
+		t.CreatedAt = time.UnixMilli(created)
+		turns = append(turns, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("memory: read turns: %w", err)
+	}
+	return turns, nil
+}
+
+// Sessions returns the names of all stored sessions in lexical order.
+func (s *Store) Sessions(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT DISTINCT session FROM conversation_turns ORDER BY session")
+	if err != nil {
+		return nil, fmt.Errorf("memory: query sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("memory: scan session: %w", err)
+		}
+		sessions = append(sessions, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("memory: read sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// Delete removes all turns of a session.
+func (s *Store) Delete(ctx context.Context, session string) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM conversation_turns WHERE session = ?", session); err != nil {
+		return fmt.Errorf("memory: delete session: %w", err)
+	}
+	return nil
+}
+
+// Close closes the underlying database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// RunOption is a functional option for configuring Store.Run.
+type RunOption func(*runConfig)
+
+// runConfig holds configuration for a Store.Run call.
+type runConfig struct {
+	strategy Strategy
+	genOpts  []generators.Option
+}
+
+// WithStrategy sets the strategy used to trim the history before it is sent.
+// By default the whole history is sent.
+func WithStrategy(s Strategy) RunOption {
+	return func(c *runConfig) { c.strategy = s }
+}
+
+// WithGenerateOptions sets the generation options passed to the generator.
+func WithGenerateOptions(opts ...generators.Option) RunOption {
+	return func(c *runConfig) { c.genOpts = opts }
+}
+
+// Run performs one conversation turn: it loads the session history, appends
+// input as a user turn, trims the result with the configured Strategy,
+// renders it with Render and calls gen. On success the user turn and the
+// assistant's answer are appended to the session; on failure the session is
+// left unchanged.
+//
+// Example:
+//
+//	resp, err := store.Run(ctx, gen, "support-42", "And in Spanish?",
+//	    memory.WithStrategy(memory.TokenBudget(8000, nil)),
+//	)
+func (s *Store) Run(ctx context.Context, gen generators.Generator, session, input string, opts ...RunOption) (*generators.Response, error) {
+	cfg := &runConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	history, err := s.Turns(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+	user := Turn{Role: RoleUser, Content: input, CreatedAt: time.Now()}
+	turns := append(history, user)
+	if cfg.strategy != nil {
+		if turns, err = cfg.strategy.Truncate(ctx, turns); err != nil {
+			return nil, err
+		}
+	}
+
+	system, prompt := Render(turns)
+	genOpts := cfg.genOpts
+	if system != "" {
+		genOpts = append([]generators.Option{generators.WithSystemInstruction(system)}, genOpts...)
+	}
+	resp, err := gen.Generate(ctx, prompt, genOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	answer := Turn{Role: RoleAssistant, Content: resp.Text}
+	if _, err := s.Append(ctx, session, user, answer); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,117 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tnotstar/go-minolas/pkg/ai/generators"
+)
+
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	store, err := OpenStore(context.Background(), "sqlite:"+filepath.Join(t.TempDir(), "chat.db"))
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestOpenStore_UnsupportedURL(t *testing.T) {
+	for _, dburl := range []string{"nosuchdb://x", "sqlserver://sa:pw@localhost/chat", "oracle://u:p@localhost/chat"} {
+		if _, err := OpenStore(context.Background(), dburl); err == nil {
+			t.Errorf("OpenStore(%q) succeeded, want error", dburl)
+		}
+	}
+}
+
+func TestStore_AppendTurns(t *testing.T) {
+	ctx := context.Background()
+	store := openTestStore(t)
+
+	if _, err := store.Append(ctx, "a", Turn{Role: RoleUser, Content: "hi"}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	got, err := store.Append(ctx, "a", Turn{Role: RoleAssistant, Content: "hello"}, Turn{Role: RoleUser, Content: "bye"})
+	if err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if got[0].Seq != 2 || got[1].Seq != 3 || got[0].CreatedAt.IsZero() {
+		t.Errorf("appended = %+v, want Seq 2 and 3 with CreatedAt set", got)
+	}
+	if _, err := store.Append(ctx, "b", Turn{Role: RoleUser, Content: "other"}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	turns, err := store.Turns(ctx, "a")
+	if err != nil {
+		t.Fatalf("Turns() error = %v", err)
+	}
+	if contents(turns) != "hi,hello,bye" || turns[1].Role != RoleAssistant {
+		t.Errorf("Turns(a) = %+v", turns)
+	}
+
+	sessions, err := store.Sessions(ctx)
+	if err != nil || strings.Join(sessions, ",") != "a,b" {
+		t.Errorf("Sessions() = %v, %v; want [a b]", sessions, err)
+	}
+
+	if err := store.Delete(ctx, "a"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if turns, _ := store.Turns(ctx, "a"); len(turns) != 0 {
+		t.Errorf("Turns(a) after Delete = %+v, want none", turns)
+	}
+}
+
+func TestStore_Run(t *testing.T) {
+	ctx := context.Background()
+	store := openTestStore(t)
+	if _, err := store.Append(ctx, "s",
+		Turn{Role: RoleSystem, Content: "be brief"},
+		Turn{Role: RoleUser, Content: "old question"},
+		Turn{Role: RoleAssistant, Content: "old answer"},
+	); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	var prompt, system string
+	gen := funcGenerator(func(_ context.Context, p string, opts ...generators.Option) (*generators.Response, error) {
+		prompt = p
+		cfg := &generators.Config{}
+		for _, opt := range opts {
+			opt(cfg)
+		}
+		system = cfg.SystemInstruction
+		return &generators.Response{Text: "new answer"}, nil
+	})
+
+	resp, err := store.Run(ctx, gen, "s", "new question", WithStrategy(SlidingWindow(1)))
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if resp.Text != "new answer" {
+		t.Errorf("Text = %q, want %q", resp.Text, "new answer")
+	}
+	if system != "be brief" || prompt != "User: new question\n\nAssistant:" {
+		t.Errorf("system/prompt = %q/%q", system, prompt)
+	}
+
+	turns, _ := store.Turns(ctx, "s")
+	if contents(turns) != "be brief,old question,old answer,new question,new answer" {
+		t.Errorf("Turns() = %s", contents(turns))
+	}
+
+	failing := funcGenerator(func(context.Context, string, ...generators.Option) (*generators.Response, error) {
+		return nil, errors.New("boom")
+	})
+	if _, err := store.Run(ctx, failing, "s", "again"); err == nil {
+		t.Fatal("expected error from failing generator")
+	}
+	if turns, _ := store.Turns(ctx, "s"); len(turns) != 5 {
+		t.Errorf("len(Turns) after failed Run = %d, want 5", len(turns))
+	}
+}
@@ -0,0 +1,123 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tnotstar/go-minolas/pkg/ai/generators"
+)
+
+// Strategy trims a conversation before it is sent to a model.
+// Strategies never modify the persisted history.
+type Strategy interface {
+
+	// Truncate returns the turns to send, oldest first.
+	Truncate(ctx context.Context, turns []Turn) ([]Turn, error)
+}
+
+// StrategyFunc adapts an ordinary function to the Strategy interface.
+type StrategyFunc func(ctx context.Context, turns []Turn) ([]Turn, error)
+
+// Truncate calls f(ctx, turns).
+func (f StrategyFunc) Truncate(ctx context.Context, turns []Turn) ([]Turn, error) {
+	return f(ctx, turns)
+}
+
+// TokenCounter returns the number of tokens in text.
+type TokenCounter func(text string) int
+
+// ApproxTokens estimates the token count of text as one token per four
+// characters, rounded up. It is a reasonable default for English prose when
+// no tokenizer is available.
+func ApproxTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// Chain returns a Strategy that applies the given strategies in order.
+func Chain(strategies ...Strategy) Strategy {
+	return StrategyFunc(func(ctx context.Context, turns []Turn) ([]Turn, error) {
+		var err error
+		for _, s := range strategies {
+			if turns, err = s.Truncate(ctx, turns); err != nil {
+				return nil, err
+			}
+		}
+		return turns, nil
+	})
+}
+
+// SlidingWindow returns a Strategy that keeps the system turns and the last
+// n other turns.
+func SlidingWindow(n int) Strategy {
+	return StrategyFunc(func(_ context.Context, turns []Turn) ([]Turn, error) {
+		system, rest := splitSystem(turns)
+		if len(rest) > n {
+			rest = rest[len(rest)-max(n, 0):]
+		}
+		return append(system, rest...), nil
+	})
+}
+
+// TokenBudget returns a Strategy that keeps the system turns and as many of
+// the most recent other turns as fit in budget tokens, as measured by
+// counter. A nil counter uses ApproxTokens. The most recent turn is always
+// kept, even if it alone exceeds the budget.
+func TokenBudget(budget int, counter TokenCounter) Strategy {
+	if counter == nil {
+		counter = ApproxTokens
+	}
+	return StrategyFunc(func(_ context.Context, turns []Turn) ([]Turn, error) {
+		system, rest := splitSystem(turns)
+		used := 0
+		for _, t := range system {
+			used += counter(t.Content)
+		}
+		start := len(rest)
+		for start > 0 {
+			cost := counter(rest[start-1].Content)
+			if used+cost > budget && start < len(rest) {
+				break
+			}
+			used += cost
+			start--
+		}
+		return append(system, rest[start:]...), nil
+	})
+}
+
+// summaryPrompt is the instruction sent to the generator by Summarize.
+const summaryPrompt = "Summarize the following conversation in a few sentences. " +
+	"Keep names, facts, decisions and open questions; omit pleasantries.\n\n"
+
+// Summarize returns a Strategy that keeps the last keep non-system turns and
+// replaces the older ones with a system turn holding a summary written by
+// gen. The options are passed to every summarization request.
+func Summarize(gen generators.Generator, keep int, opts ...generators.Option) Strategy {
+	return StrategyFunc(func(ctx context.Context, turns []Turn) ([]Turn, error) {
+		system, rest := splitSystem(turns)
+		keep := max(keep, 0)
+		if len(rest) <= keep {
+			return turns, nil
+		}
+		older, recent := rest[:len(rest)-keep], rest[len(rest)-keep:]
+
+		var b strings.Builder
+		b.WriteString(summaryPrompt)
+		for _, t := range older {
+			fmt.Fprintf(&b, "%s: %s\n", t.Role, t.Content)
+		}
+		resp, err := gen.Generate(ctx, b.String(), opts...)
+		if err != nil {
+			return nil, fmt.Errorf("memory: summarize: %w", err)
+		}
+
+		summary := Turn{
+			Role:    RoleSystem,
+			Content: "Summary of the earlier conversation: " + strings.TrimSpace(resp.Text),
+		}
+		out := append(system, summary)
+		return append(out, recent...), nil
+	})
+}
@@ -0,0 +1,132 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tnotstar/go-minolas/pkg/ai/generators"
+)
+
+// funcGenerator is a Generator backed by a function, used by the tests.
+type funcGenerator func(ctx context.Context, prompt string, opts ...generators.Option) (*generators.Response, error)
+
+func (f funcGenerator) Generate(ctx context.Context, prompt string, opts ...generators.Option) (*generators.Response, error) {
+	return f(ctx, prompt, opts...)
+}
+
+func (f funcGenerator) Stream(ctx context.Context, prompt string, opts ...generators.Option) (<-chan generators.StreamChunk, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f funcGenerator) Close() error { return nil }
+
+func conversation() []Turn {
+	return []Turn{
+		{Role: RoleSystem, Content: "be brief"},
+		{Role: RoleUser, Content: "aaaa"},
+		{Role: RoleAssistant, Content: "bbbbbbbb"},
+		{Role: RoleUser, Content: "cccc"},
+		{Role: RoleAssistant, Content: "dddd"},
+	}
+}
+
+func contents(turns []Turn) string {
+	var parts []string
+	for _, t := range turns {
+		parts = append(parts, t.Content)
+	}
+	return strings.Join(parts, ",")
+}
+
+func TestSlidingWindow(t *testing.T) {
+	testCases := []struct {
+		n    int
+		want string
+	}{
+		{n: 2, want: "be brief,cccc,dddd"},
+		{n: 10, want: "be brief,aaaa,bbbbbbbb,cccc,dddd"},
+		{n: 0, want: "be brief"},
+	}
+	for _, tc := range testCases {
+		got, err := SlidingWindow(tc.n).Truncate(context.Background(), conversation())
+		if err != nil {
+			t.Fatalf("Truncate() error = %v", err)
+		}
+		if contents(got) != tc.want {
+			t.Errorf("SlidingWindow(%d) = %s, want %s", tc.n, contents(got), tc.want)
+		}
+	}
+}
+
+func TestTokenBudget(t *testing.T) {
+	testCases := []struct {
+		budget int
+		want   string
+	}{
+		// "be brief" costs 2 tokens and every other turn 1, except "bbbbbbbb" which costs 2.
+		{budget: 4, want: "be brief,cccc,dddd"},
+		{budget: 5, want: "be brief,cccc,dddd"},
+		{budget: 6, want: "be brief,bbbbbbbb,cccc,dddd"},
+		{budget: 0, want: "be brief,dddd"},
+	}
+	for _, tc := range testCases {
+		got, err := TokenBudget(tc.budget, nil).Truncate(context.Background(), conversation())
+		if err != nil {
+			t.Fatalf("Truncate() error = %v", err)
+		}
+		if contents(got) != tc.want {
+			t.Errorf("TokenBudget(%d) = %s, want %s", tc.budget, contents(got), tc.want)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	var prompt string
+	gen := funcGenerator(func(_ context.Context, p string, _ ...generators.Option) (*generators.Response, error) {
+		prompt = p
+		return &generators.Response{Text: " they said a and b "}, nil
+	})
+
+	got, err := Summarize(gen, 2).Truncate(context.Background(), conversation())
+	if err != nil {
+		t.Fatalf("Truncate() error = %v", err)
+	}
+	if len(got) != 4 || got[1].Role != RoleSystem || !strings.HasSuffix(got[1].Content, ": they said a and b") {
+		t.Fatalf("turns = %+v, want system, summary and two recent turns", got)
+	}
+	if contents(got[2:]) != "cccc,dddd" {
+		t.Errorf("recent turns = %s, want cccc,dddd", contents(got[2:]))
+	}
+	if !strings.Contains(prompt, "user: aaaa\nassistant: bbbbbbbb\n") || strings.Contains(prompt, "cccc") {
+		t.Errorf("summary prompt = %q, want only the older turns", prompt)
+	}
+
+	// Nothing to summarize: the generator is not called.
+	prompt = ""
+	if _, err := Summarize(gen, 10).Truncate(context.Background(), conversation()); err != nil || prompt != "" {
+		t.Errorf("Summarize with short history: err = %v, prompt = %q", err, prompt)
+	}
+}
+
+func TestChain(t *testing.T) {
+	got, err := Chain(SlidingWindow(3), TokenBudget(4, nil)).Truncate(context.Background(), conversation())
+	if err != nil {
+		t.Fatalf("Truncate() error = %v", err)
+	}
+	if contents(got) != "be brief,cccc,dddd" {
+		t.Errorf("Chain = %s", contents(got))
+	}
+}
+
+func TestRender(t *testing.T) {
+	system, prompt := Render(conversation())
+	if system != "be brief" {
+		t.Errorf("system = %q, want %q", system, "be brief")
+	}
+	want := "User: aaaa\n\nAssistant: bbbbbbbb\n\nUser: cccc\n\nAssistant: dddd\n\nAssistant:"
+	if prompt != want {
+		t.Errorf("prompt = %q, want %q", prompt, want)
+	}
+}
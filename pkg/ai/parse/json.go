@@ -0,0 +1,367 @@
+package parse
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+var (
+	// ErrNoJSON is returned when a text contains no JSON object or array.
+	ErrNoJSON = errors.New("parse: no JSON found")
+)
+
+// ExtractJSON returns the first JSON object or array in text, repaired with
+// RepairJSON if needed. A fenced block labelled "json" is preferred over
+// JSON appearing elsewhere in the text.
+func ExtractJSON(text string) (json.RawMessage, error) {
+	text, _ = jsonSource(text)
+	start := strings.IndexAny(text, "{[")
+	if start < 0 {
+		return nil, ErrNoJSON
+	}
+	repaired := RepairJSON(text[start:])
+	if !json.Valid([]byte(repaired)) {
+		return nil, fmt.Errorf("parse: cannot repair JSON: %.80q", text[start:])
+	}
+	return json.RawMessage(repaired), nil
+}
+
+// jsonSource returns the part of text to look for JSON in: the code of the
+// first fenced block labelled "json", even if it is still open, or else
+// the whole text. fenced reports whether such a block was found.
+func jsonSource(text string) (source string, fenced bool) {
+	if code, ok := FindCodeBlock(text, "json"); ok {
+		return code, true
+	}
+	return text, false
+}
+
+// DecodeJSON extracts the first JSON value of text with ExtractJSON and
+// unmarshals it into v.
+func DecodeJSON(text string, v any) error {
+	raw, err := ExtractJSON(text)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("parse: decode JSON: %w", err)
+	}
+	return nil
+}
+
+// RepairJSON rewrites the JSON object or array at the start of s into valid
+// JSON. It fixes the mistakes models commonly make:
+//
+//   - single-quoted strings and unquoted object keys;
+//   - trailing and repeated commas, and missing colons after keys;
+//   - // and /* */ comments;
+//   - Python-style True, False and None;
+//   - output cut off in the middle, by closing open strings, arrays and
+//     objects and dropping incomplete keys and literals.
+//
+// Text after the end of the value is ignored. The result is compact; it is
+// not guaranteed to be valid if s is too damaged.
+func RepairJSON(s string) string {
+	r := repairer{src: s}
+	r.run()
+	return string(r.out)
+}
+
+// container is an open object or array during repair.
+type container struct {
+	object   bool
+	wantKey  bool // an object expects a key next
+	afterKey bool // an object has a key without a colon or value
+	keyStart int  // offset in out of the last key, for dropping it
+}
+
+// repairer holds the state of RepairJSON.
+type repairer struct {
+	src   string
+	pos   int
+	out   []byte
+	stack []container
+}
+
+func (r *repairer) top() *container {
+	if len(r.stack) == 0 {
+		return nil
+	}
+	return &r.stack[len(r.stack)-1]
+}
+
+func (r *repairer) run() {
+	started := false
+	for r.pos < len(r.src) {
+		if started && len(r.stack) == 0 {
+			return
+		}
+		c := r.src[r.pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			r.pos++
+		case strings.HasPrefix(r.src[r.pos:], "//"):
+			r.skipUntil("\n")
+		case strings.HasPrefix(r.src[r.pos:], "/*"):
+			r.skipUntil("*/")
+		case c == '{' || c == '[':
+			r.separate()
+			r.beginValue()
+			r.out = append(r.out, c)
+			r.stack = append(r.stack, container{object: c == '{', wantKey: c == '{'})
+			r.pos++
+			started = true
+		case c == '}' || c == ']':
+			r.closeContainer()
+			r.pos++
+		case c == ',':
+			r.pos++
+			if t := r.top(); t != nil && !r.lastIs('{', '[', ',') {
+				if t.afterKey {
+					r.dropKey()
+				}
+				r.out = append(r.out, ',')
+				t.wantKey = t.object
+			}
+		case c == ':':
+			r.pos++
+			if t := r.top(); t != nil && t.afterKey {
+				r.out = append(r.out, ':')
+				t.afterKey = false
+			}
+		case c == '"' || c == '\'':
+			str, complete := r.readString(c)
+			r.emitToken(strconv.Quote(str), true, complete)
+		default:
+			word := r.readWord()
+			if word == "" {
+				r.pos++ // stray character
+				continue
+			}
+			r.emitToken(word, false, r.pos < len(r.src))
+		}
+	}
+	r.finish()
+}
+
+// emitToken writes a string or bare word as a key or a value.
+// complete is false if the input ended inside the token.
+func (r *repairer) emitToken(tok string, quoted, complete bool) {
+	t := r.top()
+	if t == nil {
+		return // scalars outside a container are ignored
+	}
+	r.separate()
+	if t.object && t.wantKey {
+		if !complete {
+			return
+		}
+		if !quoted {
+			tok = strconv.Quote(tok)
+		}
+		t.keyStart = len(r.out)
+		r.out = append(r.out, tok...)
+		t.wantKey, t.afterKey = false, true
+		return
+	}
+	if !quoted {
+		if !complete {
+			return // a literal or number cut off in the middle
+		}
+		if lit, ok := literal(tok); ok {
+			tok = lit
+		} else {
+			tok = strconv.Quote(tok)
+		}
+	}
+	r.beginValue()
+	r.out = append(r.out, tok...)
+}
+
+// separate inserts a missing comma between two values or members.
+func (r *repairer) separate() {
+	t := r.top()
+	if t == nil || t.afterKey || r.lastIs('{', '[', ',', ':') {
+		return
+	}
+	r.out = append(r.out, ',')
+	t.wantKey = t.object
+}
+
+// beginValue inserts the colon a value needs after a key, if missing.
+func (r *repairer) beginValue() {
+	if t := r.top(); t != nil && t.afterKey {
+		r.out = append(r.out, ':')
+		t.afterKey = false
+	}
+}
+
+// closeContainer ends the innermost container.
+func (r *repairer) closeContainer() {
+	t := r.top()
+	if t == nil {
+		return
+	}
+	if t.afterKey {
+		r.dropKey()
+	}
+	if r.lastIs(':') {
+		r.out = append(r.out, "null"...)
+	}
+	r.trimComma()
+	if t.object {
+		r.out = append(r.out, '}')
+	} else {
+		r.out = append(r.out, ']')
+	}
+	r.stack = r.stack[:len(r.stack)-1]
+}
+
+// finish closes every container left open at the end of the input.
+func (r *repairer) finish() {
+	for len(r.stack) > 0 {
+		r.closeContainer()
+	}
+}
+
+// dropKey removes the last key written to the innermost object.
+func (r *repairer) dropKey() {
+	t := r.top()
+	r.out = r.out[:t.keyStart]
+	t.afterKey = false
+	t.wantKey = true
+}
+
+func (r *repairer) trimComma() {
+	if r.lastIs(',') {
+		r.out = r.out[:len(r.out)-1]
+	}
+}
+
+// lastIs reports whether the last byte written is one of cs.
+func (r *repairer) lastIs(cs ...byte) bool {
+	if len(r.out) == 0 {
+		return false
+	}
+	last := r.out[len(r.out)-1]
+	for _, c := range cs {
+		if last == c {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *repairer) skipUntil(end string) {
+	if i := strings.Index(r.src[r.pos:], end); i >= 0 {
+		r.pos += i + len(end)
+	} else {
+		r.pos = len(r.src)
+	}
+}
+
+// readString reads a string delimited by quote and returns its value and
+// whether the closing quote was found.
+func (r *repairer) readString(quote byte) (string, bool) {
+	var b strings.Builder
+	r.pos++
+	for r.pos < len(r.src) {
+		c := r.src[r.pos]
+		switch {
+		case c == quote:
+			r.pos++
+			return b.String(), true
+		case c == '\\' && r.pos+1 < len(r.src):
+			r.pos++
+			switch e := r.src[r.pos]; e {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'u':
+				if r.pos+4 < len(r.src) {
+					if n, err := strconv.ParseUint(r.src[r.pos+1:r.pos+5], 16, 32); err == nil {
+						r.pos += 4
+						b.WriteRune(r.surrogatePair(rune(n)))
+						break
+					}
+				}
+				b.WriteByte('u')
+			default:
+				b.WriteByte(e)
+			}
+			r.pos++
+		case c == '\\':
+			r.pos++
+		default:
+			b.WriteByte(c)
+			r.pos++
+		}
+	}
+	return b.String(), false
+}
+
+// surrogatePair returns c or, when c is the high half of a UTF-16
+// surrogate pair and the next \u escape holds the low half, the character
+// they encode, consuming that escape. r.pos is at the last digit of c.
+func (r *repairer) surrogatePair(c rune) rune {
+	if !utf16.IsSurrogate(c) || !strings.HasPrefix(r.src[r.pos+1:], `\u`) || r.pos+6 >= len(r.src) {
+		return c
+	}
+	n, err := strconv.ParseUint(r.src[r.pos+3:r.pos+7], 16, 32)
+	if err != nil {
+		return c
+	}
+	if d := utf16.DecodeRune(c, rune(n)); d != unicode.ReplacementChar {
+		r.pos += 6
+		return d
+	}
+	return c
+}
+
+// readWord reads a run of characters that may form a bare key, a number or
+// a literal.
+func (r *repairer) readWord() string {
+	start := r.pos
+	for r.pos < len(r.src) {
+		c := r.src[r.pos]
+		if c == '_' || c == '$' || c == '.' || c == '+' || c == '-' ||
+			(c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
+			r.pos++
+			continue
+		}
+		break
+	}
+	return r.src[start:r.pos]
+}
+
+// literal maps a bare word to its JSON form, if it is a number or a known
+// literal.
+func literal(word string) (string, bool) {
+	switch word {
+	case "true", "True", "TRUE":
+		return "true", true
+	case "false", "False", "FALSE":
+		return "false", true
+	case "null", "None", "nil", "NULL", "undefined", "NaN":
+		return "null", true
+	}
+	if json.Valid([]byte(word)) {
+		return word, true
+	}
+	if f, err := strconv.ParseFloat(word, 64); err == nil && !strings.ContainsAny(word, "xXpP_") {
+		return strconv.FormatFloat(f, 'g', -1, 64), true
+	}
+	return "", false
+}
@@ -0,0 +1,58 @@
+package parse
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRepairJSON(t *testing.T) {
+	testCases := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "valid", in: `{"a": [1, 2.5, "x"], "b": {"c": null}}`, want: `{"a":[1,2.5,"x"],"b":{"c":null}}`},
+		{name: "trailing commas", in: `{"a": [1, 2,], "b": 3,}`, want: `{"a":[1,2],"b":3}`},
+		{name: "single quotes", in: `{'a': 'it\'s "ok"'}`, want: `{"a":"it's \"ok\""}`},
+		{name: "unquoted keys", in: `{name: "Ada", age: 36}`, want: `{"name":"Ada","age":36}`},
+		{name: "python literals", in: `{"a": True, "b": None, "c": False}`, want: `{"a":true,"b":null,"c":false}`},
+		{name: "comments", in: "{\"a\": 1, // one\n /* two */ \"b\": 2}", want: `{"a":1,"b":2}`},
+		{name: "missing commas and colon", in: `{"a" 1 "b": [1 2]}`, want: `{"a":1,"b":[1,2]}`},
+		{name: "trailing prose", in: `[1, 2] and that's it`, want: `[1,2]`},
+		{name: "truncated string", in: `{"a": "hel`, want: `{"a":"hel"}`},
+		{name: "truncated key", in: `{"a": 1, "b`, want: `{"a":1}`},
+		{name: "key without value", in: `{"a": 1, "b":`, want: `{"a":1,"b":null}`},
+		{name: "truncated literal", in: `{"a": [tr`, want: `{"a":[]}`},
+		{name: "truncated number", in: `[1, 2.`, want: `[1]`},
+		{name: "nested open", in: `{"a": [{"b": 1}, {"c": [`, want: `{"a":[{"b":1},{"c":[]}]}`},
+		{name: "bare string value", in: `{"status": ok}`, want: `{"status":"ok"}`},
+		{name: "unicode escapes", in: `['caf\u00e9 \ud83d\ude00', "\ud83d\u0041"]`, want: "[\"café 😀\",\"\uFFFDA\"]"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := RepairJSON(tc.in); got != tc.want {
+				t.Errorf("RepairJSON(%q) = %s, want %s", tc.in, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestExtractJSON(t *testing.T) {
+	text := "Here you go:\n```json\n{'items': ['a', 'b',],}\n```\nAnything else? {\"not\": \"this\"}"
+	raw, err := ExtractJSON(text)
+	if err != nil {
+		t.Fatalf("ExtractJSON() error = %v", err)
+	}
+	if string(raw) != `{"items":["a","b"]}` {
+		t.Errorf("ExtractJSON() = %s", raw)
+	}
+
+	var v struct{ Answer int }
+	if err := DecodeJSON(`The result is {"Answer": 42}.`, &v); err != nil || v.Answer != 42 {
+		t.Errorf("DecodeJSON() = %+v, %v; want Answer 42", v, err)
+	}
+
+	if _, err := ExtractJSON("no data here"); !errors.Is(err, ErrNoJSON) {
+		t.Errorf("ExtractJSON() error = %v, want ErrNoJSON", err)
+	}
+}
@@ -0,0 +1,125 @@
+package parse
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/tnotstar/go-minolas/pkg/ai/generators"
+)
+
+// JSONStreamParser parses a JSON value that arrives in pieces, as with a
+// streamed model response. After every piece it repairs the text received
+// so far with RepairJSON, so callers can show partial objects while the
+// model is still writing them. The value is looked for as in ExtractJSON:
+// inside the first block fenced as "json", once one is opened, or else
+// from the first "{" or "[".
+type JSONStreamParser struct {
+	buf  strings.Builder
+	last string
+}
+
+// NewJSONStreamParser returns an empty JSONStreamParser.
+func NewJSONStreamParser() *JSONStreamParser {
+	return &JSONStreamParser{}
+}
+
+// Write appends text and returns the repaired JSON of everything received
+// so far. changed is false if the value is the same as after the previous
+// call or if no JSON has started yet.
+func (p *JSONStreamParser) Write(text string) (raw json.RawMessage, changed bool) {
+	p.buf.WriteString(text)
+	s, fenced := jsonSource(p.buf.String())
+	if !fenced && endsInFence(s) {
+		// The language of the block being opened is not known yet.
+		return json.RawMessage(p.last), false
+	}
+	start := strings.IndexAny(s, "{[")
+	if start < 0 {
+		return nil, false
+	}
+	repaired := RepairJSON(s[start:])
+	if repaired == p.last || !json.Valid([]byte(repaired)) {
+		return json.RawMessage(p.last), false
+	}
+	p.last = repaired
+	return json.RawMessage(repaired), true
+}
+
+// endsInFence reports whether the last line of s, still being written,
+// starts with a code fence.
+func endsInFence(s string) bool {
+	line := s[strings.LastIndexByte(s, '\n')+1:]
+	return fencePrefix(strings.TrimLeft(line, " ")) != ""
+}
+
+// Text returns all the text written so far.
+func (p *JSONStreamParser) Text() string {
+	return p.buf.String()
+}
+
+// PartialJSON is a snapshot of a JSON value being streamed.
+// Raw holds the repaired JSON and Value its decoded form. The last snapshot
+// has Done set; Err is set if the stream failed or held no JSON.
+type PartialJSON struct {
+	Raw   json.RawMessage
+	Value any
+	Done  bool
+	Err   error
+}
+
+// StreamJSON reads a generator stream and yields a PartialJSON every time
+// the JSON value in the text received so far changes, followed by a final
+// snapshot with Done set. Thought chunks are ignored. The returned channel
+// is closed after the final snapshot; callers must drain it.
+//
+// Example:
+//
+//	ch, err := gen.Stream(ctx, "List three colors as a JSON array.")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	for p := range parse.StreamJSON(ch) {
+//	    if p.Err != nil {
+//	        log.Fatal(p.Err)
+//	    }
+//	    fmt.Println(string(p.Raw))
+//	}
+func StreamJSON(in <-chan generators.StreamChunk) <-chan PartialJSON {
+	out := make(chan PartialJSON)
+	go func() {
+		defer close(out)
+		p := NewJSONStreamParser()
+		var streamErr error
+		for chunk := range in {
+			if chunk.Error != nil {
+				streamErr = chunk.Error
+				continue
+			}
+			if chunk.Text == "" {
+				continue
+			}
+			if raw, changed := p.Write(chunk.Text); changed {
+				out <- decodePartial(raw)
+			}
+		}
+
+		final := PartialJSON{Done: true, Err: streamErr}
+		if raw, err := ExtractJSON(p.Text()); err == nil {
+			part := decodePartial(raw)
+			final.Raw, final.Value = part.Raw, part.Value
+		} else if final.Err == nil {
+			final.Err = err
+		}
+		out <- final
+	}()
+	return out
+}
+
+// decodePartial decodes a repaired JSON snapshot.
+func decodePartial(raw json.RawMessage) PartialJSON {
+	p := PartialJSON{Raw: raw}
+	if err := json.Unmarshal(raw, &p.Value); err != nil {
+		p.Err = err
+	}
+	return p
+}
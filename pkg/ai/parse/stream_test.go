@@ -0,0 +1,94 @@
+package parse
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/tnotstar/go-minolas/pkg/ai/generators"
+)
+
+func TestJSONStreamParser(t *testing.T) {
+	p := NewJSONStreamParser()
+	steps := []struct {
+		text    string
+		want    string
+		changed bool
+	}{
+		{text: "Sure:\n```json\n", want: "", changed: false},
+		{text: `{"name": "Ad`, want: `{"name":"Ad"}`, changed: true},
+		{text: `a", "tags": [`, want: `{"name":"Ada","tags":[]}`, changed: true},
+		{text: ` `, want: `{"name":"Ada","tags":[]}`, changed: false},
+		{text: `"x", "y"]}` + "\n```", want: `{"name":"Ada","tags":["x","y"]}`, changed: true},
+	}
+	for i, s := range steps {
+		raw, changed := p.Write(s.text)
+		if string(raw) != s.want || changed != s.changed {
+			t.Errorf("step %d: Write(%q) = %s, %v; want %s, %v", i, s.text, raw, changed, s.want, s.changed)
+		}
+	}
+}
+
+func TestJSONStreamParser_FenceAfterProse(t *testing.T) {
+	p := NewJSONStreamParser()
+	steps := []struct {
+		text    string
+		want    string
+		changed bool
+	}{
+		{text: "See [1] below:\n", want: `[1]`, changed: true},
+		{text: "```js", want: `[1]`, changed: false},
+		{text: "on\n{\"a\": [2,", want: `{"a":[2]}`, changed: true},
+		{text: " 3]}\n```\nAs noted in [1].", want: `{"a":[2,3]}`, changed: true},
+	}
+	for i, s := range steps {
+		raw, changed := p.Write(s.text)
+		if string(raw) != s.want || changed != s.changed {
+			t.Errorf("step %d: Write(%q) = %s, %v; want %s, %v", i, s.text, raw, changed, s.want, s.changed)
+		}
+	}
+	if raw, err := ExtractJSON(p.Text()); err != nil || string(raw) != `{"a":[2,3]}` {
+		t.Errorf("ExtractJSON() = %s, %v; want the streamed value", raw, err)
+	}
+}
+
+func TestStreamJSON(t *testing.T) {
+	in := make(chan generators.StreamChunk)
+	go func() {
+		defer close(in)
+		in <- generators.StreamChunk{Thought: "{thinking}"}
+		in <- generators.StreamChunk{Text: `[1, 2`}
+		in <- generators.StreamChunk{Text: `, 3]`}
+		in <- generators.StreamChunk{Usage: &generators.Usage{TotalTokens: 3}}
+	}()
+
+	var parts []PartialJSON
+	for p := range StreamJSON(in) {
+		parts = append(parts, p)
+	}
+	if len(parts) != 3 {
+		t.Fatalf("got %d snapshots, want 3: %+v", len(parts), parts)
+	}
+	if string(parts[0].Raw) != "[1]" || string(parts[1].Raw) != "[1,2,3]" {
+		t.Errorf("partials = %s, %s; want [1], [1,2,3]", parts[0].Raw, parts[1].Raw)
+	}
+	final := parts[2]
+	if !final.Done || final.Err != nil || len(final.Value.([]any)) != 3 {
+		t.Errorf("final = %+v", final)
+	}
+}
+
+func TestStreamJSON_Errors(t *testing.T) {
+	boom := errors.New("boom")
+	in := make(chan generators.StreamChunk, 2)
+	in <- generators.StreamChunk{Text: "no json"}
+	in <- generators.StreamChunk{Error: boom}
+	close(in)
+
+	var final PartialJSON
+	for p := range StreamJSON(in) {
+		final = p
+	}
+	if !final.Done || !errors.Is(final.Err, boom) {
+		t.Errorf("final = %+v, want Done with boom", final)
+	}
+}
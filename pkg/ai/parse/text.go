@@ -0,0 +1,168 @@
+// Package parse extracts structured data from model output.
+//
+// Models often wrap the requested data in Markdown code fences, surround it
+// with prose or produce slightly invalid JSON. The functions in this package
+// are lenient: they look for the data where models usually put it and
+// repair the most common mistakes instead of failing.
+package parse
+
+import (
+	"regexp"
+	"strings"
+)
+
+// CodeBlock is a fenced code block found in a text.
+// Start and End are the byte offsets of the whole block, fences included.
+type CodeBlock struct {
+	Language string
+	Code     string
+	Start    int
+	End      int
+}
+
+// CodeBlocks returns the fenced code blocks of text in order. Both ``` and
+// ~~~ fences are recognized. A block left open at the end of the text, as
+// happens when output is truncated, runs to the end.
+func CodeBlocks(text string) []CodeBlock {
+	var (
+		blocks []CodeBlock
+		open   *CodeBlock
+		fence  string
+		body   strings.Builder
+	)
+	for pos := 0; pos < len(text); {
+		next := len(text)
+		if j := strings.IndexByte(text[pos:], '\n'); j >= 0 {
+			next = pos + j + 1
+		}
+		line := strings.TrimRight(text[pos:next], "\r\n")
+		trimmed := strings.TrimLeft(line, " ")
+
+		switch {
+		case open == nil:
+			if f := fencePrefix(trimmed); f != "" {
+				open = &CodeBlock{Language: strings.TrimSpace(trimmed[len(f):]), Start: pos}
+				if i := strings.IndexAny(open.Language, " \t{"); i >= 0 {
+					open.Language = open.Language[:i]
+				}
+				fence = f
+				body.Reset()
+			}
+		case strings.HasPrefix(trimmed, fence) && strings.TrimLeft(trimmed, fence[:1]) == "":
+			open.Code = strings.TrimSuffix(body.String(), "\n")
+			open.End = next
+			blocks = append(blocks, *open)
+			open = nil
+		default:
+			body.WriteString(text[pos:next])
+		}
+		pos = next
+	}
+	if open != nil {
+		open.Code = strings.TrimSuffix(body.String(), "\n")
+		open.End = len(text)
+		blocks = append(blocks, *open)
+	}
+	return blocks
+}
+
+// fencePrefix returns the opening fence at the start of line, or "".
+func fencePrefix(line string) string {
+	for _, c := range []byte{'`', '~'} {
+		n := 0
+		for n < len(line) && line[n] == c {
+			n++
+		}
+		if n >= 3 {
+			return line[:n]
+		}
+	}
+	return ""
+}
+
+// FindCodeBlock returns the code of the first fenced block whose language
+// matches lang, ignoring case. An empty lang matches any block.
+func FindCodeBlock(text, lang string) (string, bool) {
+	for _, b := range CodeBlocks(text) {
+		if lang == "" || strings.EqualFold(b.Language, lang) {
+			return b.Code, true
+		}
+	}
+	return "", false
+}
+
+// listMarker matches the bullet or number that starts a list item.
+var listMarker = regexp.MustCompile(`^\s*(?:[-*+•]|\(?\d+[.)]|\(?[a-zA-Z][.)])\s+`)
+
+// ParseList returns the items of the bulleted or numbered list in text.
+// Items may be marked with "-", "*", "+", "•", "1.", "1)", "(1)" or "a.".
+// Indented lines that follow an item without a marker continue it; other
+// lines are ignored. Surrounding Markdown emphasis is removed from items.
+func ParseList(text string) []string {
+	var items []string
+	inItem := false
+	for _, line := range strings.Split(text, "\n") {
+		if loc := listMarker.FindStringIndex(line); loc != nil {
+			items = append(items, stripEmphasis(strings.TrimSpace(line[loc[1]:])))
+			inItem = true
+			continue
+		}
+		trimmed := strings.TrimSpace(line)
+		if inItem && trimmed != "" && line != strings.TrimLeft(line, " \t") {
+			items[len(items)-1] = stripEmphasis(items[len(items)-1] + " " + trimmed)
+			continue
+		}
+		inItem = false
+	}
+	return items
+}
+
+// KeyValue is a single "key: value" pair.
+type KeyValue struct {
+	Key   string
+	Value string
+}
+
+// ParseKeyValues returns the "key: value" pairs of text in order. Lines may
+// start with a list marker and keys may be wrapped in Markdown emphasis, as
+// in "- **Name**: Ada". Lines without a colon or value, and lines whose key
+// is longer than a few words, are ignored.
+func ParseKeyValues(text string) []KeyValue {
+	var pairs []KeyValue
+	for _, line := range strings.Split(text, "\n") {
+		if loc := listMarker.FindStringIndex(line); loc != nil {
+			line = line[loc[1]:]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.Trim(strings.TrimSpace(key), "*_`")
+		value = strings.TrimSpace(strings.TrimLeft(value, "*_"))
+		if key == "" || value == "" || len(strings.Fields(key)) > 5 {
+			continue
+		}
+		pairs = append(pairs, KeyValue{Key: key, Value: stripEmphasis(value)})
+	}
+	return pairs
+}
+
+// KeyValueMap is like ParseKeyValues but returns a map keyed by the
+// lower-cased key. Later pairs win over earlier ones with the same key.
+func KeyValueMap(text string) map[string]string {
+	m := make(map[string]string)
+	for _, kv := range ParseKeyValues(text) {
+		m[strings.ToLower(kv.Key)] = kv.Value
+	}
+	return m
+}
+
+// stripEmphasis removes Markdown bold or italic markers around s.
+func stripEmphasis(s string) string {
+	for _, m := range []string{"**", "__", "*", "_", "`"} {
+		if len(s) > 2*len(m) && strings.HasPrefix(s, m) && strings.HasSuffix(s, m) {
+			return strings.TrimSpace(s[len(m) : len(s)-len(m)])
+		}
+	}
+	return s
+}
@@ -0,0 +1,71 @@
+package parse
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCodeBlocks(t *testing.T) {
+	text := "Intro\n```go\nfmt.Println(1)\n```\nmiddle\n~~~~ python {.numberLines}\nprint(2)\n```\nstill python\n~~~~\n```json\n{\"open\": tru"
+	blocks := CodeBlocks(text)
+	if len(blocks) != 3 {
+		t.Fatalf("len(blocks) = %d, want 3: %+v", len(blocks), blocks)
+	}
+	if blocks[0].Language != "go" || blocks[0].Code != "fmt.Println(1)" {
+		t.Errorf("blocks[0] = %+v", blocks[0])
+	}
+	if blocks[1].Language != "python" || blocks[1].Code != "print(2)\n```\nstill python" {
+		t.Errorf("blocks[1] = %+v", blocks[1])
+	}
+	if blocks[2].Code != `{"open": tru` || blocks[2].End != len(text) {
+		t.Errorf("unclosed block = %+v", blocks[2])
+	}
+	if text[blocks[0].Start:blocks[0].End] != "```go\nfmt.Println(1)\n```\n" {
+		t.Errorf("block offsets = %d:%d", blocks[0].Start, blocks[0].End)
+	}
+
+	if code, ok := FindCodeBlock(text, "PYTHON"); !ok || !strings.HasPrefix(code, "print(2)") {
+		t.Errorf("FindCodeBlock(PYTHON) = %q, %v", code, ok)
+	}
+	if _, ok := FindCodeBlock(text, "rust"); ok {
+		t.Error("FindCodeBlock(rust) found a block")
+	}
+}
+
+func TestParseList(t *testing.T) {
+	text := "Sure! Here are the steps:\n" +
+		"1. Install Go\n" +
+		"2) Run **go build**\n" +
+		"   with the right flags\n" +
+		"- Test it\n" +
+		"* `deploy`\n" +
+		"\n" +
+		"Good luck."
+	got := strings.Join(ParseList(text), "|")
+	want := "Install Go|Run **go build** with the right flags|Test it|deploy"
+	if got != want {
+		t.Errorf("ParseList() = %q, want %q", got, want)
+	}
+	if items := ParseList("no list here"); items != nil {
+		t.Errorf("ParseList() = %q, want nil", items)
+	}
+}
+
+func TestParseKeyValues(t *testing.T) {
+	text := "Here is the data:\n" +
+		"- **Name**: Ada Lovelace\n" +
+		"Born: 1815\n" +
+		"**Known for:** the first program\n" +
+		"This long sentence has a colon in it somewhere: ignored\n"
+	pairs := ParseKeyValues(text)
+	if len(pairs) != 3 {
+		t.Fatalf("pairs = %+v, want 3", pairs)
+	}
+	if pairs[0] != (KeyValue{Key: "Name", Value: "Ada Lovelace"}) {
+		t.Errorf("pairs[0] = %+v", pairs[0])
+	}
+	m := KeyValueMap(text)
+	if m["born"] != "1815" || m["known for"] != "the first program" {
+		t.Errorf("KeyValueMap() = %v", m)
+	}
+}
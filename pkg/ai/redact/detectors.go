@@ -0,0 +1,230 @@
+package redact
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// Kinds of personal data found by the built-in detectors. The kind names
+// the placeholders that replace the data, as in "[EMAIL_1]".
+const (
+	KindEmail = "EMAIL"
+	KindPhone = "PHONE"
+	KindCard  = "CARD"
+	KindSSN   = "SSN"
+	KindDNI   = "DNI"
+	KindIBAN  = "IBAN"
+)
+
+// Detector finds one kind of personal data. Pattern finds candidates and
+// Validate, if set, rejects the ones that fail a checksum or other rule.
+// When a candidate is rejected, the shorter candidates Pattern matches at
+// the same position are validated too, so that a number followed by more
+// digits, such as a card number followed by its CVV, is still found.
+type Detector struct {
+	Kind     string
+	Pattern  *regexp.Regexp
+	Validate func(match string) bool
+}
+
+// find returns the start and end of the data d detects in text.
+func (d Detector) find(text string) [][]int {
+	var locs [][]int
+	for _, loc := range d.Pattern.FindAllStringIndex(text, -1) {
+		if d.Validate == nil || d.Validate(text[loc[0]:loc[1]]) {
+			locs = append(locs, loc)
+		} else if end := d.shorter(text, loc[0], loc[1]); end > 0 {
+			locs = append(locs, []int{loc[0], end})
+		}
+	}
+	return locs
+}
+
+// shorter returns the end of the longest valid candidate that starts at
+// start and ends before end, or 0 if there is none. A candidate must end
+// at a word boundary of text, so that it never splits a number.
+func (d Detector) shorter(text string, start, end int) int {
+	for e := end - 1; e > start; e-- {
+		if isWordChar(text[e-1]) && isWordChar(text[e]) {
+			continue
+		}
+		if loc := d.Pattern.FindStringIndex(text[start:e]); loc != nil && loc[0] == 0 && loc[1] == e-start &&
+			d.Validate(text[start:e]) {
+			return e
+		}
+	}
+	return 0
+}
+
+// isWordChar reports whether c is an ASCII word character, as matched by
+// \w.
+func isWordChar(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// kindPattern restricts kinds to names that can appear in placeholders.
+var kindPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// NewDetector returns a Detector for kind, which must be upper case
+// letters, digits and underscores, matching pattern. validate may be nil.
+func NewDetector(kind, pattern string, validate func(string) bool) (Detector, error) {
+	if !kindPattern.MatchString(kind) {
+		return Detector{}, fmt.Errorf("redact: invalid kind %q", kind)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return Detector{}, fmt.Errorf("redact: detector %s: %w", kind, err)
+	}
+	return Detector{Kind: kind, Pattern: re, Validate: validate}, nil
+}
+
+// DefaultDetectors returns the built-in detectors: e-mail addresses, card
+// numbers, IBANs, US social security numbers, Spanish DNI and NIE numbers
+// and phone numbers. When matches overlap, detectors earlier in the list
+// win, so the phone detector, which is the least specific, comes last.
+func DefaultDetectors() []Detector {
+	return []Detector{Email(), Card(), IBAN(), SSN(), DNI(), Phone()}
+}
+
+// Email detects e-mail addresses.
+func Email() Detector {
+	return Detector{
+		Kind:    KindEmail,
+		Pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`),
+	}
+}
+
+// Card detects payment card numbers of 13 to 19 digits, optionally
+// grouped with spaces or dashes, that pass the Luhn check.
+func Card() Detector {
+	return Detector{
+		Kind:    KindCard,
+		Pattern: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+		Validate: func(s string) bool {
+			return Luhn(digits(s))
+		},
+	}
+}
+
+// IBAN detects international bank account numbers, optionally grouped in
+// blocks of four, that pass the ISO 7064 mod-97 check.
+func IBAN() Detector {
+	return Detector{
+		Kind:    KindIBAN,
+		Pattern: regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,3})?\b`),
+		Validate: func(s string) bool {
+			return validIBAN(strings.ReplaceAll(s, " ", ""))
+		},
+	}
+}
+
+// SSN detects US social security numbers written as AAA-GG-SSSS, skipping
+// the area, group and serial numbers that are never assigned.
+func SSN() Detector {
+	return Detector{
+		Kind:    KindSSN,
+		Pattern: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
+		Validate: func(s string) bool {
+			area, group, serial := s[:3], s[4:6], s[7:]
+			return area != "000" && area != "666" && area[0] != '9' && group != "00" && serial != "0000"
+		},
+	}
+}
+
+// dniLetters are the check letters of Spanish DNI and NIE numbers.
+const dniLetters = "TRWAGMYFPDXBNJZSQVHLCKE"
+
+// DNI detects Spanish national identity (DNI) and foreigner (NIE) numbers
+// with a valid check letter.
+func DNI() Detector {
+	return Detector{
+		Kind:    KindDNI,
+		Pattern: regexp.MustCompile(`\b(?:\d{8}|[XYZ]\d{7})-?[A-Z]\b`),
+		Validate: func(s string) bool {
+			s = strings.ReplaceAll(s, "-", "")
+			num := strings.NewReplacer("X", "0", "Y", "1", "Z", "2").Replace(s[:len(s)-1])
+			n := 0
+			for _, c := range num {
+				n = n*10 + int(c-'0')
+			}
+			return dniLetters[n%23] == s[len(s)-1]
+		},
+	}
+}
+
+// isoDate matches text that starts like an ISO date, which the phone
+// pattern would otherwise accept.
+var isoDate = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}`)
+
+// Phone detects phone numbers of 9 to 15 digits, with an optional
+// international prefix and digits grouped with spaces, dots, dashes or
+// parentheses. Detection is heuristic: it favors catching numbers over
+// precision, so other long grouped numbers may be redacted too.
+func Phone() Detector {
+	return Detector{
+		Kind:    KindPhone,
+		Pattern: regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{1,4}\)[ .-]?)?\b\d{2,4}(?:[ .-]?\d{2,4}){2,5}\b`),
+		Validate: func(s string) bool {
+			n := len(digits(s))
+			return n >= 9 && n <= 15 && !isoDate.MatchString(s)
+		},
+	}
+}
+
+// Luhn reports whether the decimal digits in s pass the Luhn checksum used
+// by payment card numbers.
+func Luhn(s string) bool {
+	if len(s) < 2 {
+		return false
+	}
+	sum := 0
+	double := false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			return false
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// validIBAN reports whether s, without spaces, passes the mod-97 check.
+func validIBAN(s string) bool {
+	if len(s) < 15 || len(s) > 34 {
+		return false
+	}
+	var b strings.Builder
+	for _, c := range s[4:] + s[:4] {
+		switch {
+		case c >= '0' && c <= '9':
+			b.WriteRune(c)
+		case c >= 'A' && c <= 'Z':
+			fmt.Fprint(&b, int(c-'A')+10)
+		default:
+			return false
+		}
+	}
+	n, ok := new(big.Int).SetString(b.String(), 10)
+	return ok && new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+// digits returns the decimal digits of s.
+func digits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
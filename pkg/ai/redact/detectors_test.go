@@ -0,0 +1,69 @@
+package redact
+
+import "testing"
+
+func TestLuhn(t *testing.T) {
+	for s, want := range map[string]bool{
+		"4111111111111111": true,
+		"5500005555555559": true,
+		"378282246310005":  true,
+		"4111111111111112": false,
+		"1":                false,
+		"41111111a1111111": false,
+	} {
+		if got := Luhn(s); got != want {
+			t.Errorf("Luhn(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestDetectors(t *testing.T) {
+	tests := []struct {
+		detector Detector
+		text     string
+		want     []string
+	}{
+		{Email(), "mail ada.lovelace+ai@math.example.co.uk or bob@x.io.", []string{"ada.lovelace+ai@math.example.co.uk", "bob@x.io"}},
+		{Card(), "card 4111 1111 1111 1111, fake 4111-1111-1111-1112, amex 378282246310005", []string{"4111 1111 1111 1111", "378282246310005"}},
+		{Card(), "card 4111 1111 1111 1111 123 and 4111-1111-1111-1111 12 times", []string{"4111 1111 1111 1111", "4111-1111-1111-1111"}},
+		{Card(), "amex 378282246310005 1234, visa 4111111111111111 99", []string{"378282246310005", "4111111111111111"}},
+		{IBAN(), "pay ES91 2100 0418 4502 0005 1332 not ES00 2100 0418 4502 0005 1332", []string{"ES91 2100 0418 4502 0005 1332"}},
+		{IBAN(), "GB29NWBK60161331926819", []string{"GB29NWBK60161331926819"}},
+		{SSN(), "ssn 123-45-6789, bad 000-12-3456 and 666-12-3456", []string{"123-45-6789"}},
+		{DNI(), "DNI 12345678Z, wrong 12345678A, NIE X1234567L", []string{"12345678Z", "X1234567L"}},
+		{Phone(), "call +34 612 345 678 or (555) 123-4567 on 2024-01-15", []string{"+34 612 345 678", "(555) 123-4567"}},
+		{Phone(), "order 12345 on 2024-01-15 10:30", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, loc := range tt.detector.find(tt.text) {
+			got = append(got, tt.text[loc[0]:loc[1]])
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s in %q: got %q, want %q", tt.detector.Kind, tt.text, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s in %q: got %q, want %q", tt.detector.Kind, tt.text, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestNewDetector(t *testing.T) {
+	d, err := NewDetector("EMPLOYEE_ID", `\bEMP-\d{5}\b`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := d.Pattern.FindString("ask EMP-00042"); got != "EMP-00042" {
+		t.Errorf("match = %q", got)
+	}
+	if _, err := NewDetector("employee", `x`, nil); err == nil {
+		t.Error("lower-case kind accepted")
+	}
+	if _, err := NewDetector("X", `(`, nil); err == nil {
+		t.Error("invalid pattern accepted")
+	}
+}
@@ -0,0 +1,133 @@
+package redact
+
+import (
+	"context"
+	"strings"
+
+	"github.com/tnotstar/go-minolas/pkg/ai/generators"
+)
+
+// Wrap returns a Generator that redacts the prompt and system instruction
+// of every request before forwarding it to gen, and restores the original
+// values in the response text, thoughts and candidates, and in streamed
+// chunks. Byte offsets reported by the provider, such as those of
+// citations, refer to the redacted text.
+func (r *Redactor) Wrap(gen generators.Generator) generators.Generator {
+	return &redactedGenerator{gen: gen, redactor: r}
+}
+
+// redactedGenerator is the Generator middleware returned by Redactor.Wrap.
+type redactedGenerator struct {
+	gen      generators.Generator
+	redactor *Redactor
+}
+
+// redact redacts the request and returns the options to send with it.
+func (g *redactedGenerator) redact(prompt string, opts []generators.Option) (string, []generators.Option, *Mapping) {
+	m := &Mapping{}
+	prompt = g.redactor.Redact(prompt, m)
+
+	cfg := &generators.Config{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.SystemInstruction != "" {
+		if sys := g.redactor.Redact(cfg.SystemInstruction, m); sys != cfg.SystemInstruction {
+			opts = append(opts[:len(opts):len(opts)], generators.WithSystemInstruction(sys))
+		}
+	}
+	if g.redactor.report != nil && m.Len() > 0 {
+		g.redactor.report(m.Counts())
+	}
+	return prompt, opts, m
+}
+
+// Generate redacts the request, forwards it and restores the response.
+func (g *redactedGenerator) Generate(ctx context.Context, prompt string, opts ...generators.Option) (*generators.Response, error) {
+	prompt, opts, m := g.redact(prompt, opts)
+	resp, err := g.gen.Generate(ctx, prompt, opts...)
+	if err != nil || m.Len() == 0 {
+		return resp, err
+	}
+	resp.Text = m.Restore(resp.Text)
+	resp.Thoughts = m.Restore(resp.Thoughts)
+	for i := range resp.Candidates {
+		c := &resp.Candidates[i]
+		c.Text = m.Restore(c.Text)
+		c.Thoughts = m.Restore(c.Thoughts)
+	}
+	return resp, nil
+}
+
+// Stream redacts the request, forwards it and restores the streamed text.
+// A placeholder split across chunks is held back until it is complete.
+func (g *redactedGenerator) Stream(ctx context.Context, prompt string, opts ...generators.Option) (<-chan generators.StreamChunk, error) {
+	prompt, opts, m := g.redact(prompt, opts)
+	in, err := g.gen.Stream(ctx, prompt, opts...)
+	if err != nil || m.Len() == 0 {
+		return in, err
+	}
+
+	out := make(chan generators.StreamChunk)
+	go func() {
+		defer close(out)
+		text := &restorer{m: m, max: m.maxPlaceholder()}
+		thought := &restorer{m: m, max: m.maxPlaceholder()}
+		flush := func() {
+			if t := thought.flush(); t != "" {
+				out <- generators.StreamChunk{Thought: t}
+			}
+			if t := text.flush(); t != "" {
+				out <- generators.StreamChunk{Text: t}
+			}
+		}
+		for chunk := range in {
+			switch {
+			case chunk.Text != "" || chunk.Thought != "":
+				chunk.Thought = thought.write(chunk.Thought)
+				chunk.Text = text.write(chunk.Text)
+				if chunk.Text == "" && chunk.Thought == "" && chunk.Error == nil && chunk.Usage == nil {
+					continue // everything held back
+				}
+			default:
+				flush()
+			}
+			out <- chunk
+		}
+		flush()
+	}()
+	return out, nil
+}
+
+// Close closes the wrapped generator.
+func (g *redactedGenerator) Close() error {
+	return g.gen.Close()
+}
+
+// restorer restores placeholders in a stream of text, holding back a
+// trailing "[" that may start a placeholder completed by the next chunk.
+type restorer struct {
+	m       *Mapping
+	max     int
+	pending string
+}
+
+// write adds s to the stream and returns the text that can be emitted.
+func (r *restorer) write(s string) string {
+	if s == "" {
+		return ""
+	}
+	buf := r.pending + s
+	r.pending = ""
+	if i := strings.LastIndexByte(buf, '['); i >= 0 && !strings.Contains(buf[i:], "]") && len(buf)-i < r.max {
+		buf, r.pending = buf[:i], buf[i:]
+	}
+	return r.m.Restore(buf)
+}
+
+// flush returns the text held back.
+func (r *restorer) flush() string {
+	s := r.pending
+	r.pending = ""
+	return r.m.Restore(s)
+}
@@ -0,0 +1,184 @@
+// Package redact keeps personal data out of requests sent to hosted
+// providers.
+//
+// A Redactor finds personal data such as e-mail addresses, phone numbers,
+// card numbers and national IDs with a list of Detectors, and replaces each
+// distinct value with a placeholder like "[EMAIL_1]". The placeholders and
+// the values they stand for are kept in a Mapping, which restores the
+// originals in the model's answer. Wrap applies this to every request of a
+// generator:
+//
+//	r := redact.New()
+//	gen = r.Wrap(gen)
+//	resp, err := gen.Generate(ctx, "Write to ada@example.com about card 4111 1111 1111 1111")
+//	// The provider sees "Write to [EMAIL_1] about card [CARD_1]"; resp.Text
+//	// contains the original address and number again.
+package redact
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Option configures a Redactor.
+type Option func(*Redactor)
+
+// WithDetectors replaces the default detectors. Detectors earlier in the
+// list win when matches overlap.
+func WithDetectors(detectors ...Detector) Option {
+	return func(r *Redactor) { r.detectors = detectors }
+}
+
+// WithExtraDetectors adds detectors to be tried before the configured ones.
+func WithExtraDetectors(detectors ...Detector) Option {
+	return func(r *Redactor) { r.detectors = append(slices.Clone(detectors), r.detectors...) }
+}
+
+// WithReport calls fn with the number of values redacted per kind after
+// each wrapped request that redacted anything. The values themselves are
+// never passed to fn.
+func WithReport(fn func(counts map[string]int)) Option {
+	return func(r *Redactor) { r.report = fn }
+}
+
+// Redactor replaces personal data with reversible placeholders.
+// It is safe for concurrent use.
+type Redactor struct {
+	detectors []Detector
+	report    func(map[string]int)
+}
+
+// New returns a Redactor using DefaultDetectors, configured by opts.
+func New(opts ...Option) *Redactor {
+	r := &Redactor{detectors: DefaultDetectors()}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// match is a detected value in a text.
+type match struct {
+	start, end int
+	kind       string
+	rank       int // index of the detector, for precedence
+}
+
+// Redact replaces the personal data in text with placeholders recorded in
+// m, reusing the placeholder of a value already in m. Use one Mapping for
+// all the texts of a request so that the same value gets the same
+// placeholder everywhere.
+func (r *Redactor) Redact(text string, m *Mapping) string {
+	var matches []match
+	for rank, d := range r.detectors {
+		for _, loc := range d.find(text) {
+			matches = append(matches, match{start: loc[0], end: loc[1], kind: d.Kind, rank: rank})
+		}
+	}
+	if len(matches) == 0 {
+		return text
+	}
+	slices.SortFunc(matches, func(a, b match) int {
+		return cmp.Or(cmp.Compare(a.rank, b.rank), cmp.Compare(a.start, b.start))
+	})
+
+	// Accept matches by precedence, skipping those overlapping an
+	// accepted one.
+	var accepted []match
+	for _, mt := range matches {
+		if !slices.ContainsFunc(accepted, func(a match) bool { return mt.start < a.end && a.start < mt.end }) {
+			accepted = append(accepted, mt)
+		}
+	}
+	slices.SortFunc(accepted, func(a, b match) int { return cmp.Compare(a.start, b.start) })
+
+	var b strings.Builder
+	last := 0
+	for _, mt := range accepted {
+		b.WriteString(text[last:mt.start])
+		b.WriteString(m.placeholder(mt.kind, text[mt.start:mt.end]))
+		last = mt.end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// placeholderPattern matches placeholders created by a Mapping.
+var placeholderPattern = regexp.MustCompile(`\[[A-Z][A-Z0-9_]*_\d+\]`)
+
+// Mapping records the placeholders of a request and the values they
+// replace. The zero value is ready to use and it is safe for concurrent
+// use.
+type Mapping struct {
+	mu      sync.Mutex
+	values  map[string]string // placeholder -> value
+	reverse map[string]string // kind + "\x00" + value -> placeholder
+	counts  map[string]int    // kind -> placeholders created
+	longest int
+}
+
+// placeholder returns the placeholder for value, creating it if needed.
+func (m *Mapping) placeholder(kind, value string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := kind + "\x00" + value
+	if p, ok := m.reverse[key]; ok {
+		return p
+	}
+	if m.values == nil {
+		m.values = make(map[string]string)
+		m.reverse = make(map[string]string)
+		m.counts = make(map[string]int)
+	}
+	m.counts[kind]++
+	p := fmt.Sprintf("[%s_%d]", kind, m.counts[kind])
+	m.values[p] = value
+	m.reverse[key] = p
+	m.longest = max(m.longest, len(p))
+	return p
+}
+
+// Len returns the number of distinct values redacted.
+func (m *Mapping) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.values)
+}
+
+// Counts returns the number of distinct values redacted per kind.
+func (m *Mapping) Counts() map[string]int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	counts := make(map[string]int, len(m.counts))
+	for k, v := range m.counts {
+		counts[k] = v
+	}
+	return counts
+}
+
+// Restore replaces the placeholders of m in text with the original
+// values. Unknown placeholders are left as they are.
+func (m *Mapping) Restore(text string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.values) == 0 {
+		return text
+	}
+	return placeholderPattern.ReplaceAllStringFunc(text, func(p string) string {
+		if v, ok := m.values[p]; ok {
+			return v
+		}
+		return p
+	})
+}
+
+// maxPlaceholder returns the length of the longest placeholder.
+func (m *Mapping) maxPlaceholder() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.longest
+}
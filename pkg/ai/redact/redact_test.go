@@ -0,0 +1,157 @@
+package redact
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/tnotstar/go-minolas/pkg/ai/generators"
+)
+
+func TestRedactAndRestore(t *testing.T) {
+	r := New()
+	m := &Mapping{}
+	text := "Ada (ada@example.com, +34 612 345 678) paid with 4111 1111 1111 1111. Reply to ada@example.com."
+	got := r.Redact(text, m)
+	want := "Ada ([EMAIL_1], [PHONE_1]) paid with [CARD_1]. Reply to [EMAIL_1]."
+	if got != want {
+		t.Fatalf("Redact = %q, want %q", got, want)
+	}
+	if m.Len() != 3 || m.Counts()[KindEmail] != 1 {
+		t.Errorf("mapping len = %d, counts = %v", m.Len(), m.Counts())
+	}
+	if back := m.Restore(got + " [EMAIL_9]"); back != text+" [EMAIL_9]" {
+		t.Errorf("Restore = %q", back)
+	}
+
+	// A second text of the same request reuses the placeholders.
+	if got := r.Redact("bob@example.com and ada@example.com", m); got != "[EMAIL_2] and [EMAIL_1]" {
+		t.Errorf("second Redact = %q", got)
+	}
+}
+
+func TestRedactCardFollowedByDigits(t *testing.T) {
+	r := New()
+	for text, want := range map[string]string{
+		"card 4111 1111 1111 1111 123":     "card [CARD_1] 123",
+		"pay 4111-1111-1111-1111 12 times": "pay [CARD_1] 12 times",
+	} {
+		if got := r.Redact(text, &Mapping{}); got != want {
+			t.Errorf("Redact(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestRedactPrecedence(t *testing.T) {
+	emp, _ := NewDetector("EMPLOYEE", `\b\d{3}-\d{2}-\d{4}\b`, nil)
+	r := New(WithExtraDetectors(emp))
+	if got := r.Redact("id 123-45-6789", &Mapping{}); got != "id [EMPLOYEE_1]" {
+		t.Errorf("extra detector did not win: %q", got)
+	}
+
+	r = New(WithDetectors(Email()))
+	if got := r.Redact("a@b.io 4111 1111 1111 1111", &Mapping{}); got != "[EMAIL_1] 4111 1111 1111 1111" {
+		t.Errorf("WithDetectors = %q", got)
+	}
+}
+
+// echoGenerator answers with a fixed text, split into chunks when
+// streaming, and records the request.
+type echoGenerator struct {
+	answer []string
+	prompt string
+	system string
+}
+
+func (g *echoGenerator) record(prompt string, opts []generators.Option) {
+	cfg := &generators.Config{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	g.prompt, g.system = prompt, cfg.SystemInstruction
+}
+
+func (g *echoGenerator) Generate(ctx context.Context, prompt string, opts ...generators.Option) (*generators.Response, error) {
+	g.record(prompt, opts)
+	text := strings.Join(g.answer, "")
+	return &generators.Response{
+		Text:       text,
+		Candidates: []generators.Candidate{{Text: text}, {Text: "alt " + text}},
+	}, nil
+}
+
+func (g *echoGenerator) Stream(ctx context.Context, prompt string, opts ...generators.Option) (<-chan generators.StreamChunk, error) {
+	g.record(prompt, opts)
+	ch := make(chan generators.StreamChunk, len(g.answer)+1)
+	for _, s := range g.answer {
+		ch <- generators.StreamChunk{Text: s}
+	}
+	ch <- generators.StreamChunk{Usage: &generators.Usage{TotalTokens: 3}}
+	close(ch)
+	return ch, nil
+}
+
+func (g *echoGenerator) Close() error { return nil }
+
+func TestWrapGenerate(t *testing.T) {
+	inner := &echoGenerator{answer: []string{"Sent to [EMAIL_1]."}}
+	var reported map[string]int
+	gen := New(WithReport(func(c map[string]int) { reported = c })).Wrap(inner)
+
+	resp, err := gen.Generate(context.Background(), "Email ada@example.com",
+		generators.WithSystemInstruction("The user is ada@example.com, SSN 123-45-6789."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inner.prompt != "Email [EMAIL_1]" || inner.system != "The user is [EMAIL_1], SSN [SSN_1]." {
+		t.Errorf("provider saw prompt %q, system %q", inner.prompt, inner.system)
+	}
+	if resp.Text != "Sent to ada@example.com." || resp.Candidates[1].Text != "alt Sent to ada@example.com." {
+		t.Errorf("response not restored: %+v", resp)
+	}
+	if reported[KindEmail] != 1 || reported[KindSSN] != 1 {
+		t.Errorf("report = %v", reported)
+	}
+}
+
+func TestWrapStream(t *testing.T) {
+	inner := &echoGenerator{answer: []string{"Card [CA", "RD_1] and mail [", "EMAIL_1", "] ok [x", "] [EMA"}}
+	gen := New().Wrap(inner)
+
+	ch, err := gen.Stream(context.Background(), "card 4111 1111 1111 1111, mail ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var text strings.Builder
+	var usage *generators.Usage
+	for chunk := range ch {
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+			if !strings.HasSuffix(text.String(), "[EMA") {
+				t.Error("held-back text not flushed before the usage chunk")
+			}
+		}
+		if strings.Contains(chunk.Text, "[CARD_1]") || strings.Contains(chunk.Text, "[EMAIL_1]") {
+			t.Errorf("placeholder leaked in chunk %q", chunk.Text)
+		}
+		text.WriteString(chunk.Text)
+	}
+	want := "Card 4111 1111 1111 1111 and mail ada@example.com ok [x] [EMA"
+	if text.String() != want {
+		t.Errorf("streamed text = %q, want %q", text.String(), want)
+	}
+	if usage == nil || usage.TotalTokens != 3 {
+		t.Errorf("usage = %+v", usage)
+	}
+}
+
+func TestWrapNothingToRedact(t *testing.T) {
+	inner := &echoGenerator{answer: []string{"[EMAIL_1] stays"}}
+	resp, err := New().Wrap(inner).Generate(context.Background(), "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inner.prompt != "hello" || resp.Text != "[EMAIL_1] stays" {
+		t.Errorf("prompt %q, text %q", inner.prompt, resp.Text)
+	}
+}
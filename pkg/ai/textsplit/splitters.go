@@ -0,0 +1,154 @@
+package textsplit
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// NewRecursive returns a splitter that cuts text at the coarsest separator
+// that yields pieces short enough: paragraphs first, then lines, words and
+// characters (see WithSeparators). Pieces are then packed into chunks of up
+// to the chunk size.
+func NewRecursive(opts ...Option) Splitter {
+	return &recursiveSplitter{cfg: newConfig(opts)}
+}
+
+type recursiveSplitter struct {
+	cfg *config
+}
+
+// Split implements Splitter.
+func (s *recursiveSplitter) Split(text string) []Chunk {
+	return s.cfg.merge(text, s.cfg.pieces(text, 0, len(text), s.cfg.separators), nil)
+}
+
+// NewTokenBudget returns a recursive splitter whose chunks hold at most
+// maxTokens tokens as counted by counter. Overlap set with WithOverlap is
+// measured in tokens too.
+func NewTokenBudget(counter LengthFunc, maxTokens int, opts ...Option) Splitter {
+	opts = append(opts, WithLengthFunc(counter), WithChunkSize(maxTokens))
+	return NewRecursive(opts...)
+}
+
+// NewSentence returns a splitter that packs whole sentences into chunks.
+// A sentence ends after '.', '!' or '?' (and any closing quotes or
+// brackets) followed by white space, or at a blank line. Sentences longer
+// than the chunk size are split at lines and words.
+func NewSentence(opts ...Option) Splitter {
+	return &sentenceSplitter{cfg: newConfig(opts)}
+}
+
+type sentenceSplitter struct {
+	cfg *config
+}
+
+// Split implements Splitter.
+func (s *sentenceSplitter) Split(text string) []Chunk {
+	var pieces []span
+	for _, sent := range sentences(text) {
+		pieces = append(pieces, s.cfg.pieces(text, sent.start, sent.end, defaultSeparators[1:])...)
+	}
+	return s.cfg.merge(text, pieces, nil)
+}
+
+// sentences returns contiguous spans covering text, one per sentence. The
+// white space after a sentence belongs to it.
+func sentences(text string) []span {
+	var out []span
+	start := 0
+	for i := 0; i < len(text); {
+		r, n := utf8.DecodeRuneInString(text[i:])
+		i += n
+		terminal := r == '.' || r == '!' || r == '?'
+		if !terminal && !(r == '\n' && strings.HasPrefix(text[i:], "\n")) {
+			continue
+		}
+		for i < len(text) {
+			r, n := utf8.DecodeRuneInString(text[i:])
+			if !terminal || !strings.ContainsRune(".!?\"')]”’", r) {
+				break
+			}
+			i += n
+		}
+		end := i
+		for end < len(text) {
+			r, n := utf8.DecodeRuneInString(text[end:])
+			if !unicode.IsSpace(r) {
+				break
+			}
+			end += n
+		}
+		if end == i && end < len(text) {
+			continue // "3.14" or "e.g.x": not followed by white space
+		}
+		out = append(out, span{start, end})
+		start, i = end, end
+	}
+	if start < len(text) {
+		out = append(out, span{start, len(text)})
+	}
+	return out
+}
+
+// NewMarkdown returns a splitter that never mixes Markdown sections: the
+// text is first cut at ATX headers ("#" to "######"), then each section is
+// split recursively. Each chunk's Metadata holds the enclosing headers under
+// the keys "h1" to "h6". Header lines inside fenced code blocks are ignored.
+func NewMarkdown(opts ...Option) Splitter {
+	return &markdownSplitter{cfg: newConfig(opts)}
+}
+
+type markdownSplitter struct {
+	cfg *config
+}
+
+// Split implements Splitter.
+func (s *markdownSplitter) Split(text string) []Chunk {
+	var (
+		chunks  []Chunk
+		headers = map[string]string{}
+		start   = 0
+		fenced  = false
+	)
+	flush := func(end int) {
+		if start < end {
+			chunks = append(chunks, s.cfg.merge(text, s.cfg.pieces(text, start, end, s.cfg.separators), headers)...)
+		}
+		start = end
+	}
+
+	for pos := 0; pos < len(text); {
+		next := len(text)
+		if j := strings.IndexByte(text[pos:], '\n'); j >= 0 {
+			next = pos + j + 1
+		}
+		line := strings.TrimRight(text[pos:next], "\r\n")
+
+		if strings.HasPrefix(strings.TrimLeft(line, " "), "```") {
+			fenced = !fenced
+		} else if level, title, ok := parseHeader(line); ok && !fenced {
+			flush(pos)
+			for l := level; l <= 6; l++ {
+				delete(headers, "h"+strconv.Itoa(l))
+			}
+			headers["h"+strconv.Itoa(level)] = title
+		}
+		pos = next
+	}
+	flush(len(text))
+	return chunks
+}
+
+// parseHeader parses an ATX header line and returns its level and title.
+func parseHeader(line string) (level int, title string, ok bool) {
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || (level < len(line) && line[level] != ' ' && line[level] != '\t') {
+		return 0, "", false
+	}
+	title = strings.TrimSpace(strings.TrimRight(strings.TrimSpace(line[level:]), "#"))
+	return level, title, true
+}
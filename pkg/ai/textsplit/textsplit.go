@@ -0,0 +1,205 @@
+// Package textsplit splits documents into chunks suitable for embedding.
+//
+// Every splitter returns Chunks that carry their byte offsets in the source
+// text, so that search results and citations can point back to the exact
+// passage: for every chunk, source[c.Start:c.End] == c.Text.
+//
+// Chunk sizes are measured by a LengthFunc, which defaults to counting
+// characters. Pass a tokenizer with WithLengthFunc, or use NewTokenBudget,
+// to bound chunks in tokens instead.
+package textsplit
+
+import (
+	"maps"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// defaultChunkSize is the maximum chunk length used when none is configured.
+	defaultChunkSize = 1000
+)
+
+// defaultSeparators are tried in order by the recursive splitter: paragraphs,
+// lines, words and finally single characters.
+var defaultSeparators = []string{"\n\n", "\n", " ", ""}
+
+// Chunk is a piece of a source text.
+// Metadata is set by splitters that know more about the chunk's context,
+// such as the Markdown headers that enclose it.
+type Chunk struct {
+	Text     string
+	Start    int
+	End      int
+	Metadata map[string]string
+}
+
+// Splitter splits a text into chunks, in source order.
+type Splitter interface {
+	Split(text string) []Chunk
+}
+
+// LengthFunc measures the length of a text, in characters, tokens or any
+// other unit.
+type LengthFunc func(text string) int
+
+// Option is a functional option for configuring a splitter.
+type Option func(*config)
+
+// config holds the configuration shared by all splitters.
+type config struct {
+	size       int
+	overlap    int
+	length     LengthFunc
+	separators []string
+}
+
+// WithChunkSize sets the maximum length of a chunk, as measured by the
+// splitter's LengthFunc. Values lower than one are ignored.
+func WithChunkSize(n int) Option {
+	return func(c *config) {
+		if n > 0 {
+			c.size = n
+		}
+	}
+}
+
+// WithOverlap sets how much of the end of a chunk is repeated at the start
+// of the next one, as measured by the splitter's LengthFunc. Overlap is made
+// of whole pieces (sentences, lines or words) and never exceeds n.
+func WithOverlap(n int) Option {
+	return func(c *config) { c.overlap = max(n, 0) }
+}
+
+// WithLengthFunc sets the function used to measure chunks.
+func WithLengthFunc(fn LengthFunc) Option {
+	return func(c *config) { c.length = fn }
+}
+
+// WithSeparators sets the separators tried, in order, by the recursive
+// splitter. An empty separator splits between characters.
+func WithSeparators(seps ...string) Option {
+	return func(c *config) { c.separators = seps }
+}
+
+// newConfig builds a config with defaults applied, then overrides from opts.
+func newConfig(opts []Option) *config {
+	c := &config{
+		size:       defaultChunkSize,
+		length:     utf8.RuneCountInString,
+		separators: defaultSeparators,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.length == nil {
+		c.length = utf8.RuneCountInString
+	}
+	return c
+}
+
+// span is a byte range of the source text.
+type span struct {
+	start, end int
+}
+
+// pieces splits text[start:end] into contiguous spans no longer than the
+// chunk size, trying each separator in turn. Separators stay attached to the
+// end of the preceding piece so that the spans cover the range exactly.
+func (c *config) pieces(text string, start, end int, seps []string) []span {
+	if c.measure(text[start:end]) <= c.size || len(seps) == 0 {
+		return []span{{start, end}}
+	}
+	sep, rest := seps[0], seps[1:]
+
+	if sep == "" {
+		var out []span
+		for i := start; i < end; {
+			_, n := utf8.DecodeRuneInString(text[i:end])
+			out = append(out, span{i, i + n})
+			i += n
+		}
+		return out
+	}
+	if !strings.Contains(text[start:end], sep) {
+		return c.pieces(text, start, end, rest)
+	}
+
+	var out []span
+	for i := start; i < end; {
+		next := end
+		if j := strings.Index(text[i:end], sep); j >= 0 {
+			next = i + j + len(sep)
+		}
+		out = append(out, c.pieces(text, i, next, rest)...)
+		i = next
+	}
+	return out
+}
+
+// merge combines consecutive pieces into chunks no longer than the chunk
+// size, repeating trailing pieces of each chunk at the start of the next
+// one up to the configured overlap.
+func (c *config) merge(text string, pieces []span, metadata map[string]string) []Chunk {
+	var (
+		chunks []Chunk
+		cur    []span
+	)
+	flush := func() {
+		if chunk, ok := newChunk(text, cur[0].start, cur[len(cur)-1].end, metadata); ok {
+			chunks = append(chunks, chunk)
+		}
+	}
+
+	for _, p := range pieces {
+		if len(cur) > 0 && c.measure(text[cur[0].start:p.end]) > c.size {
+			flush()
+			last := cur[len(cur)-1].end
+			keep := len(cur)
+			for keep > 0 && c.measure(text[cur[keep-1].start:last]) <= c.overlap {
+				keep--
+			}
+			cur = cur[keep:]
+			for len(cur) > 0 && c.measure(text[cur[0].start:p.end]) > c.size {
+				cur = cur[1:]
+			}
+		}
+		cur = append(cur, p)
+	}
+	if len(cur) > 0 {
+		flush()
+	}
+	return chunks
+}
+
+// measure returns the length of s without surrounding white space, which is
+// dropped from chunks anyway.
+func (c *config) measure(s string) int {
+	return c.length(strings.TrimSpace(s))
+}
+
+// newChunk returns the chunk for text[start:end] with surrounding white
+// space removed, or false if nothing but white space remains. The chunk
+// gets its own copy of metadata, so that chunks can be annotated
+// independently.
+func newChunk(text string, start, end int, metadata map[string]string) (Chunk, bool) {
+	for start < end {
+		r, n := utf8.DecodeRuneInString(text[start:end])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		start += n
+	}
+	for end > start {
+		r, n := utf8.DecodeLastRuneInString(text[start:end])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		end -= n
+	}
+	if start == end {
+		return Chunk{}, false
+	}
+	return Chunk{Text: text[start:end], Start: start, End: end, Metadata: maps.Clone(metadata)}, true
+}
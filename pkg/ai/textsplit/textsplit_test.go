@@ -0,0 +1,153 @@
+package textsplit
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// checkChunks verifies the offsets and size limit of every chunk.
+func checkChunks(t *testing.T, text string, chunks []Chunk, size int, length LengthFunc) {
+	t.Helper()
+	if length == nil {
+		length = utf8.RuneCountInString
+	}
+	for i, c := range chunks {
+		if text[c.Start:c.End] != c.Text {
+			t.Errorf("chunk %d: text[%d:%d] = %q, want %q", i, c.Start, c.End, text[c.Start:c.End], c.Text)
+		}
+		if length(c.Text) > size {
+			t.Errorf("chunk %d: length %d exceeds %d: %q", i, length(c.Text), size, c.Text)
+		}
+		if i > 0 && c.Start < chunks[i-1].Start {
+			t.Errorf("chunk %d starts before chunk %d", i, i-1)
+		}
+	}
+}
+
+func texts(chunks []Chunk) []string {
+	out := make([]string, len(chunks))
+	for i, c := range chunks {
+		out[i] = c.Text
+	}
+	return out
+}
+
+func TestRecursive(t *testing.T) {
+	text := "First paragraph here.\n\nSecond paragraph is a bit longer than the first.\n\nThird."
+	chunks := NewRecursive(WithChunkSize(30)).Split(text)
+	checkChunks(t, text, chunks, 30, nil)
+
+	got := strings.Join(texts(chunks), "|")
+	want := "First paragraph here.\n\nSecond|paragraph is a bit longer than|the first.\n\nThird."
+	if got != want {
+		t.Errorf("chunks = %q, want %q", got, want)
+	}
+}
+
+func TestRecursive_Overlap(t *testing.T) {
+	text := "one two three four five six seven eight nine ten"
+	chunks := NewRecursive(WithChunkSize(15), WithOverlap(6)).Split(text)
+	checkChunks(t, text, chunks, 15, nil)
+
+	got := strings.Join(texts(chunks), "|")
+	want := "one two three|three four five|five six seven|seven eight|eight nine ten"
+	if got != want {
+		t.Errorf("chunks = %q, want %q", got, want)
+	}
+}
+
+func TestRecursive_Characters(t *testing.T) {
+	text := "añbñcñdñe"
+	chunks := NewRecursive(WithChunkSize(4)).Split(text)
+	checkChunks(t, text, chunks, 4, nil)
+	if got := strings.Join(texts(chunks), "|"); got != "añbñ|cñdñ|e" {
+		t.Errorf("chunks = %q", got)
+	}
+}
+
+func TestSentence(t *testing.T) {
+	text := `Pi is 3.14 roughly. Is it "exact"? No!  It goes on.` + "\n\nNew paragraph"
+	chunks := NewSentence(WithChunkSize(25)).Split(text)
+	checkChunks(t, text, chunks, 25, nil)
+
+	got := strings.Join(texts(chunks), "|")
+	want := `Pi is 3.14 roughly.|Is it "exact"? No!|It goes on.|New paragraph`
+	if got != want {
+		t.Errorf("chunks = %q, want %q", got, want)
+	}
+}
+
+func TestTokenBudget(t *testing.T) {
+	words := func(s string) int { return len(strings.Fields(s)) }
+	text := "a b c d e f g h i j"
+	chunks := NewTokenBudget(words, 4, WithOverlap(1)).Split(text)
+	checkChunks(t, text, chunks, 4, words)
+
+	got := strings.Join(texts(chunks), "|")
+	if want := "a b c d|d e f g|g h i j"; got != want {
+		t.Errorf("chunks = %q, want %q", got, want)
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	text := "Intro text.\n" +
+		"# Guide\n" +
+		"Welcome.\n" +
+		"## Install\n" +
+		"Run it.\n" +
+		"```\n# not a header\n```\n" +
+		"## Usage ##\n" +
+		"Call it.\n"
+	chunks := NewMarkdown(WithChunkSize(100)).Split(text)
+	checkChunks(t, text, chunks, 100, nil)
+
+	if len(chunks) != 4 {
+		t.Fatalf("len(chunks) = %d, want 4: %q", len(chunks), texts(chunks))
+	}
+	if len(chunks[0].Metadata) != 0 {
+		t.Errorf("intro metadata = %v, want none", chunks[0].Metadata)
+	}
+	if chunks[1].Metadata["h1"] != "Guide" || chunks[1].Metadata["h2"] != "" {
+		t.Errorf("chunk 1 metadata = %v", chunks[1].Metadata)
+	}
+	if !strings.Contains(chunks[2].Text, "# not a header") || chunks[2].Metadata["h2"] != "Install" {
+		t.Errorf("chunk 2 = %q %v, want install section with code block", chunks[2].Text, chunks[2].Metadata)
+	}
+	if chunks[3].Metadata["h1"] != "Guide" || chunks[3].Metadata["h2"] != "Usage" {
+		t.Errorf("chunk 3 metadata = %v", chunks[3].Metadata)
+	}
+}
+
+func TestMarkdownMetadataPerChunk(t *testing.T) {
+	text := "# Guide\n" + strings.Repeat("Some words here. ", 10)
+	chunks := NewMarkdown(WithChunkSize(40)).Split(text)
+	if len(chunks) < 2 {
+		t.Fatalf("len(chunks) = %d, want a section split in several chunks", len(chunks))
+	}
+	chunks[0].Metadata["source"] = "doc-1"
+	if _, ok := chunks[1].Metadata["source"]; ok || chunks[1].Metadata["h1"] != "Guide" {
+		t.Errorf("chunk 1 metadata = %v, want its own copy", chunks[1].Metadata)
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	testCases := []struct {
+		line  string
+		level int
+		title string
+		ok    bool
+	}{
+		{line: "# Title", level: 1, title: "Title", ok: true},
+		{line: "### Deep ###", level: 3, title: "Deep", ok: true},
+		{line: "#hashtag", ok: false},
+		{line: "####### seven", ok: false},
+		{line: "plain", ok: false},
+	}
+	for _, tc := range testCases {
+		level, title, ok := parseHeader(tc.line)
+		if level != tc.level || title != tc.title || ok != tc.ok {
+			t.Errorf("parseHeader(%q) = %d, %q, %v; want %d, %q, %v", tc.line, level, title, ok, tc.level, tc.title, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package usage
+
+import (
+	"context"
+
+	"github.com/tnotstar/go-minolas/pkg/ai/generators"
+)
+
+// modeler is implemented by generators that expose their default model.
+type modeler interface {
+	Model() string
+}
+
+// Wrap returns a Generator that forwards calls to gen and records the usage
+// of every successful Generate call and every completed Stream.
+//
+// Example:
+//
+//	tracker := usage.NewTracker(
+//	    usage.WithPrice("gemini-2.0-flash", usage.Price{InputPerMillion: 0.10, OutputPerMillion: 0.40}),
+//	)
+//	gen = tracker.Wrap(gen)
+//	resp, err := gen.Generate(usage.WithTag(ctx, "search"), prompt)
+func (t *Tracker) Wrap(gen generators.Generator) generators.Generator {
+	return &trackedGenerator{gen: gen, tracker: t}
+}
+
+// trackedGenerator is the Generator middleware returned by Tracker.Wrap.
+type trackedGenerator struct {
+	gen     generators.Generator
+	tracker *Tracker
+}
+
+// Generate forwards the call and records the usage of the response.
+func (g *trackedGenerator) Generate(ctx context.Context, prompt string, opts ...generators.Option) (*generators.Response, error) {
+	resp, err := g.gen.Generate(ctx, prompt, opts...)
+	if err != nil {
+		return nil, err
+	}
+	model := resp.Model
+	if model == "" {
+		model = g.resolveModel(opts)
+	}
+	g.tracker.Record(model, TagFromContext(ctx), resp.Usage)
+	return resp, nil
+}
+
+// Stream forwards the call and records the usage reported by the stream.
+func (g *trackedGenerator) Stream(ctx context.Context, prompt string, opts ...generators.Option) (<-chan generators.StreamChunk, error) {
+	in, err := g.gen.Stream(ctx, prompt, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	model := g.resolveModel(opts)
+	tag := TagFromContext(ctx)
+	out := make(chan generators.StreamChunk)
+
+	go func() {
+		defer close(out)
+		for chunk := range in {
+			if chunk.Usage != nil {
+				g.tracker.Record(model, tag, *chunk.Usage)
+			}
+			out <- chunk
+		}
+	}()
+
+	return out, nil
+}
+
+// Close closes the wrapped generator.
+func (g *trackedGenerator) Close() error {
+	return g.gen.Close()
+}
+
+// resolveModel returns the model requested by the options, falling back to
+// the wrapped generator's default model when it is known.
+func (g *trackedGenerator) resolveModel(opts []generators.Option) string {
+	cfg := &generators.Config{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.Model != "" {
+		return cfg.Model
+	}
+	if m, ok := g.gen.(modeler); ok {
+		return m.Model()
+	}
+	return ""
+}
@@ -0,0 +1,167 @@
+package usage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/tnotstar/go-minolas/pkg/db/sqlt"
+)
+
+const (
+	// scopeModel, scopeTag and scopeTotal identify the kind of row stored in
+	// the usage_totals table.
+	scopeModel = "model"
+	scopeTag   = "tag"
+	scopeTotal = "total"
+)
+
+// schema creates the table used to persist usage totals.
+const schema = `CREATE TABLE IF NOT EXISTS usage_totals (
+	scope             VARCHAR(16)  NOT NULL,
+	name              VARCHAR(255) NOT NULL,
+	requests          BIGINT       NOT NULL,
+	prompt_tokens     BIGINT       NOT NULL,
+	completion_tokens BIGINT       NOT NULL,
+	thought_tokens    BIGINT       NOT NULL,
+	total_tokens      BIGINT       NOT NULL,
+	cost              DOUBLE PRECISION NOT NULL,
+	PRIMARY KEY (scope, name)
+)`
+
+// Store persists usage snapshots in a SQL database.
+// The statements use "?" placeholders, as supported by SQLite.
+type Store struct {
+	db *sql.DB
+}
+
+// OpenStore opens the SQLite database at dburl through sqlt.Open and
+// prepares the usage_totals table. URLs of other databases are rejected,
+// as the statements are written for SQLite.
+//
+// Example:
+//
+//	store, err := usage.OpenStore(ctx, "sqlite:usage.db")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer store.Close()
+//	err = store.Save(ctx, tracker.Snapshot())
+func OpenStore(ctx context.Context, dburl string) (*Store, error) {
+	u, err := url.Parse(dburl)
+	if err != nil {
+		return nil, fmt.Errorf("usage: open store: %w", err)
+	}
+	if !(&sqlt.SqliteOpener{}).CanOpen(u) {
+		return nil, fmt.Errorf("usage: open store: scheme %q not supported (expected sqlite or sqlite3)", u.Scheme)
+	}
+	db, err := sqlt.Open(dburl)
+	if err != nil {
+		return nil, fmt.Errorf("usage: open store: %w", err)
+	}
+	s, err := NewStore(ctx, db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewStore prepares the usage_totals table in an already open database.
+// Closing the returned Store closes db.
+func NewStore(ctx context.Context, db *sql.DB) (*Store, error) {
+	if db == nil {
+		return nil, errors.New("usage: database cannot be nil")
+	}
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		return nil, fmt.Errorf("usage: create schema: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Save replaces the persisted totals with the given snapshot.
+func (s *Store) Save(ctx context.Context, snap Snapshot) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("usage: begin save: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM usage_totals"); err != nil {
+		return fmt.Errorf("usage: clear totals: %w", err)
+	}
+
+	insert := func(scope, name string, t Totals) error {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO usage_totals (scope, name, requests, prompt_tokens, completion_tokens, thought_tokens, total_tokens, cost)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			scope, name, t.Requests, t.PromptTokens, t.CompletionTokens, t.ThoughtTokens, t.TotalTokens, t.Cost)
+		if err != nil {
+			return fmt.Errorf("usage: insert %s %q: %w", scope, name, err)
+		}
+		return nil
+	}
+
+	for name, t := range snap.Models {
+		if err := insert(scopeModel, name, t); err != nil {
+			return err
+		}
+	}
+	for name, t := range snap.Tags {
+		if err := insert(scopeTag, name, t); err != nil {
+			return err
+		}
+	}
+	if err := insert(scopeTotal, "", snap.Total); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("usage: commit save: %w", err)
+	}
+	return nil
+}
+
+// Load returns the persisted totals as a Snapshot.
+func (s *Store) Load(ctx context.Context) (Snapshot, error) {
+	snap := Snapshot{
+		Models: make(map[string]Totals),
+		Tags:   make(map[string]Totals),
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT scope, name, requests, prompt_tokens, completion_tokens, thought_tokens, total_tokens, cost FROM usage_totals`)
+	if err != nil {
+		return snap, fmt.Errorf("usage: query totals: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			scope, name string
+			t           Totals
+		)
+		if err := rows.Scan(&scope, &name, &t.Requests, &t.PromptTokens, &t.CompletionTokens, &t.ThoughtTokens, &t.TotalTokens, &t.Cost); err != nil {
+			return snap, fmt.Errorf("usage: scan totals: %w", err)
+		}
+		switch scope {
+		case scopeModel:
+			snap.Models[name] = t
+		case scopeTag:
+			snap.Tags[name] = t
+		case scopeTotal:
+			snap.Total = t
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return snap, fmt.Errorf("usage: read totals: %w", err)
+	}
+	return snap, nil
+}
+
+// Close closes the underlying database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,53 @@
+package usage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/tnotstar/go-minolas/pkg/ai/generators"
+)
+
+func TestStore_SaveLoad(t *testing.T) {
+	ctx := context.Background()
+	store, err := OpenStore(ctx, "sqlite:"+filepath.Join(t.TempDir(), "usage.db"))
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+	defer store.Close()
+
+	tr := NewTracker(WithPrice("m", Price{InputPerMillion: 1, OutputPerMillion: 1}))
+	tr.Record("m", "a", generators.Usage{PromptTokens: 10, CompletionTokens: 5, ThoughtTokens: 3, TotalTokens: 18})
+	tr.Record("m", "b", generators.Usage{PromptTokens: 1, CompletionTokens: 1, TotalTokens: 2})
+
+	if err := store.Save(ctx, tr.Snapshot()); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	// Saving again replaces the previous totals instead of adding to them.
+	if err := store.Save(ctx, tr.Snapshot()); err != nil {
+		t.Fatalf("second Save() error = %v", err)
+	}
+
+	got, err := store.Load(ctx)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	want := tr.Snapshot()
+	if got.Total != want.Total {
+		t.Errorf("Total = %+v, want %+v", got.Total, want.Total)
+	}
+	if got.Models["m"] != want.Models["m"] {
+		t.Errorf("Models[m] = %+v, want %+v", got.Models["m"], want.Models["m"])
+	}
+	if len(got.Tags) != 2 || got.Tags["a"] != want.Tags["a"] {
+		t.Errorf("Tags = %+v, want %+v", got.Tags, want.Tags)
+	}
+}
+
+func TestOpenStore_UnsupportedURL(t *testing.T) {
+	for _, dburl := range []string{"nosuchdb://x", "sqlserver://sa:pw@localhost/usage", "oracle://u:p@localhost/usage"} {
+		if _, err := OpenStore(context.Background(), dburl); err == nil {
+			t.Errorf("OpenStore(%q) succeeded, want error", dburl)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+// Package usage provides token usage and cost accounting for AI generators.
+// A Tracker wraps any generators.Generator as a middleware, aggregates the
+// reported token usage per model and per caller-supplied tag, and applies a
+// configurable price table to compute cost.
+//
+// Totals can be persisted to a database opened through the sqlt package.
+package usage
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/tnotstar/go-minolas/pkg/ai/generators"
+)
+
+// Price holds the cost of a model in currency units per million tokens.
+type Price struct {
+	InputPerMillion  float64
+	OutputPerMillion float64
+}
+
+// Cost returns the cost of the given usage at this price.
+// Thought tokens are billed as output tokens.
+func (p Price) Cost(u generators.Usage) float64 {
+	output := u.CompletionTokens + u.ThoughtTokens
+	return (float64(u.PromptTokens)*p.InputPerMillion + float64(output)*p.OutputPerMillion) / 1e6
+}
+
+// Totals holds aggregated usage and cost.
+type Totals struct {
+	Requests         int64
+	PromptTokens     int64
+	CompletionTokens int64
+	ThoughtTokens    int64
+	TotalTokens      int64
+	Cost             float64
+}
+
+// add accumulates a single usage record into the totals.
+func (t *Totals) add(u generators.Usage, cost float64) {
+	t.Requests++
+	t.PromptTokens += int64(u.PromptTokens)
+	t.CompletionTokens += int64(u.CompletionTokens)
+	t.ThoughtTokens += int64(u.ThoughtTokens)
+	t.TotalTokens += int64(u.TotalTokens)
+	t.Cost += cost
+}
+
+// Snapshot is a point-in-time copy of the totals aggregated by a Tracker.
+// Requests without a tag are not included in Tags.
+type Snapshot struct {
+	Models map[string]Totals
+	Tags   map[string]Totals
+	Total  Totals
+}
+
+// TrackerOption is a functional option for configuring a Tracker.
+type TrackerOption func(*Tracker)
+
+// WithPrice sets the price for a model. The price also applies to models
+// whose name starts with the given name (for example "gemini-2.0-flash"
+// matches "gemini-2.0-flash-001"); the longest matching name wins.
+func WithPrice(model string, p Price) TrackerOption {
+	return func(t *Tracker) { t.prices[model] = p }
+}
+
+// Tracker aggregates token usage per model and per tag.
+// It is safe for concurrent use.
+type Tracker struct {
+	mu     sync.Mutex
+	prices map[string]Price
+	models map[string]*Totals
+	tags   map[string]*Totals
+	total  Totals
+}
+
+// NewTracker creates a Tracker with the given options.
+func NewTracker(opts ...TrackerOption) *Tracker {
+	t := &Tracker{
+		prices: make(map[string]Price),
+		models: make(map[string]*Totals),
+		tags:   make(map[string]*Totals),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// SetPrice sets or replaces the price for a model. It only affects usage
+// recorded afterwards.
+func (t *Tracker) SetPrice(model string, p Price) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.prices[model] = p
+}
+
+// Record adds a usage record for the given model and tag.
+// An empty tag records the usage under the model and overall totals only.
+func (t *Tracker) Record(model, tag string, u generators.Usage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	cost := t.priceFor(model).Cost(u)
+
+	m, ok := t.models[model]
+	if !ok {
+		m = &Totals{}
+		t.models[model] = m
+	}
+	m.add(u, cost)
+
+	if tag != "" {
+		tt, ok := t.tags[tag]
+		if !ok {
+			tt = &Totals{}
+			t.tags[tag] = tt
+		}
+		tt.add(u, cost)
+	}
+
+	t.total.add(u, cost)
+}
+
+// priceFor returns the price of the longest configured model name that
+// prefixes model, or a zero Price if none matches.
+// The caller must hold t.mu.
+func (t *Tracker) priceFor(model string) Price {
+	if p, ok := t.prices[model]; ok {
+		return p
+	}
+	var (
+		best  Price
+		bestN int
+	)
+	for name, p := range t.prices {
+		if len(name) > bestN && strings.HasPrefix(model, name) {
+			best, bestN = p, len(name)
+		}
+	}
+	return best
+}
+
+// Snapshot returns a copy of the current totals.
+func (t *Tracker) Snapshot() Snapshot {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := Snapshot{
+		Models: make(map[string]Totals, len(t.models)),
+		Tags:   make(map[string]Totals, len(t.tags)),
+		Total:  t.total,
+	}
+	for k, v := range t.models {
+		s.Models[k] = *v
+	}
+	for k, v := range t.tags {
+		s.Tags[k] = *v
+	}
+	return s
+}
+
+// Reset clears all aggregated totals. Configured prices are kept.
+func (t *Tracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.models = make(map[string]*Totals)
+	t.tags = make(map[string]*Totals)
+	t.total = Totals{}
+}
+
+// tagKey is the context key for the usage tag.
+type tagKey struct{}
+
+// WithTag returns a copy of ctx carrying the given usage tag. Generation
+// calls made with the returned context are attributed to the tag.
+func WithTag(ctx context.Context, tag string) context.Context {
+	return context.WithValue(ctx, tagKey{}, tag)
+}
+
+// TagFromContext returns the usage tag carried by ctx, or "" if none.
+func TagFromContext(ctx context.Context) string {
+	tag, _ := ctx.Value(tagKey{}).(string)
+	return tag
+}
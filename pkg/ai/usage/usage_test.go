@@ -0,0 +1,140 @@
+package usage
+
+import (
+	"context"
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/tnotstar/go-minolas/pkg/ai/generators"
+)
+
+type fakeGenerator struct {
+	model string
+	usage generators.Usage
+}
+
+func (f *fakeGenerator) Generate(_ context.Context, _ string, opts ...generators.Option) (*generators.Response, error) {
+	cfg := &generators.Config{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	model := f.model
+	if cfg.Model != "" {
+		model = cfg.Model
+	}
+	return &generators.Response{Text: "ok", Model: model, Usage: f.usage}, nil
+}
+
+func (f *fakeGenerator) Stream(_ context.Context, _ string, _ ...generators.Option) (<-chan generators.StreamChunk, error) {
+	ch := make(chan generators.StreamChunk, 2)
+	ch <- generators.StreamChunk{Text: "ok"}
+	u := f.usage
+	ch <- generators.StreamChunk{Usage: &u}
+	close(ch)
+	return ch, nil
+}
+
+func (f *fakeGenerator) Close() error { return nil }
+
+func (f *fakeGenerator) Model() string { return f.model }
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestPriceCost(t *testing.T) {
+	p := Price{InputPerMillion: 1, OutputPerMillion: 4}
+	got := p.Cost(generators.Usage{PromptTokens: 1_000_000, CompletionTokens: 500_000})
+	if !almostEqual(got, 3) {
+		t.Errorf("Cost() = %v, want 3", got)
+	}
+	got = p.Cost(generators.Usage{CompletionTokens: 250_000, ThoughtTokens: 250_000})
+	if !almostEqual(got, 2) {
+		t.Errorf("Cost() with thoughts = %v, want 2", got)
+	}
+}
+
+func TestTracker_Record(t *testing.T) {
+	tr := NewTracker(
+		WithPrice("gemini-2.0-flash", Price{InputPerMillion: 1, OutputPerMillion: 2}),
+		WithPrice("gemini-2.0-flash-lite", Price{InputPerMillion: 10, OutputPerMillion: 20}),
+	)
+	u := generators.Usage{PromptTokens: 100, CompletionTokens: 50, TotalTokens: 150}
+
+	tr.Record("gemini-2.0-flash-001", "search", u)
+	tr.Record("gemini-2.0-flash-lite-001", "search", u)
+	tr.Record("llama3.2", "", u)
+
+	snap := tr.Snapshot()
+	if got := snap.Models["gemini-2.0-flash-001"].Cost; !almostEqual(got, 200e-6) {
+		t.Errorf("flash cost = %v, want prefix price applied", got)
+	}
+	if got := snap.Models["gemini-2.0-flash-lite-001"].Cost; !almostEqual(got, 2000e-6) {
+		t.Errorf("flash-lite cost = %v, want longest prefix price applied", got)
+	}
+	if got := snap.Models["llama3.2"]; got.Cost != 0 || got.Requests != 1 {
+		t.Errorf("unpriced model totals = %+v, want one free request", got)
+	}
+	if got := snap.Tags["search"]; got.Requests != 2 || got.TotalTokens != 300 {
+		t.Errorf("tag totals = %+v, want 2 requests and 300 tokens", got)
+	}
+	if _, ok := snap.Tags[""]; ok {
+		t.Error("untagged usage should not be recorded as a tag")
+	}
+	if snap.Total.Requests != 3 || snap.Total.PromptTokens != 300 {
+		t.Errorf("total = %+v, want 3 requests and 300 prompt tokens", snap.Total)
+	}
+
+	tr.Reset()
+	if snap := tr.Snapshot(); snap.Total.Requests != 0 || len(snap.Models) != 0 {
+		t.Errorf("after Reset snapshot = %+v, want empty", snap)
+	}
+}
+
+func TestTracker_Wrap(t *testing.T) {
+	tr := NewTracker(WithPrice("base", Price{InputPerMillion: 1e6, OutputPerMillion: 0}))
+	gen := tr.Wrap(&fakeGenerator{model: "base", usage: generators.Usage{PromptTokens: 2, CompletionTokens: 1, TotalTokens: 3}})
+	ctx := WithTag(context.Background(), "feature-a")
+
+	if _, err := gen.Generate(ctx, "hi"); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if _, err := gen.Generate(context.Background(), "hi", generators.WithModel("other")); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	ch, err := gen.Stream(ctx, "hi")
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	for range ch {
+	}
+
+	snap := tr.Snapshot()
+	if got := snap.Models["base"]; got.Requests != 2 || !almostEqual(got.Cost, 4) {
+		t.Errorf("base totals = %+v, want 2 requests costing 4", got)
+	}
+	if got := snap.Models["other"]; got.Requests != 1 {
+		t.Errorf("other totals = %+v, want 1 request", got)
+	}
+	if got := snap.Tags["feature-a"]; got.Requests != 2 {
+		t.Errorf("tag totals = %+v, want 2 requests", got)
+	}
+}
+
+func TestTracker_Concurrency(t *testing.T) {
+	tr := NewTracker()
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tr.Record("m", "t", generators.Usage{TotalTokens: 1})
+			_ = tr.Snapshot()
+		}()
+	}
+	wg.Wait()
+	if got := tr.Snapshot().Total.TotalTokens; got != 50 {
+		t.Errorf("TotalTokens = %d, want 50", got)
+	}
+}
@@ -0,0 +1,338 @@
+package vector
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/tnotstar/go-minolas/pkg/ai/generators"
+	"github.com/tnotstar/go-minolas/pkg/db/sqlt"
+)
+
+// schema creates the table used to persist documents.
+const schema = `CREATE TABLE IF NOT EXISTS vector_documents (
+	id        VARCHAR(255) NOT NULL PRIMARY KEY,
+	content   TEXT         NOT NULL,
+	metadata  TEXT         NOT NULL,
+	embedding BLOB         NOT NULL
+)`
+
+var (
+	// ErrNoEmbedder is returned when text must be embedded but the store was
+	// opened without an Embedder.
+	ErrNoEmbedder = errors.New("vector: no embedder configured")
+)
+
+// Option is a functional option for configuring a Store.
+type Option func(*Store)
+
+// WithEmbedder sets the embedder used to compute missing document
+// embeddings in Upsert and query embeddings in SearchText.
+func WithEmbedder(e generators.Embedder) Option {
+	return func(s *Store) { s.embedder = e }
+}
+
+// WithIndex keeps every document in memory so that searches do not read
+// the database. The index is loaded when the store is opened and kept in
+// sync by Upsert and Delete; changes made to the table by other processes
+// are not seen.
+func WithIndex() Option {
+	return func(s *Store) { s.index = make(map[string]Document) }
+}
+
+// Store persists documents and their embeddings in a SQLite database.
+// A Store is safe for concurrent use.
+type Store struct {
+	db       *sql.DB
+	embedder generators.Embedder
+
+	mu    sync.RWMutex
+	index map[string]Document
+}
+
+// OpenStore opens the SQLite database at dburl through sqlt.Open and
+// prepares the vector_documents table.
+//
+// Example:
+//
+//	emb, err := generators.OpenEmbedder(ctx, "ollama://localhost:11434/nomic-embed-text")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	store, err := vector.OpenStore(ctx, "sqlite:docs.db", vector.WithEmbedder(emb))
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer store.Close()
+//	err = store.Upsert(ctx, vector.Document{ID: "1", Content: "Go is fun"})
+//	results, err := store.SearchText(ctx, "programming languages", 5)
+func OpenStore(ctx context.Context, dburl string, opts ...Option) (*Store, error) {
+	db, err := sqlt.Open(dburl)
+	if err != nil {
+		return nil, fmt.Errorf("vector: open store: %w", err)
+	}
+	s, err := NewStore(ctx, db, opts...)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewStore prepares the vector_documents table in an already open database.
+// Closing the returned Store closes db.
+func NewStore(ctx context.Context, db *sql.DB, opts ...Option) (*Store, error) {
+	if db == nil {
+		return nil, errors.New("vector: database cannot be nil")
+	}
+	s := &Store{db: db}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		return nil, fmt.Errorf("vector: create schema: %w", err)
+	}
+	if s.index != nil {
+		docs, err := s.load(ctx, "", nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range docs {
+			s.index[d.ID] = d
+		}
+	}
+	return s, nil
+}
+
+// Upsert inserts documents or replaces the documents with the same IDs.
+// Documents without an embedding are embedded with the store's Embedder in
+// a single request.
+func (s *Store) Upsert(ctx context.Context, docs ...Document) error {
+	var missing []int
+	for i, d := range docs {
+		if d.ID == "" {
+			return errors.New("vector: document ID cannot be empty")
+		}
+		if len(d.Embedding) == 0 {
+			missing = append(missing, i)
+		}
+	}
+	if len(missing) > 0 {
+		if s.embedder == nil {
+			return ErrNoEmbedder
+		}
+		texts := make([]string, len(missing))
+		for j, i := range missing {
+			texts[j] = docs[i].Content
+		}
+		vectors, err := s.embedder.Embed(ctx, texts)
+		if err != nil {
+			return fmt.Errorf("vector: embed documents: %w", err)
+		}
+		if len(vectors) != len(texts) {
+			return fmt.Errorf("vector: embedder returned %d vectors for %d documents", len(vectors), len(texts))
+		}
+		docs = append([]Document(nil), docs...)
+		for j, i := range missing {
+			docs[i].Embedding = vectors[j]
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("vector: begin upsert: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, d := range docs {
+		meta, err := json.Marshal(d.Metadata)
+		if err != nil {
+			return fmt.Errorf("vector: encode metadata of %q: %w", d.ID, err)
+		}
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO vector_documents (id, content, metadata, embedding) VALUES (?, ?, ?, ?)
+			 ON CONFLICT (id) DO UPDATE SET content = excluded.content, metadata = excluded.metadata, embedding = excluded.embedding`,
+			d.ID, d.Content, string(meta), encodeEmbedding(d.Embedding))
+		if err != nil {
+			return fmt.Errorf("vector: upsert %q: %w", d.ID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("vector: commit upsert: %w", err)
+	}
+
+	if s.index != nil {
+		s.mu.Lock()
+		for _, d := range docs {
+			s.index[d.ID] = d
+		}
+		s.mu.Unlock()
+	}
+	return nil
+}
+
+// Delete removes the documents with the given IDs. Unknown IDs are ignored.
+func (s *Store) Delete(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	query := "DELETE FROM vector_documents WHERE id IN (?" + strings.Repeat(", ?", len(ids)-1) + ")"
+	if _, err := s.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("vector: delete: %w", err)
+	}
+
+	if s.index != nil {
+		s.mu.Lock()
+		for _, id := range ids {
+			delete(s.index, id)
+		}
+		s.mu.Unlock()
+	}
+	return nil
+}
+
+// Get returns the document with the given ID and whether it exists.
+func (s *Store) Get(ctx context.Context, id string) (Document, bool, error) {
+	if s.index != nil {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		d, ok := s.index[id]
+		return d, ok, nil
+	}
+	docs, err := s.load(ctx, "WHERE id = ?", []any{id})
+	if err != nil || len(docs) == 0 {
+		return Document{}, false, err
+	}
+	return docs[0], true, nil
+}
+
+// Count returns the number of stored documents.
+func (s *Store) Count(ctx context.Context) (int, error) {
+	if s.index != nil {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		return len(s.index), nil
+	}
+	var n int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM vector_documents").Scan(&n); err != nil {
+		return 0, fmt.Errorf("vector: count: %w", err)
+	}
+	return n, nil
+}
+
+// SearchOption is a functional option for configuring a search.
+type SearchOption func(*searchConfig)
+
+// searchConfig holds configuration for a search.
+type searchConfig struct {
+	metric   Metric
+	filter   map[string]string
+	minScore *float64
+}
+
+// WithMetric sets the similarity metric. The default is Cosine.
+func WithMetric(m Metric) SearchOption {
+	return func(c *searchConfig) { c.metric = m }
+}
+
+// WithFilter restricts the search to documents whose metadata has the given
+// value for key. Several filters must all match.
+func WithFilter(key, value string) SearchOption {
+	return func(c *searchConfig) {
+		if c.filter == nil {
+			c.filter = make(map[string]string)
+		}
+		c.filter[key] = value
+	}
+}
+
+// WithMinScore drops results scoring below score.
+func WithMinScore(score float64) SearchOption {
+	return func(c *searchConfig) { c.minScore = &score }
+}
+
+// Search returns the k documents most similar to query, best first.
+// A negative k returns every matching document.
+func (s *Store) Search(ctx context.Context, query []float32, k int, opts ...SearchOption) ([]Result, error) {
+	cfg := &searchConfig{metric: Cosine}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	if s.index != nil {
+		s.mu.RLock()
+		docs := make([]Document, 0, len(s.index))
+		for _, d := range s.index {
+			docs = append(docs, d)
+		}
+		s.mu.RUnlock()
+		return rank(docs, query, k, cfg)
+	}
+
+	docs, err := s.load(ctx, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	return rank(docs, query, k, cfg)
+}
+
+// SearchText embeds text with the store's Embedder and searches for it.
+func (s *Store) SearchText(ctx context.Context, text string, k int, opts ...SearchOption) ([]Result, error) {
+	if s.embedder == nil {
+		return nil, ErrNoEmbedder
+	}
+	vectors, err := s.embedder.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, fmt.Errorf("vector: embed query: %w", err)
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("vector: embedder returned %d vectors for one query", len(vectors))
+	}
+	return s.Search(ctx, vectors[0], k, opts...)
+}
+
+// Close closes the underlying database. It does not close the Embedder.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// load reads the documents selected by the optional where clause.
+func (s *Store) load(ctx context.Context, where string, args []any) ([]Document, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT id, content, metadata, embedding FROM vector_documents "+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("vector: query documents: %w", err)
+	}
+	defer rows.Close()
+
+	var docs []Document
+	for rows.Next() {
+		var (
+			d    Document
+			meta string
+			emb  []byte
+		)
+		if err := rows.Scan(&d.ID, &d.Content, &meta, &emb); err != nil {
+			return nil, fmt.Errorf("vector: scan document: %w", err)
+		}
+		if err := json.Unmarshal([]byte(meta), &d.Metadata); err != nil {
+			return nil, fmt.Errorf("vector: decode metadata of %q: %w", d.ID, err)
+		}
+		if d.Embedding, err = decodeEmbedding(emb); err != nil {
+			return nil, fmt.Errorf("vector: decode embedding of %q: %w", d.ID, err)
+		}
+		docs = append(docs, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("vector: read documents: %w", err)
+	}
+	return docs, nil
+}
@@ -0,0 +1,165 @@
+package vector
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tnotstar/go-minolas/pkg/ai/generators"
+)
+
+// fakeEmbedder maps each text to a fixed vector and counts its calls.
+type fakeEmbedder struct {
+	vectors map[string][]float32
+	calls   int
+}
+
+func (e *fakeEmbedder) Embed(_ context.Context, texts []string, _ ...generators.Option) ([][]float32, error) {
+	e.calls++
+	out := make([][]float32, len(texts))
+	for i, t := range texts {
+		v, ok := e.vectors[t]
+		if !ok {
+			return nil, errors.New("unknown text " + t)
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+func (e *fakeEmbedder) Close() error { return nil }
+
+func openTestStore(t *testing.T, opts ...Option) *Store {
+	t.Helper()
+	store, err := OpenStore(context.Background(), "sqlite:"+filepath.Join(t.TempDir(), "vectors.db"), opts...)
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func ids(results []Result) string {
+	var parts []string
+	for _, r := range results {
+		parts = append(parts, r.ID)
+	}
+	return strings.Join(parts, ",")
+}
+
+func seed(t *testing.T, store *Store) {
+	t.Helper()
+	err := store.Upsert(context.Background(),
+		Document{ID: "north", Content: "n", Metadata: map[string]string{"lang": "en"}, Embedding: []float32{0, 1}},
+		Document{ID: "east", Content: "e", Metadata: map[string]string{"lang": "es"}, Embedding: []float32{1, 0}},
+		Document{ID: "northeast", Content: "ne", Metadata: map[string]string{"lang": "en"}, Embedding: []float32{2, 2}},
+	)
+	if err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+}
+
+func TestStore_Search(t *testing.T) {
+	for _, indexed := range []bool{false, true} {
+		var opts []Option
+		if indexed {
+			opts = append(opts, WithIndex())
+		}
+		store := openTestStore(t, opts...)
+		seed(t, store)
+		ctx := context.Background()
+
+		testCases := []struct {
+			name string
+			k    int
+			opts []SearchOption
+			want string
+		}{
+			{name: "cosine", k: 2, want: "north,northeast"},
+			{name: "dot product", k: 2, opts: []SearchOption{WithMetric(DotProduct)}, want: "northeast,north"},
+			{name: "filter", k: -1, opts: []SearchOption{WithFilter("lang", "es")}, want: "east"},
+			{name: "min score", k: -1, opts: []SearchOption{WithMinScore(0.5)}, want: "north,northeast"},
+		}
+		for _, tc := range testCases {
+			got, err := store.Search(ctx, []float32{0, 1}, tc.k, tc.opts...)
+			if err != nil {
+				t.Fatalf("indexed=%v %s: Search() error = %v", indexed, tc.name, err)
+			}
+			if ids(got) != tc.want {
+				t.Errorf("indexed=%v %s: Search() = %s, want %s", indexed, tc.name, ids(got), tc.want)
+			}
+		}
+
+		if _, err := store.Search(ctx, []float32{1, 2, 3}, 1); err == nil {
+			t.Errorf("indexed=%v: expected dimension mismatch error", indexed)
+		}
+	}
+}
+
+func TestStore_UpsertDelete(t *testing.T) {
+	ctx := context.Background()
+	store := openTestStore(t)
+	seed(t, store)
+
+	if err := store.Upsert(ctx, Document{ID: "east", Content: "updated", Embedding: []float32{0, 1}}); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	d, ok, err := store.Get(ctx, "east")
+	if err != nil || !ok || d.Content != "updated" || d.Embedding[1] != 1 || d.Metadata != nil {
+		t.Errorf("Get(east) = %+v, %v, %v; want updated document", d, ok, err)
+	}
+
+	if err := store.Delete(ctx, "east", "north", "missing"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if n, _ := store.Count(ctx); n != 1 {
+		t.Errorf("Count() = %d, want 1", n)
+	}
+	if _, ok, _ := store.Get(ctx, "north"); ok {
+		t.Error("Get(north) found a deleted document")
+	}
+
+	// A new store with an index loads the persisted documents.
+	reopened, err := NewStore(ctx, store.db, WithIndex())
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	if d, ok, _ := reopened.Get(ctx, "northeast"); !ok || d.Metadata["lang"] != "en" {
+		t.Errorf("indexed Get(northeast) = %+v, %v", d, ok)
+	}
+}
+
+func TestStore_Embedder(t *testing.T) {
+	ctx := context.Background()
+	emb := &fakeEmbedder{vectors: map[string][]float32{
+		"cats":   {1, 0},
+		"dogs":   {0, 1},
+		"kitten": {0.9, 0.1},
+	}}
+	store := openTestStore(t, WithEmbedder(emb), WithIndex())
+
+	if err := store.Upsert(ctx, Document{ID: "1", Content: "cats"}, Document{ID: "2", Content: "dogs"}); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	if emb.calls != 1 {
+		t.Errorf("embedder calls = %d, want 1 batched call", emb.calls)
+	}
+
+	got, err := store.SearchText(ctx, "kitten", 1)
+	if err != nil {
+		t.Fatalf("SearchText() error = %v", err)
+	}
+	if ids(got) != "1" {
+		t.Errorf("SearchText() = %s, want 1", ids(got))
+	}
+
+	plain := openTestStore(t)
+	if err := plain.Upsert(ctx, Document{ID: "x", Content: "no vector"}); !errors.Is(err, ErrNoEmbedder) {
+		t.Errorf("Upsert() without embedder error = %v, want ErrNoEmbedder", err)
+	}
+	if _, err := plain.SearchText(ctx, "q", 1); !errors.Is(err, ErrNoEmbedder) {
+		t.Errorf("SearchText() without embedder error = %v, want ErrNoEmbedder", err)
+	}
+}
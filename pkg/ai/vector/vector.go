@@ -0,0 +1,132 @@
+// Package vector provides a small vector store for retrieval-augmented
+// generation, backed by SQLite through the sqlt package.
+//
+// Documents are stored with their text, string metadata and embedding.
+// Searches are brute force: every candidate is scored against the query,
+// which is fast enough for tens of thousands of documents. WithIndex keeps a
+// copy of the documents in memory so searches do not read the database.
+//
+// Embeddings can be supplied by the caller or computed by a
+// generators.Embedder, for example one opened with generators.OpenEmbedder.
+package vector
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Metric selects how a query vector is compared with document vectors.
+// Higher scores always mean more similar.
+type Metric string
+
+const (
+	// Cosine scores by the cosine of the angle between the vectors, in [-1, 1].
+	Cosine Metric = "cosine"
+
+	// DotProduct scores by the dot product of the vectors. It equals Cosine
+	// for normalized embeddings and is cheaper to compute.
+	DotProduct Metric = "dot"
+)
+
+// Document is a piece of text stored with its embedding.
+type Document struct {
+	ID        string
+	Content   string
+	Metadata  map[string]string
+	Embedding []float32
+}
+
+// Result is a document returned by a search together with its score.
+type Result struct {
+	Document
+	Score float64
+}
+
+// score compares a and b with the metric. The vectors must have the same
+// length.
+func (m Metric) score(a, b []float32) (float64, error) {
+	var dot, na, nb float64
+	for i := range a {
+		x, y := float64(a[i]), float64(b[i])
+		dot += x * y
+		na += x * x
+		nb += y * y
+	}
+	switch m {
+	case DotProduct:
+		return dot, nil
+	case Cosine, "":
+		if na == 0 || nb == 0 {
+			return 0, nil
+		}
+		return dot / (math.Sqrt(na) * math.Sqrt(nb)), nil
+	default:
+		return 0, fmt.Errorf("vector: unknown metric %q", m)
+	}
+}
+
+// matches reports whether the metadata contains every key/value in filter.
+func matches(metadata, filter map[string]string) bool {
+	for k, v := range filter {
+		if got, ok := metadata[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// rank scores the documents that pass the filter and returns the k best,
+// highest score first. Ties are broken by ID so results are deterministic.
+func rank(docs []Document, query []float32, k int, cfg *searchConfig) ([]Result, error) {
+	var results []Result
+	for _, d := range docs {
+		if !matches(d.Metadata, cfg.filter) {
+			continue
+		}
+		if len(d.Embedding) != len(query) {
+			return nil, fmt.Errorf("vector: document %q has %d dimensions, query has %d", d.ID, len(d.Embedding), len(query))
+		}
+		s, err := cfg.metric.score(query, d.Embedding)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.minScore != nil && s < *cfg.minScore {
+			continue
+		}
+		results = append(results, Result{Document: d, Score: s})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID < results[j].ID
+	})
+	if k >= 0 && len(results) > k {
+		results = results[:k]
+	}
+	return results, nil
+}
+
+// encodeEmbedding serializes an embedding as little-endian float32 values.
+func encodeEmbedding(v []float32) []byte {
+	buf := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(f))
+	}
+	return buf
+}
+
+// decodeEmbedding is the inverse of encodeEmbedding.
+func decodeEmbedding(buf []byte) ([]float32, error) {
+	if len(buf)%4 != 0 {
+		return nil, errors.New("vector: corrupt embedding")
+	}
+	v := make([]float32, len(buf)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return v, nil
+}
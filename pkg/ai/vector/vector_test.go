@@ -0,0 +1,49 @@
+package vector
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMetricScore(t *testing.T) {
+	testCases := []struct {
+		metric Metric
+		a, b   []float32
+		want   float64
+	}{
+		{metric: Cosine, a: []float32{1, 0}, b: []float32{2, 0}, want: 1},
+		{metric: Cosine, a: []float32{1, 0}, b: []float32{0, 3}, want: 0},
+		{metric: Cosine, a: []float32{1, 1}, b: []float32{-1, -1}, want: -1},
+		{metric: Cosine, a: []float32{0, 0}, b: []float32{1, 1}, want: 0},
+		{metric: DotProduct, a: []float32{1, 2}, b: []float32{3, 4}, want: 11},
+	}
+	for _, tc := range testCases {
+		got, err := tc.metric.score(tc.a, tc.b)
+		if err != nil {
+			t.Fatalf("score() error = %v", err)
+		}
+		if math.Abs(got-tc.want) > 1e-6 {
+			t.Errorf("%s(%v, %v) = %v, want %v", tc.metric, tc.a, tc.b, got, tc.want)
+		}
+	}
+
+	if _, err := Metric("euclid").score([]float32{1}, []float32{1}); err == nil {
+		t.Error("expected error for unknown metric")
+	}
+}
+
+func TestEncodeDecodeEmbedding(t *testing.T) {
+	in := []float32{0, -1.5, 3.25, float32(math.Inf(1))}
+	out, err := decodeEmbedding(encodeEmbedding(in))
+	if err != nil {
+		t.Fatalf("decodeEmbedding() error = %v", err)
+	}
+	for i := range in {
+		if out[i] != in[i] {
+			t.Fatalf("round trip = %v, want %v", out, in)
+		}
+	}
+	if _, err := decodeEmbedding([]byte{1, 2, 3}); err == nil {
+		t.Error("expected error for truncated embedding")
+	}
+}